@@ -0,0 +1,348 @@
+package main
+
+import (
+	"backend/tasks"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// Candidate is a completion of a word, with a description for the shells
+// that show one beside it.
+type Candidate struct {
+	Value       string
+	Description string
+}
+
+// completions returns what can be typed in place of current, the word under
+// the cursor, after words, the command line without the program name. Only
+// candidates starting with current are returned.
+func completions(store *tasks.Store, words []string, current string) []Candidate {
+	candidates := candidatesFor(store, words, current)
+	return slices.DeleteFunc(candidates, func(candidate Candidate) bool {
+		return !strings.HasPrefix(candidate.Value, current)
+	})
+}
+
+func candidatesFor(store *tasks.Store, words []string, current string) []Candidate {
+	// The global flags come before the command. One still waiting for its
+	// value is the last word left.
+	_, words = extractGlobalFlags(words)
+	if len(words) == 1 && slices.ContainsFunc(globalFlagCandidates, func(flag Candidate) bool { return flag.Value == words[0] }) {
+		return globalFlagValueCandidates(words[0])
+	}
+	if len(words) == 0 && strings.HasPrefix(current, "-") {
+		return slices.Clone(globalFlagCandidates)
+	}
+	if len(words) == 0 {
+		return commandCandidates()
+	}
+	spec, ok := commands.Lookup(words[0])
+	if !ok {
+		return nil
+	}
+
+	// Walk the words to find what current is: a flag, the value of the flag
+	// before it or a positional arg.
+	position := 0
+	var valueOf *Flag
+	for _, word := range words[1:] {
+		if valueOf != nil {
+			valueOf = nil
+			continue
+		}
+		if name, isFlag := strings.CutPrefix(word, "--"); isFlag {
+			if flag, ok := spec.flag(name); ok && flag.Kind != KindBool {
+				valueOf = &flag
+			}
+			continue
+		}
+		position++
+	}
+	switch {
+	case valueOf != nil:
+		return flagValueCandidates(store, *valueOf)
+	case strings.HasPrefix(current, "-"):
+		candidates := []Candidate{}
+		for _, flag := range spec.Flags {
+			candidates = append(candidates, Candidate{"--" + flag.Name, flag.Help})
+		}
+		return candidates
+	}
+
+	var arg Arg
+	switch {
+	case position < len(spec.Args):
+		arg = spec.Args[position]
+	case len(spec.Args) > 0 && spec.Args[len(spec.Args)-1].Rest:
+		arg = spec.Args[len(spec.Args)-1]
+	default:
+		return nil
+	}
+	switch {
+	case arg.Kind == KindInt && spec.Name == "restore":
+		trash, _ := store.Trash()
+		return taskCandidates(trash)
+	case arg.Kind == KindInt:
+		list, _ := store.Query(tasks.Query{Status: tasks.AllTasks})
+		return taskCandidates(list)
+	case arg.Name == "status":
+		return statusCandidates()
+	case arg.Name == "filters":
+		return slices.Concat(statusCandidates(), viewCandidates(), tagCandidates(store))
+	case arg.Name == "level":
+		return priorityCandidates()
+	case arg.Name == "name":
+		return projectCandidates(store)
+	case arg.Name == "command":
+		return commandCandidates()
+	case spec.Name == "completion":
+		candidates := []Candidate{}
+		for _, shell := range CompletionShells {
+			candidates = append(candidates, Candidate{shell, ""})
+		}
+		return candidates
+	}
+	return nil
+}
+
+func flagValueCandidates(store *tasks.Store, flag Flag) []Candidate {
+	switch {
+	case flag.Kind == KindInt:
+		list, _ := store.Query(tasks.Query{Status: tasks.AllTasks})
+		return taskCandidates(list)
+	case flag.Name == "priority":
+		return priorityCandidates()
+	case flag.Name == "project":
+		return projectCandidates(store)
+	case flag.Name == "format":
+		candidates := []Candidate{}
+		for _, format := range tasks.Formats {
+			candidates = append(candidates, Candidate{format, ""})
+		}
+		return candidates
+	case flag.Name == "sort":
+		candidates := []Candidate{}
+		for _, key := range tasks.SortKeys {
+			candidates = append(candidates, Candidate{string(key), ""})
+		}
+		return candidates
+	}
+	return nil
+}
+
+// globalFlagCandidates are the flags accepted before the command.
+var globalFlagCandidates = []Candidate{
+	{"--db", "the database file"},
+	{"--backend", "the storage format of the database"},
+	{"--error-format", "how errors are written, text or json"},
+}
+
+func globalFlagValueCandidates(flag string) []Candidate {
+	var values []string
+	switch flag {
+	case "--backend":
+		values = tasks.Backends
+	case "--error-format":
+		values = []string{ErrorFormatText, ErrorFormatJSON}
+	}
+	candidates := []Candidate{}
+	for _, value := range values {
+		candidates = append(candidates, Candidate{value, ""})
+	}
+	return candidates
+}
+
+func commandCandidates() []Candidate {
+	candidates := []Candidate{}
+	for _, spec := range commands.Visible() {
+		candidates = append(candidates, Candidate{spec.Name, spec.Summary})
+		for _, alias := range spec.Aliases {
+			candidates = append(candidates, Candidate{alias, "same as " + spec.Name})
+		}
+	}
+	return candidates
+}
+
+func taskCandidates(list []tasks.Task) []Candidate {
+	candidates := make([]Candidate, len(list))
+	for i, task := range list {
+		candidates[i] = Candidate{strconv.Itoa(task.ID), task.Description}
+	}
+	return candidates
+}
+
+func statusCandidates() []Candidate {
+	candidates := []Candidate{}
+	for _, status := range tasks.CurrentWorkflow().Statuses {
+		candidates = append(candidates, Candidate{status.Name, status.Label})
+	}
+	return candidates
+}
+
+func viewCandidates() []Candidate {
+	candidates := []Candidate{}
+	for _, view := range tasks.Views {
+		candidates = append(candidates, Candidate{view, ""})
+	}
+	return candidates
+}
+
+func tagCandidates(store *tasks.Store) []Candidate {
+	tags, _ := store.Tags()
+	candidates := []Candidate{}
+	for _, tag := range tags {
+		candidates = append(candidates, Candidate{"+" + tag.Tag, fmt.Sprintf("%d tasks", tag.Count)})
+	}
+	return candidates
+}
+
+func priorityCandidates() []Candidate {
+	candidates := []Candidate{}
+	for priority := tasks.PriorityNone; priority <= tasks.PriorityCritical; priority++ {
+		candidates = append(candidates, Candidate{priority.String(), ""})
+	}
+	return candidates
+}
+
+func projectCandidates(store *tasks.Store) []Candidate {
+	projects, _ := store.Projects()
+	candidates := []Candidate{}
+	for _, project := range projects {
+		candidates = append(candidates, Candidate{project.Project, ""})
+	}
+	return candidates
+}
+
+// CompleteCommand is called by the completion scripts with the words of the
+// command line, the last being the one to complete. It prints one candidate
+// per line, with a tab before its description. Task IDs, tags and projects
+// come from the database --db and --backend name on the line, if they do.
+type CompleteCommand Command
+
+func (com *CompleteCommand) Execute(in *Input) error {
+	words := in.Words("words")
+	current := ""
+	if len(words) > 0 {
+		current = words[len(words)-1]
+		words = words[:len(words)-1]
+	}
+	store, err := com.storeFor(words)
+	if err != nil {
+		return err
+	}
+	for _, candidate := range completions(store, words, current) {
+		fmt.Printf("%s\t%s\n", candidate.Value, strings.ReplaceAll(candidate.Description, "\n", " "))
+	}
+	return nil
+}
+
+// storeFor returns a store on the database the global flags in words name,
+// or the store of the command when they name none.
+func (com *CompleteCommand) storeFor(words []string) (*tasks.Store, error) {
+	flags, _ := extractGlobalFlags(words)
+	if flags.db == "" && flags.backend == "" {
+		return com.store, nil
+	}
+	config, err := loadConfig()
+	if err == nil {
+		config, err = config.resolve(flags)
+	}
+	if err != nil {
+		return nil, err
+	}
+	repo, err := tasks.NewRepository(config.Backend, config.DB)
+	if err != nil {
+		return nil, err
+	}
+	return tasks.NewStore(repo), nil
+}
+
+// completionScripts load the completions of the program into each shell.
+// They ask the hidden __complete command for the candidates, so task IDs
+// and statuses are always current. The whole line goes to __complete,
+// global flags included, so it completes against the database they name.
+var completionScripts = map[string]string{
+	"bash": `# bash completion for {{.Program}}. Load it with
+#   source <({{.Program}} completion bash)
+# bash cannot show descriptions apart, so they are added to the candidates
+# when there is more than one to choose from.
+_{{.Function}}() {
+    local IFS=$'\n'
+    local -a lines
+    lines=($({{.Program}} __complete -- "${COMP_WORDS[@]:1:COMP_CWORD}" 2>/dev/null))
+    COMPREPLY=()
+    if [[ ${#lines[@]} -eq 1 ]]; then
+        COMPREPLY=("${lines[0]%%$'\t'*}")
+        return
+    fi
+    local line
+    for line in "${lines[@]}"; do
+        if [[ $line == *$'\t'?* ]]; then
+            COMPREPLY+=("${line%%$'\t'*}  (${line#*$'\t'})")
+        else
+            COMPREPLY+=("${line%%$'\t'*}")
+        fi
+    done
+}
+complete -o default -F _{{.Function}} {{.Program}}
+`,
+	"zsh": `#compdef {{.Program}}
+# zsh completion for {{.Program}}. Load it with
+#   source <({{.Program}} completion zsh)
+# or save it as _{{.Program}} in a directory of $fpath.
+_{{.Function}}() {
+    local -a candidates
+    local line
+    for line in "${(@f)$({{.Program}} __complete -- "${(@)words[2,CURRENT]}" 2>/dev/null)}"; do
+        [[ -z $line ]] && continue
+        candidates+=("${${line%%$'\t'*}//:/\\:}:${line#*$'\t'}")
+    done
+    _describe '{{.Program}}' candidates
+}
+compdef _{{.Function}} {{.Program}}
+`,
+	"fish": `# fish completion for {{.Program}}. Load it with
+#   {{.Program}} completion fish | source
+function __{{.Function}}_complete
+    set -l words (commandline -opc)
+    set -l current (commandline -ct)
+    {{.Program}} __complete -- $words[2..-1] "$current" 2>/dev/null
+end
+complete -c {{.Program}} -f -a '(__{{.Function}}_complete)'
+`,
+}
+
+// CompletionShells are the shells completion writes scripts for.
+var CompletionShells = []string{"bash", "zsh", "fish"}
+
+type CompletionCommand Command
+
+func (com *CompletionCommand) Verify(in *Input) error {
+	if !slices.Contains(CompletionShells, in.Arg("shell")) {
+		return fmt.Errorf("invalid shell for completion command. Expected %s. Received %s", strings.Join(CompletionShells, ", "), in.Arg("shell"))
+	}
+	return nil
+}
+
+func (com *CompletionCommand) Execute(in *Input) error {
+	return writeCompletionScript(os.Stdout, in.Arg("shell"), programName())
+}
+
+// writeCompletionScript writes the completion script of shell for program.
+func writeCompletionScript(w io.Writer, shell, program string) error {
+	script := template.Must(template.New("completion").Parse(completionScripts[shell]))
+	return script.Execute(w, struct{ Program, Function string }{
+		program,
+		strings.Map(func(r rune) rune {
+			if r == '-' || r == '.' {
+				return '_'
+			}
+			return r
+		}, program),
+	})
+}
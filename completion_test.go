@@ -0,0 +1,95 @@
+package main
+
+import (
+	"backend/tasks"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func candidateValues(candidates []Candidate) []string {
+	values := make([]string, len(candidates))
+	for i, candidate := range candidates {
+		values[i] = candidate.Value
+	}
+	return values
+}
+
+func TestCompletions(t *testing.T) {
+	store := tasks.NewStore(tasks.NewMemoryRepository())
+	store.AddTask("build")
+	store.AddTask("test")
+	store.AddTask("old")
+	store.DeleteTask(3)
+
+	tests := []struct {
+		words   []string
+		current string
+		want    []string
+	}{
+		{nil, "undo", []string{"undo"}},
+		{[]string{"--db", "tasks.json"}, "undo", []string{"undo"}},
+		{[]string{"--db=tasks.json", "--backend", "json"}, "undo", []string{"undo"}},
+		{[]string{"--db", "tasks.json", "--"}, "undo", []string{"undo"}},
+		{nil, "--b", []string{"--backend"}},
+		{[]string{"--backend"}, "", tasks.Backends},
+		{[]string{"--error-format"}, "", []string{"text", "json"}},
+		{[]string{"--db"}, "", nil},
+		{[]string{"mark-done"}, "", []string{"1", "2"}},
+		{[]string{"--db", "tasks.json", "mark-done"}, "", []string{"1", "2"}},
+		{[]string{"restore"}, "", []string{"3"}},
+		{[]string{"mark-done"}, "--f", []string{"--force"}},
+		{[]string{"list", "--sort"}, "pr", []string{"priority"}},
+		{[]string{"mark", "1"}, "do", []string{"done"}},
+		{[]string{"mark", "1", "done"}, "", nil},
+		{[]string{"completion"}, "", CompletionShells},
+		{[]string{"nothing"}, "", nil},
+	}
+	for _, test := range tests {
+		got := candidateValues(completions(store, test.words, test.current))
+		if !slices.Equal(got, test.want) {
+			t.Errorf("completions(%q, %q): expected %q, received %q", test.words, test.current, test.want, got)
+		}
+	}
+}
+
+func TestCompleteReadsTheDatabaseOnTheLine(t *testing.T) {
+	t.Setenv(configEnvVar, filepath.Join(t.TempDir(), "config.json"))
+	path := filepath.Join(t.TempDir(), "other.json")
+	repo, err := tasks.NewRepository(tasks.BackendJSON, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tasks.NewStore(repo).AddTask("from the other database")
+
+	com := &CompleteCommand{store: tasks.NewStore(tasks.NewMemoryRepository())}
+	words := []string{"--db", path, "mark-done"}
+	store, err := com.storeFor(words)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := completions(store, words, ""); len(got) != 1 || got[0].Description != "from the other database" {
+		t.Errorf("expected the task of %s, received %v", path, got)
+	}
+	if store, _ := com.storeFor([]string{"mark-done"}); store != com.store {
+		t.Errorf("without global flags the store of the command should be used")
+	}
+}
+
+func TestCompletionScripts(t *testing.T) {
+	for _, shell := range CompletionShells {
+		var script strings.Builder
+		if err := writeCompletionScript(&script, shell, "task-tracker"); err != nil {
+			t.Fatalf("%s: %s", shell, err)
+		}
+		for _, want := range []string{"task-tracker __complete -- ", "_task_tracker"} {
+			if !strings.Contains(script.String(), want) {
+				t.Errorf("%s: expected %q in\n%s", shell, want, script.String())
+			}
+		}
+		if strings.Contains(script.String(), "{{") {
+			t.Errorf("%s: template left unexpanded:\n%s", shell, script.String())
+		}
+	}
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"backend/tasks"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	dbEnvVar      = "TASK_TRACKER_DB"
+	backendEnvVar = "TASK_TRACKER_BACKEND"
+	configEnvVar  = "TASK_TRACKER_CONFIG"
+)
+
+// Config is read from $XDG_CONFIG_HOME/task-tracker/config.json. Flags and
+// environment variables override it.
+type Config struct {
+	DB      string `json:"db"`
+	Backend string `json:"backend"`
+	// User signs the history entries. It defaults to $USER.
+	User string `json:"user"`
+	// Statuses extends the todo, in-progress and done workflow, as in
+	// {"statuses": [{"value": 3, "name": "in-review", "transitions": ["done"]}]}.
+	Statuses []tasks.StatusDefinition `json:"statuses"`
+	// UndoDepth is how many changes undo can revert. It defaults to 20.
+	UndoDepth int `json:"undo_depth"`
+	// AutoArchive archives done tasks older than an age, like "30d", on
+	// every change. Empty turns it off.
+	AutoArchive string `json:"auto_archive"`
+
+	// errorFormat comes from --error-format only.
+	errorFormat string
+}
+
+// globalFlags are the options accepted before the command.
+type globalFlags struct {
+	db      string
+	backend string
+	// errorFormat is how errors are written to stderr: text or json.
+	errorFormat string
+}
+
+// extractGlobalFlags removes --db, --backend and --error-format from the
+// start of args, keeping the value of the last occurrence of each. It stops
+// at the first other word, the command, or after --, so the args of the
+// command are left as they are.
+func extractGlobalFlags(args []string) (globalFlags, []string) {
+	flags := globalFlags{errorFormat: ErrorFormatText}
+	targets := map[string]*string{"--db": &flags.db, "--backend": &flags.backend, "--error-format": &flags.errorFormat}
+
+	for i := 0; i < len(args); i++ {
+		if args[i] == "--" {
+			return flags, args[i+1:]
+		}
+		name, value, hasValue := strings.Cut(args[i], "=")
+		target, ok := targets[name]
+		switch {
+		case ok && hasValue:
+			*target = value
+		case ok && i+1 < len(args):
+			*target = args[i+1]
+			i++
+		default:
+			return flags, args[i:]
+		}
+	}
+	return flags, nil
+}
+
+func configPath() (string, error) {
+	if path := os.Getenv(configEnvVar); path != "" {
+		return path, nil
+	}
+	configHome := os.Getenv("XDG_CONFIG_HOME")
+	if configHome == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		configHome = filepath.Join(home, ".config")
+	}
+	return filepath.Join(configHome, "task-tracker", "config.json"), nil
+}
+
+// loadConfig reads the config file. A missing file is an empty config.
+func loadConfig() (Config, error) {
+	var config Config
+	path, err := configPath()
+	if err != nil {
+		return config, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return config, nil
+	}
+	if err != nil {
+		return config, err
+	}
+	err = json.Unmarshal(data, &config)
+	return config, err
+}
+
+// resolve applies flags and environment variables on top of the config file.
+// The database path falls back to the XDG data dir.
+func (config Config) resolve(flags globalFlags) (Config, error) {
+	config.Backend = firstNonEmpty(flags.backend, os.Getenv(backendEnvVar), config.Backend, tasks.BackendJSON)
+	config.DB = firstNonEmpty(flags.db, os.Getenv(dbEnvVar), config.DB)
+	config.User = firstNonEmpty(config.User, os.Getenv("USER"), os.Getenv("USERNAME"), "unknown")
+	config.errorFormat = flags.errorFormat
+	if config.DB != "" {
+		return config, nil
+	}
+
+	dataHome := os.Getenv("XDG_DATA_HOME")
+	if dataHome == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return config, err
+		}
+		dataHome = filepath.Join(home, ".local", "share")
+	}
+	fileName := "tasks.json"
+	if config.Backend == tasks.BackendJSONLines {
+		fileName = "tasks.jsonl"
+	}
+	config.DB = filepath.Join(dataHome, "task-tracker", fileName)
+	return config, nil
+}
+
+// history returns where the change history of the database is kept.
+func (config Config) history() tasks.History {
+	if config.Backend == tasks.BackendMemory {
+		return tasks.NewMemoryHistory()
+	}
+	return tasks.NewFileHistory(config.DB + ".history.jsonl")
+}
+
+// undoLog returns where the undo stack of the database is kept.
+func (config Config) undoLog() tasks.UndoLog {
+	if config.Backend == tasks.BackendMemory {
+		return tasks.NewMemoryUndoLog()
+	}
+	return tasks.NewFileUndoLog(config.DB + ".undo.json")
+}
+
+// archive returns the repository archived tasks are moved to, next to the
+// database as tasks.archive.json, and the auto-archive age.
+func (config Config) archive() (tasks.Repository, time.Duration, error) {
+	var autoArchive time.Duration
+	if config.AutoArchive != "" {
+		age, err := tasks.ParseAge(config.AutoArchive)
+		if err != nil {
+			return nil, 0, err
+		}
+		autoArchive = age
+	}
+	extension := filepath.Ext(config.DB)
+	path := strings.TrimSuffix(config.DB, extension) + ".archive" + extension
+	repo, err := tasks.NewRepository(config.Backend, path)
+	return repo, autoArchive, err
+}
+
+// openStore returns a store on repo with the history, undo and archive of the
+// database.
+func (config Config) openStore(repo tasks.Repository) (*tasks.Store, error) {
+	archive, autoArchive, err := config.archive()
+	if err != nil {
+		return nil, err
+	}
+	return tasks.NewStore(repo,
+		tasks.WithHistory(config.history(), config.User),
+		tasks.WithUndo(config.undoLog(), config.UndoDepth),
+		tasks.WithArchive(archive, autoArchive)), nil
+}
+
+// shellHistory returns the file the lines typed in the shell are kept in, or
+// "" when they are not kept.
+func (config Config) shellHistory() string {
+	if config.Backend == tasks.BackendMemory {
+		return ""
+	}
+	return config.DB + ".shell_history"
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
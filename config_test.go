@@ -0,0 +1,126 @@
+package main
+
+import (
+	"backend/tasks"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// setConfigFile points the config at a temporary file holding data, or at a
+// missing one when data is empty.
+func setConfigFile(t *testing.T, data string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.json")
+	if data != "" {
+		if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv(configEnvVar, path)
+}
+
+func TestResolveDB(t *testing.T) {
+	home, dataHome := t.TempDir(), t.TempDir()
+	tests := []struct {
+		name     string
+		flag     string
+		env      string
+		config   string
+		dataHome string
+		backend  string
+		want     string
+	}{
+		{"flag first", "flag.json", "env.json", `{"db": "config.json"}`, dataHome, "", "flag.json"},
+		{"then the environment", "", "env.json", `{"db": "config.json"}`, dataHome, "", "env.json"},
+		{"then the config file", "", "", `{"db": "config.json"}`, dataHome, "", "config.json"},
+		{"then XDG_DATA_HOME", "", "", "", dataHome, "", filepath.Join(dataHome, "task-tracker", "tasks.json")},
+		{"jsonl by default for jsonl", "", "", "", dataHome, tasks.BackendJSONLines, filepath.Join(dataHome, "task-tracker", "tasks.jsonl")},
+		{"then the home dir", "", "", "", "", "", filepath.Join(home, ".local", "share", "task-tracker", "tasks.json")},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Setenv("HOME", home)
+			t.Setenv("XDG_DATA_HOME", test.dataHome)
+			t.Setenv(dbEnvVar, test.env)
+			t.Setenv(backendEnvVar, "")
+			setConfigFile(t, test.config)
+
+			config, err := loadConfig()
+			if err != nil {
+				t.Fatal(err)
+			}
+			config, err = config.resolve(globalFlags{db: test.flag, backend: test.backend})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if config.DB != test.want {
+				t.Errorf("expected %s, received %s", test.want, config.DB)
+			}
+		})
+	}
+}
+
+func TestResolveBackend(t *testing.T) {
+	tests := []struct {
+		flag, env, config string
+		want              string
+	}{
+		{tasks.BackendMemory, tasks.BackendJSONLines, `{"backend": "jsonl"}`, tasks.BackendMemory},
+		{"", tasks.BackendMemory, `{"backend": "jsonl"}`, tasks.BackendMemory},
+		{"", "", `{"backend": "jsonl"}`, tasks.BackendJSONLines},
+		{"", "", "", tasks.BackendJSON},
+	}
+	for _, test := range tests {
+		t.Setenv(backendEnvVar, test.env)
+		t.Setenv(dbEnvVar, "tasks.db")
+		setConfigFile(t, test.config)
+
+		config, err := loadConfig()
+		if err == nil {
+			config, err = config.resolve(globalFlags{backend: test.flag})
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if config.Backend != test.want {
+			t.Errorf("flag %q, env %q, config %q: expected %s, received %s", test.flag, test.env, test.config, test.want, config.Backend)
+		}
+	}
+}
+
+func TestConfigPath(t *testing.T) {
+	configHome := t.TempDir()
+	t.Setenv(configEnvVar, "")
+	t.Setenv("XDG_CONFIG_HOME", configHome)
+	if path, _ := configPath(); path != filepath.Join(configHome, "task-tracker", "config.json") {
+		t.Errorf("expected the config under XDG_CONFIG_HOME, received %s", path)
+	}
+	t.Setenv(configEnvVar, "custom.json")
+	if path, _ := configPath(); path != "custom.json" {
+		t.Errorf("expected %s to win, received %s", configEnvVar, path)
+	}
+}
+
+func TestExtractGlobalFlags(t *testing.T) {
+	tests := []struct {
+		args     []string
+		want     globalFlags
+		wantRest []string
+	}{
+		{[]string{"list"}, globalFlags{errorFormat: ErrorFormatText}, []string{"list"}},
+		{[]string{"--db", "a.json", "list", "--db", "b"}, globalFlags{db: "a.json", errorFormat: ErrorFormatText}, []string{"list", "--db", "b"}},
+		{[]string{"--db=a.json", "--db", "b.json", "list"}, globalFlags{db: "b.json", errorFormat: ErrorFormatText}, []string{"list"}},
+		{[]string{"--backend", "jsonl", "--error-format=json", "show", "1"}, globalFlags{backend: "jsonl", errorFormat: ErrorFormatJSON}, []string{"show", "1"}},
+		{[]string{"--db", "a.json", "--", "--help"}, globalFlags{db: "a.json", errorFormat: ErrorFormatText}, []string{"--help"}},
+		{[]string{"--db"}, globalFlags{errorFormat: ErrorFormatText}, []string{"--db"}},
+		{nil, globalFlags{errorFormat: ErrorFormatText}, nil},
+	}
+	for _, test := range tests {
+		flags, rest := extractGlobalFlags(test.args)
+		if flags != test.want || !slices.Equal(rest, test.wantRest) {
+			t.Errorf("extractGlobalFlags(%q): expected %+v %q, received %+v %q", test.args, test.want, test.wantRest, flags, rest)
+		}
+	}
+}
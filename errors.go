@@ -0,0 +1,120 @@
+package main
+
+import (
+	"backend/jsondatabase"
+	"backend/tasks"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+)
+
+// Exit codes of the program, so scripts can tell failures apart.
+const (
+	ExitOK = iota
+	// ExitFailure is any failure not covered below.
+	ExitFailure
+	// ExitUsage is a command line that does not match the command.
+	ExitUsage
+	// ExitNotFound is a task or project that does not exist.
+	ExitNotFound
+	// ExitInvalid is a value or a change the tasks do not allow.
+	ExitInvalid
+	// ExitStorage is a database that cannot be read or written.
+	ExitStorage
+)
+
+const (
+	ErrorFormatText = "text"
+	ErrorFormatJSON = "json"
+)
+
+// exitKinds names the exit codes in JSON errors.
+var exitKinds = map[int]string{
+	ExitFailure:  "failure",
+	ExitUsage:    "usage",
+	ExitNotFound: "not_found",
+	ExitInvalid:  "invalid",
+	ExitStorage:  "storage",
+}
+
+// UsageError is a command line that cannot run. Command is empty when no
+// command was recognized.
+type UsageError struct {
+	Command string
+	Err     error
+}
+
+func (e *UsageError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *UsageError) Unwrap() error {
+	return e.Err
+}
+
+// isError reports whether err wraps an error of type T.
+func isError[T error](err error) bool {
+	var target T
+	return errors.As(err, &target)
+}
+
+// exitCode returns the exit code for an error returned by a command.
+func exitCode(err error) int {
+	switch {
+	case err == nil:
+		return ExitOK
+	case isError[*UsageError](err), isError[*UnknownCommandError](err),
+		isError[*UnknownFlagError](err), isError[*InvalidArgNumberError](err):
+		return ExitUsage
+	case isError[*tasks.ErrTaskNotFound](err), isError[*tasks.ErrProjectNotFound](err):
+		return ExitNotFound
+	case errors.Is(err, tasks.ErrNoDescriptionProvided),
+		errors.Is(err, tasks.ErrNothingToUndo), errors.Is(err, tasks.ErrNothingToRedo),
+		isError[*tasks.ErrInvalidStatus](err), isError[*tasks.ErrUnknownStatus](err),
+		isError[*tasks.ErrInvalidTransition](err), isError[*tasks.ErrInvalidWorkflow](err),
+		isError[*tasks.ErrInvalidPriority](err), isError[*tasks.ErrInvalidDueDate](err),
+		isError[*tasks.ErrInvalidRecurrence](err), isError[*tasks.ErrInvalidTag](err),
+		isError[*tasks.ErrInvalidProject](err), isError[*tasks.ErrInvalidSortKey](err),
+		isError[*tasks.ErrInvalidAge](err), isError[*tasks.ErrInvalidFormat](err),
+		isError[*tasks.ErrBlocked](err), isError[*tasks.ErrDependencyCycle](err),
+		isError[*tasks.ErrOpenChildren](err), isError[*tasks.ErrHasChildren](err),
+		isError[*tasks.ErrHierarchyCycle](err), isError[*tasks.ErrUndoConflict](err):
+		return ExitInvalid
+	case errors.Is(err, jsondatabase.ErrLocked), errors.Is(err, jsondatabase.ErrReadOnly),
+		errors.Is(err, jsondatabase.ErrNotAJsonOld), errors.Is(err, tasks.ErrMigrationUnsupported),
+		isError[*jsondatabase.ErrSchemaTooNew](err), isError[*jsondatabase.ErrMigrationOrder](err),
+		isError[*jsondatabase.NotAJsonError](err), isError[*tasks.ErrSessionConflict](err),
+		isError[*tasks.ErrUnknownBackend](err), isError[*fs.PathError](err),
+		isError[*os.LinkError](err), isError[*os.SyscallError](err),
+		isError[*json.SyntaxError](err), isError[*json.UnmarshalTypeError](err):
+		return ExitStorage
+	}
+	return ExitFailure
+}
+
+// reportError writes err to w in the given format and returns the exit code
+// for it.
+func reportError(w io.Writer, format string, err error) int {
+	code := exitCode(err)
+	if format == ErrorFormatJSON {
+		data, marshalErr := json.Marshal(struct {
+			Error string `json:"error"`
+			Kind  string `json:"kind"`
+			Code  int    `json:"code"`
+		}{err.Error(), exitKinds[code], code})
+		if marshalErr == nil {
+			fmt.Fprintln(w, string(data))
+			return code
+		}
+	}
+
+	fmt.Fprintln(w, err.Error())
+	var usage *UsageError
+	if errors.As(err, &usage) && usage.Command != "" {
+		fmt.Fprintf(w, "Run '%s %s --help' for usage\n", programName(), usage.Command)
+	}
+	return code
+}
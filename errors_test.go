@@ -0,0 +1,37 @@
+package main
+
+import (
+	"backend/jsondatabase"
+	"backend/tasks"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"testing"
+)
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{nil, ExitOK},
+		{errors.New("something else"), ExitFailure},
+		{&UsageError{Err: errors.New("missing args")}, ExitUsage},
+		{&UnknownCommandError{}, ExitUsage},
+		{&tasks.ErrTaskNotFound{ID: 3}, ExitNotFound},
+		{fmt.Errorf("add: %w", tasks.ErrNoDescriptionProvided), ExitInvalid},
+		{&tasks.ErrUndoConflict{ID: 3}, ExitInvalid},
+		{jsondatabase.ErrLocked, ExitStorage},
+		{&fs.PathError{Op: "open", Path: "tasks.json", Err: fs.ErrPermission}, ExitStorage},
+		{&os.LinkError{Op: "rename", Old: "tasks.json.tmp", New: "tasks.json", Err: fs.ErrPermission}, ExitStorage},
+		{fmt.Errorf("save: %w", os.NewSyscallError("fsync", fs.ErrInvalid)), ExitStorage},
+		{&jsondatabase.NotAJsonError{}, ExitStorage},
+		{fmt.Errorf("save: %w", &tasks.ErrSessionConflict{ID: 3}), ExitStorage},
+	}
+	for _, test := range tests {
+		if got := exitCode(test.err); got != test.want {
+			t.Errorf("exitCode(%v): expected %d, received %d", test.err, test.want, got)
+		}
+	}
+}
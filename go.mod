@@ -6,7 +6,10 @@ replace backend/jsondatabase => ./json-database
 
 replace backend/tasks => ./tasks
 
+replace backend/tui => ./tui
+
 require (
-	backend/jsondatabase v0.0.0-00010101000000-000000000000 // indirect
+	backend/jsondatabase v0.0.0-00010101000000-000000000000
 	backend/tasks v0.0.0-00010101000000-000000000000
+	backend/tui v0.0.0-00010101000000-000000000000
 )
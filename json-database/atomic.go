@@ -0,0 +1,150 @@
+package jsondatabase
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+// fileSystem holds the file operations used to replace the database file.
+// Tests swap it to inject failures between steps.
+type fileSystem interface {
+	CreateTemp(dir, pattern string) (tempFile, error)
+	Rename(oldPath, newPath string) error
+	Remove(name string) error
+	SyncDir(dir string) error
+	Open(path string, mode os.FileMode) (*os.File, error)
+}
+
+// ErrReopen is returned when the new contents were written to the database
+// file but the file could not be opened again afterwards. Unlike the other
+// errors of a write, the change is on disk; only the handle is lost.
+type ErrReopen struct {
+	Path string
+	Err  error
+}
+
+func (e *ErrReopen) Error() string {
+	return fmt.Sprintf("%s was written but could not be opened again: %v", e.Path, e.Err)
+}
+
+func (e *ErrReopen) Unwrap() error {
+	return e.Err
+}
+
+type tempFile interface {
+	io.Writer
+	Name() string
+	Chmod(mode os.FileMode) error
+	Sync() error
+	Close() error
+}
+
+type osFileSystem struct{}
+
+func (osFileSystem) CreateTemp(dir, pattern string) (tempFile, error) {
+	return os.CreateTemp(dir, pattern)
+}
+
+func (osFileSystem) Rename(oldPath, newPath string) error {
+	return os.Rename(oldPath, newPath)
+}
+
+func (osFileSystem) Remove(name string) error {
+	return os.Remove(name)
+}
+
+func (osFileSystem) SyncDir(dir string) error {
+	// Directories cannot be opened for syncing on windows, and the rename
+	// is already durable there once it returns.
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
+
+func (osFileSystem) Open(path string, mode os.FileMode) (*os.File, error) {
+	return getDb(path, mode)
+}
+
+func (db *Database[T]) fileSystem() fileSystem {
+	if db.fs == nil {
+		return osFileSystem{}
+	}
+	return db.fs
+}
+
+func (db *Database[T]) replaceFile(data []byte) error {
+	return replaceFile(db.fileSystem(), db.Path(), &db.file, data, db.mode())
+}
+
+// replaceFile writes data to a temporary file next to path, syncs it and
+// renames it over path, so a crash at any point leaves either the old or the
+// new contents on disk. The open handle in file is moved to the new file; if
+// that fails after the rename, the error is an ErrReopen.
+func replaceFile(fs fileSystem, path string, file **os.File, data []byte, mode os.FileMode) error {
+	dir := filepath.Dir(path)
+	if info, statErr := os.Stat(path); statErr == nil {
+		mode = info.Mode().Perm()
+	}
+
+	tmp, err := fs.CreateTemp(dir, filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
+	committed := false
+	defer func() {
+		if !committed {
+			fs.Remove(tmpName)
+		}
+	}()
+
+	if err := writeTemp(tmp, data, mode); err != nil {
+		return err
+	}
+
+	// Windows refuses to rename over a file that is still open.
+	if *file != nil {
+		(*file).Close()
+		*file = nil
+	}
+	renameErr := fs.Rename(tmpName, path)
+	if renameErr == nil {
+		committed = true
+		fs.SyncDir(dir)
+	}
+
+	reopened, reopenErr := fs.Open(path, mode)
+	if reopenErr != nil {
+		if renameErr == nil {
+			return &ErrReopen{path, reopenErr}
+		}
+		return renameErr
+	}
+	*file = reopened
+	return renameErr
+}
+
+func writeTemp(tmp tempFile, data []byte, mode os.FileMode) error {
+	if err := tmp.Chmod(mode); err != nil {
+		tmp.Close()
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	return tmp.Close()
+}
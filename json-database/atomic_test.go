@@ -0,0 +1,273 @@
+package jsondatabase
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var errInjected = errors.New("injected failure")
+
+// faultyFileSystem fails the named step. When crash is set, every operation
+// after the failure fails too, as if the process had died at that point.
+type faultyFileSystem struct {
+	osFileSystem
+	failAt  string
+	crash   bool
+	crashed bool
+}
+
+func (fs *faultyFileSystem) fail(step string) bool {
+	if fs.crashed {
+		return true
+	}
+	if fs.failAt == step {
+		fs.crashed = fs.crash
+		return true
+	}
+	return false
+}
+
+func (fs *faultyFileSystem) CreateTemp(dir, pattern string) (tempFile, error) {
+	if fs.fail("create") {
+		return nil, errInjected
+	}
+	file, err := fs.osFileSystem.CreateTemp(dir, pattern)
+	if err != nil {
+		return nil, err
+	}
+	return &faultyTempFile{file, fs}, nil
+}
+
+func (fs *faultyFileSystem) Rename(oldPath, newPath string) error {
+	if fs.fail("rename") {
+		return errInjected
+	}
+	return fs.osFileSystem.Rename(oldPath, newPath)
+}
+
+func (fs *faultyFileSystem) Remove(name string) error {
+	if fs.crashed {
+		return errInjected
+	}
+	return fs.osFileSystem.Remove(name)
+}
+
+func (fs *faultyFileSystem) SyncDir(dir string) error {
+	if fs.fail("syncdir") {
+		return errInjected
+	}
+	return fs.osFileSystem.SyncDir(dir)
+}
+
+func (fs *faultyFileSystem) Open(path string, mode os.FileMode) (*os.File, error) {
+	if fs.fail("reopen") {
+		return nil, errInjected
+	}
+	return fs.osFileSystem.Open(path, mode)
+}
+
+type faultyTempFile struct {
+	tempFile
+	fs *faultyFileSystem
+}
+
+func (f *faultyTempFile) Chmod(mode os.FileMode) error {
+	if f.fs.fail("chmod") {
+		return errInjected
+	}
+	return f.tempFile.Chmod(mode)
+}
+
+func (f *faultyTempFile) Write(p []byte) (int, error) {
+	if f.fs.fail("write") {
+		// Leave half of the data behind, like an interrupted write.
+		n, _ := f.tempFile.Write(p[:len(p)/2])
+		return n, errInjected
+	}
+	return f.tempFile.Write(p)
+}
+
+func (f *faultyTempFile) Sync() error {
+	if f.fs.fail("sync") {
+		return errInjected
+	}
+	return f.tempFile.Sync()
+}
+
+func (f *faultyTempFile) Close() error {
+	if f.fs.fail("close") {
+		f.tempFile.Close()
+		return errInjected
+	}
+	return f.tempFile.Close()
+}
+
+var failurePoints = []string{"create", "chmod", "write", "sync", "close", "rename"}
+
+func seedDatabase(t *testing.T, items []MockupStorable) (string, []byte) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "atomic.json")
+	db := New[MockupStorable](path)
+	if err := db.Open(); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.WriteAll(items); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+	original, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return path, original
+}
+
+func readItems(t *testing.T, path string) []MockupStorable {
+	t.Helper()
+	db := New[MockupStorable](path)
+	if err := db.OpenReadOnly(); err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	items, err := db.GetAll()
+	if err != nil {
+		t.Fatalf("database is unreadable after failed write: %s", err)
+	}
+	return items
+}
+
+func leftoverTempFiles(t *testing.T, path string) []string {
+	t.Helper()
+	matches, err := filepath.Glob(path + ".tmp-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return matches
+}
+
+func TestWriteAllFailureKeepsOldContents(t *testing.T) {
+	original := []MockupStorable{{1, "first"}, {2, "second"}}
+	replacement := []MockupStorable{{1, "replaced"}, {3, strings.Repeat("x", 4096)}}
+
+	for _, step := range failurePoints {
+		t.Run(step, func(t *testing.T) {
+			path, before := seedDatabase(t, original)
+
+			db := New[MockupStorable](path)
+			db.fs = &faultyFileSystem{failAt: step}
+			if err := db.Open(); err != nil {
+				t.Fatal(err)
+			}
+			err := db.WriteAll(replacement)
+			if !errors.Is(err, errInjected) {
+				t.Fatalf("expected injected error, received %v", err)
+			}
+			db.Close()
+
+			after, _ := os.ReadFile(path)
+			if string(after) != string(before) {
+				t.Errorf("database changed after failure at %s:\n%s", step, after)
+			}
+			if left := leftoverTempFiles(t, path); len(left) != 0 {
+				t.Errorf("temp files left behind: %v", left)
+			}
+			if items := readItems(t, path); len(items) != len(original) {
+				t.Errorf("expected %d items, received %d", len(original), len(items))
+			}
+		})
+	}
+}
+
+func TestWriteAllCrashKeepsOldContents(t *testing.T) {
+	original := []MockupStorable{{1, "first"}, {2, "second"}}
+	replacement := []MockupStorable{{1, "replaced"}}
+
+	for _, step := range failurePoints {
+		t.Run(step, func(t *testing.T) {
+			path, before := seedDatabase(t, original)
+
+			db := New[MockupStorable](path)
+			db.fs = &faultyFileSystem{failAt: step, crash: true}
+			if err := db.Open(); err != nil {
+				t.Fatal(err)
+			}
+			if err := db.WriteAll(replacement); err == nil {
+				t.Fatal("expected write to fail")
+			}
+			db.Close()
+
+			after, _ := os.ReadFile(path)
+			if string(after) != string(before) {
+				t.Errorf("database changed after crash at %s:\n%s", step, after)
+			}
+			items := readItems(t, path)
+			if len(items) != len(original) || items[0].StringField != "first" {
+				t.Errorf("expected original items, received %v", items)
+			}
+		})
+	}
+}
+
+func TestWriteAllDirSyncFailureKeepsNewContents(t *testing.T) {
+	path, _ := seedDatabase(t, []MockupStorable{{1, "first"}})
+
+	db := New[MockupStorable](path)
+	db.fs = &faultyFileSystem{failAt: "syncdir"}
+	if err := db.Open(); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.WriteAll([]MockupStorable{{1, "replaced"}}); err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+	items := readItems(t, path)
+	if len(items) != 1 || items[0].StringField != "replaced" {
+		t.Errorf("expected replaced item, received %v", items)
+	}
+}
+
+func TestWriteAllReopenFailureKeepsNewContents(t *testing.T) {
+	path, _ := seedDatabase(t, []MockupStorable{{1, "first"}})
+
+	db := New[MockupStorable](path)
+	db.fs = &faultyFileSystem{failAt: "reopen"}
+	if err := db.Open(); err != nil {
+		t.Fatal(err)
+	}
+	err := db.WriteAll([]MockupStorable{{1, "replaced"}})
+	var reopenErr *ErrReopen
+	if !errors.As(err, &reopenErr) || !errors.Is(err, errInjected) {
+		t.Fatalf("expected ErrReopen, received %v", err)
+	}
+	db.Close()
+	items := readItems(t, path)
+	if len(items) != 1 || items[0].StringField != "replaced" {
+		t.Errorf("expected replaced item, received %v", items)
+	}
+}
+
+func TestWriteAllKeepsHandleUsable(t *testing.T) {
+	path, _ := seedDatabase(t, nil)
+
+	db := New[MockupStorable](path)
+	if err := db.Open(); err != nil {
+		t.Fatal(err)
+	}
+	for i := range 3 {
+		if _, err := db.Append(MockupStorable{StringField: "item"}); err != nil {
+			t.Fatalf("append %d: %s", i, err)
+		}
+	}
+	db.Close()
+	if items := readItems(t, path); len(items) != 3 {
+		t.Errorf("expected 3 items, received %d", len(items))
+	}
+	if left := leftoverTempFiles(t, path); len(left) != 0 {
+		t.Errorf("temp files left behind: %v", left)
+	}
+}
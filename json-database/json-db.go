@@ -1,11 +1,15 @@
 package jsondatabase
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
 	"strings"
+	"time"
 )
 
 type NotAJsonError struct {
@@ -23,32 +27,141 @@ type Storable interface {
 	SetID(id int) Storable
 }
 
+const defaultPath = "db.json"
+
+const defaultFileMode os.FileMode = 0644
+
+// Option configures a Database created with New.
+type Option func(*options)
+
+type options struct {
+	fileMode    os.FileMode
+	lockTimeout time.Duration
+	migrations  []Migration
+}
+
+// WithFileMode sets the permissions used when the database file is created.
+func WithFileMode(mode os.FileMode) Option {
+	return func(o *options) {
+		o.fileMode = mode
+	}
+}
+
+// envelope is the layout of the database file. NextID only ever grows, so IDs
+// of deleted items are never handed out again.
+type envelope[T any] struct {
+	Version int `json:"version"`
+	NextID  int `json:"next_id"`
+	Items   []T `json:"items"`
+}
+
 type Database[T Storable] struct {
-	path string
-	file *os.File
+	path     string
+	file     *os.File
+	lock     *os.File
+	readOnly bool
+	nextID   int
+	fs       fileSystem
+	options
+}
+
+// New returns a database stored at path. An empty path means db.json in the
+// working directory. Missing parent directories are created on Open.
+func New[T Storable](path string, opts ...Option) *Database[T] {
+	db := &Database[T]{path: path}
+	for _, opt := range opts {
+		opt(&db.options)
+	}
+	return db
+}
+
+// Path returns the location of the database file.
+func (db *Database[T]) Path() string {
+	if db.path == "" {
+		return defaultPath
+	}
+	return db.path
 }
 
+// Open opens the database for reading and writing. It holds an exclusive lock
+// until Close, so read-modify-write cycles in other processes wait their turn.
 func (db *Database[T]) Open() error {
-	file,err := getDb(db.path)
-    if err != nil {
-        return err
-    }
-    db.file = file   
-    return nil
+	return db.open(true)
+}
+
+// OpenReadOnly opens the database under a shared lock, which other readers
+// can hold at the same time. Writes fail with ErrReadOnly.
+func (db *Database[T]) OpenReadOnly() error {
+	return db.open(false)
+}
+
+func (db *Database[T]) open(exclusive bool) error {
+	file, lock, err := openLocked(db.path, db.options, exclusive)
+	if err != nil {
+		return err
+	}
+	db.file = file
+	db.lock = lock
+	db.readOnly = !exclusive
+	if exclusive {
+		if _, upgradeErr := db.upgrade(false); upgradeErr != nil {
+			db.Close()
+			return upgradeErr
+		}
+	}
+	return nil
+}
+
+// openLocked locks and opens the file at path, creating it if needed.
+func openLocked(path string, opts options, exclusive bool) (file, lock *os.File, err error) {
+	if pathErr := checkPath(path); pathErr != nil {
+		return nil, nil, pathErr
+	}
+	if path == "" {
+		path = defaultPath
+	}
+	lock, lockErr := acquireLock(path, exclusive, opts.timeout())
+	if lockErr != nil {
+		return nil, nil, lockErr
+	}
+	file, openErr := getDb(path, opts.mode())
+	if openErr != nil {
+		releaseLock(lock)
+		return nil, nil, openErr
+	}
+	return file, lock, nil
+}
+
+func (o options) mode() os.FileMode {
+	if o.fileMode == 0 {
+		return defaultFileMode
+	}
+	return o.fileMode
 }
 
 func (db *Database[T]) Close() error {
-	return db.file.Close()
+	closeErr := closeLocked(db.file, db.lock)
+	db.lock = nil
+	return closeErr
 }
 
+func closeLocked(file, lock *os.File) error {
+	closeErr := file.Close()
+	if lock != nil {
+		if lockErr := releaseLock(lock); closeErr == nil {
+			closeErr = lockErr
+		}
+	}
+	return closeErr
+}
 
-func (db *Database[T]) Append(item T) (T,error) {
-    var zero T
+func (db *Database[T]) Append(item T) (T, error) {
+	var zero T
 	items, getErr := db.GetAll()
-    if getErr != nil {
-        return zero,getErr
-    }
-	lastId := item.GetID()
+	if getErr != nil {
+		return zero, getErr
+	}
+	lastId := max(item.GetID(), db.nextID-1)
 	for _, other := range items {
 		if other.GetID() > lastId {
 			lastId = other.GetID()
@@ -59,50 +172,106 @@ func (db *Database[T]) Append(item T) (T,error) {
 	// Ideally, we would set the id using pointers.
 	modifiedItem := item.SetID(lastId + 1)
 	items = append(items, modifiedItem.(T))
-	db.WriteAll(items)
-	return modifiedItem.(T),nil
+	if writeErr := db.WriteAll(items); writeErr != nil {
+		return zero, writeErr
+	}
+	return modifiedItem.(T), nil
 }
 
-func (db *Database[T]) GetAll() ([]T,error) {
-	decoder := json.NewDecoder(db.file)
-	// decoder.Token()
+// GetAll reads every item. Files from older schema versions are migrated in
+// memory; opening the database for writing upgrades them on disk.
+func (db *Database[T]) GetAll() ([]T, error) {
+	data, readErr := db.readFile()
+	if readErr != nil {
+		return nil, readErr
+	}
+	contents, decodeErr := decodeEnvelope(data, db.schemaVersion())
+	if decodeErr != nil {
+		return nil, decodeErr
+	}
+	raw, _, migrateErr := db.migrate(db.Path(), contents.Version, contents.Items)
+	if migrateErr != nil {
+		return nil, migrateErr
+	}
+	items, itemsErr := decodeItems[T](raw)
+	if itemsErr != nil {
+		return nil, itemsErr
+	}
 
-	entries := []T{}
+	db.nextID = max(contents.NextID, nextID(items))
+	return items, nil
+}
 
-	for decoder.More() {
-		err := decoder.Decode(&entries)
-		if err != nil {
-			return nil, err
+func (db *Database[T]) readFile() ([]byte, error) {
+	if _, seekErr := db.file.Seek(0, io.SeekStart); seekErr != nil {
+		return nil, seekErr
+	}
+	return io.ReadAll(db.file)
+}
+
+// decodeEnvelope splits a database file into its metadata and raw items. A
+// bare array predates the envelope and is schema version 0; an empty file is
+// a new database at the latest version.
+func decodeEnvelope(data []byte, latest int) (envelope[json.RawMessage], error) {
+	contents := envelope[json.RawMessage]{Version: latest}
+	data = bytes.TrimSpace(data)
+	switch {
+	case len(data) == 0:
+	case data[0] == '[':
+		contents.Version = 0
+		if err := json.Unmarshal(data, &contents.Items); err != nil {
+			return contents, err
+		}
+	default:
+		if err := json.Unmarshal(data, &contents); err != nil {
+			return contents, err
 		}
 	}
+	if contents.Items == nil {
+		contents.Items = []json.RawMessage{}
+	}
+	return contents, nil
+}
 
-	return entries,nil
+func decodeItems[T Storable](raw []json.RawMessage) ([]T, error) {
+	items := make([]T, len(raw))
+	for i, data := range raw {
+		if err := json.Unmarshal(data, &items[i]); err != nil {
+			return nil, err
+		}
+	}
+	return items, nil
 }
 
 func (db *Database[T]) WriteAll(items []T) error {
-	data, marshallErr := json.MarshalIndent(items, "", "    ")
-	if marshallErr != nil {
-        return marshallErr
+	if db.readOnly {
+		return ErrReadOnly
 	}
-
-	clearErr := db.Clear()
-    if clearErr != nil {
-        return clearErr
-    }
-	
-    _, writingErr := db.file.WriteAt(data, 0)
-	if writingErr != nil {
-		return writingErr
+	if items == nil {
+		items = []T{}
+	}
+	db.nextID = max(db.nextID, nextID(items))
+	file := envelope[T]{Version: db.schemaVersion(), NextID: db.nextID, Items: items}
+	data, marshallErr := json.MarshalIndent(file, "", "    ")
+	if marshallErr != nil {
+		return marshallErr
 	}
 
-	return nil
+	return db.replaceFile(data)
 }
 
-func (db *Database[T]) Clear() error{
-	if db.path == "" {
-		db.path = "db.json"
+// Clear removes every item but keeps the ID sequence, so the IDs of the
+// removed items are not handed out again. Like WriteAll, it never leaves a
+// partially written file behind.
+func (db *Database[T]) Clear() error {
+	if db.readOnly {
+		return ErrReadOnly
 	}
-	return os.Truncate(db.path, 0)
+	// Reading brings the sequence up to date with the file.
+	if _, readErr := db.GetAll(); readErr != nil {
+		return readErr
+	}
+	return db.WriteAll(nil)
 }
 
 func isValidPath(pth string) error {
@@ -112,18 +281,24 @@ func isValidPath(pth string) error {
 	return &NotAJsonError{pth}
 }
 
-func getDb(customPath string) (*os.File,error) {
+// checkPath validates a custom database path and creates its directory.
+func checkPath(customPath string) error {
 	if customPath == "" {
-		customPath = "db.json"
-	} else {
-		pathErr := isValidPath(customPath)
-		if pathErr != nil {
-			return nil, pathErr
-		}
+		return nil
+	}
+	if pathErr := isValidPath(customPath); pathErr != nil {
+		return pathErr
+	}
+	return os.MkdirAll(filepath.Dir(customPath), 0755)
+}
+
+func getDb(customPath string, mode os.FileMode) (*os.File, error) {
+	if customPath == "" {
+		customPath = defaultPath
 	}
-	file, openFileErr := os.OpenFile(customPath, os.O_RDWR|os.O_CREATE, 0644)
+	file, openFileErr := os.OpenFile(customPath, os.O_RDWR|os.O_CREATE, mode)
 	if openFileErr != nil {
 		return nil, openFileErr
 	}
-	return file,nil
+	return file, nil
 }
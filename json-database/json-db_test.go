@@ -1,8 +1,11 @@
 package jsondatabase
 
 import (
+	"encoding/json"
 	"errors"
 	"os"
+	"path/filepath"
+	"runtime"
 	"testing"
 )
 
@@ -20,14 +23,20 @@ func (ms MockupStorable) GetID() int {
 	return ms.Id
 }
 
+// removeDatabase deletes a test database together with its lock file.
+func removeDatabase(path string) error {
+	os.Remove(path + ".lock")
+	return os.Remove(path)
+}
+
 func TestOpenEmpty(t *testing.T) {
 	db := Database[MockupStorable]{}
 
 	got := db.Open()
-	defer db.file.Close()
+	defer db.Close()
 
 	t.Cleanup(func() {
-		err := os.Remove("./db.json")
+		err := removeDatabase("./db.json")
 		if err != nil {
 			panic(err)
 		}
@@ -59,10 +68,10 @@ func TestOpenWithInvalidPath(t *testing.T) {
 func TestOpenWithValidPath(t *testing.T) {
 	db := Database[MockupStorable]{path: "custompath.json"}
 	got := db.Open()
-	defer db.file.Close()
+	defer db.Close()
 
 	t.Cleanup(func() {
-		err := os.Remove("./custompath.json")
+		err := removeDatabase("./custompath.json")
 		if err != nil {
 			panic(err)
 		}
@@ -84,7 +93,7 @@ func TestWriteAllSingleItem(t *testing.T) {
 	defer db.Close()
 
 	t.Cleanup(func() {
-		err := os.Remove("./writeAllDB.json")
+		err := removeDatabase("./writeAllDB.json")
 		if err != nil {
 			panic(err)
 		}
@@ -104,7 +113,7 @@ func TestWriteAllMultipleItems(t *testing.T) {
 	defer db.Close()
 
 	t.Cleanup(func() {
-		err := os.Remove("./writeAllDB.json")
+		err := removeDatabase("./writeAllDB.json")
 		if err != nil {
 			panic(err)
 		}
@@ -125,7 +134,7 @@ func TestWriteAllEmpty(t *testing.T) {
 	defer db.Close()
 
 	t.Cleanup(func() {
-		err := os.Remove("./writeAllDB.json")
+		err := removeDatabase("./writeAllDB.json")
 		if err != nil {
 			panic(err)
 		}
@@ -144,7 +153,7 @@ func TestClear(t *testing.T) {
 	defer db.Close()
 
 	t.Cleanup(func() {
-		err := os.Remove("./db.json")
+		err := removeDatabase("./db.json")
 		if err != nil {
 			panic(err)
 		}
@@ -158,16 +167,23 @@ func TestClear(t *testing.T) {
 		t.Error(err.Error())
 	}
 
-	db.Clear()
+	if err := db.Clear(); err != nil {
+		t.Fatal(err)
+	}
 
-	fileStat, err := db.file.Stat()
+	items, err = db.GetAll()
 	if err != nil {
 		t.Error(err.Error())
 	}
-	filledSize := fileStat.Size()
-
-	if filledSize != 0 {
-		t.Errorf("cleaning database failed. Expected 0 bytes inside the file, but received %d", filledSize)
+	if len(items) != 0 {
+		t.Errorf("cleaning database failed. Expected no items, but received %v", items)
+	}
+	appended, err := db.Append(MockupStorable{0, "test3"})
+	if err != nil {
+		t.Error(err.Error())
+	}
+	if appended.Id != 2 {
+		t.Errorf("expected the ID sequence kept after clearing, but received ID %d", appended.Id)
 	}
 }
 
@@ -177,7 +193,7 @@ func TestAppend(t *testing.T) {
 	defer db.Close()
 
 	t.Cleanup(func() {
-		err := os.Remove("./db.json")
+		err := removeDatabase("./db.json")
 		if err != nil {
 			panic(err)
 		}
@@ -200,7 +216,7 @@ func TestGetAll(t *testing.T) {
 	defer db.Close()
 
 	t.Cleanup(func() {
-		err := os.Remove("./db.json")
+		err := removeDatabase("./db.json")
 		if err != nil {
 			panic(err)
 		}
@@ -221,3 +237,79 @@ func TestGetAll(t *testing.T) {
 		t.Errorf("expected %d items but received %d",len(items),len(result))
 	}
 }
+
+func TestNewCreatesParentDirs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "a", "b", "items.json")
+	db := New[MockupStorable](path, WithFileMode(0600))
+	if err := db.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("open should create %s: %s", path, err)
+	}
+	if runtime.GOOS != "windows" && info.Mode().Perm() != 0600 {
+		t.Errorf("expected file mode 0600, received %o", info.Mode().Perm())
+	}
+	if db.Path() != path {
+		t.Errorf("expected path %s, received %s", path, db.Path())
+	}
+}
+
+func TestGetAllReadsLegacyArray(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "legacy.json")
+	legacy := `[{"Id": 1, "StringField": "one"}, {"Id": 4, "StringField": "four"}]`
+	if err := os.WriteFile(path, []byte(legacy), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	db := New[MockupStorable](path)
+	err := db.Update(func(tx *Tx[MockupStorable]) error {
+		if len(tx.All()) != 2 {
+			t.Errorf("expected 2 legacy items, received %d", len(tx.All()))
+		}
+		if err := tx.Delete(4); err != nil {
+			return err
+		}
+		inserted, err := tx.Insert(MockupStorable{StringField: "five"})
+		if inserted.Id != 5 {
+			t.Errorf("expected id 5, received %d", inserted.Id)
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, _ := os.ReadFile(path)
+	var file envelope[MockupStorable]
+	if err := json.Unmarshal(data, &file); err != nil {
+		t.Fatalf("database should be rewritten as an envelope: %s\n%s", err, data)
+	}
+	if file.Version != envelopeVersion || file.NextID != 6 || len(file.Items) != 2 {
+		t.Errorf("unexpected envelope: %+v", file)
+	}
+}
+
+func TestDeletedIDsAreNotReused(t *testing.T) {
+	db := New[MockupStorable](filepath.Join(t.TempDir(), "sequence.json"))
+	db.Update(func(tx *Tx[MockupStorable]) error {
+		tx.Insert(MockupStorable{StringField: "one"})
+		tx.Insert(MockupStorable{StringField: "two"})
+		return nil
+	})
+	db.Update(func(tx *Tx[MockupStorable]) error {
+		return tx.Delete(2)
+	})
+
+	var inserted MockupStorable
+	db.Update(func(tx *Tx[MockupStorable]) error {
+		inserted, _ = tx.Insert(MockupStorable{StringField: "three"})
+		return nil
+	})
+	if inserted.Id != 3 {
+		t.Errorf("expected id 3 after deleting id 2, received %d", inserted.Id)
+	}
+}
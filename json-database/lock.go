@@ -0,0 +1,70 @@
+package jsondatabase
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+const defaultLockTimeout = 5 * time.Second
+
+const lockRetryInterval = 10 * time.Millisecond
+
+// ErrLocked is returned by Open when another process holds the database lock
+// for longer than the lock timeout.
+var ErrLocked = errors.New("database is locked")
+
+// ErrReadOnly is returned when writing to a database opened with OpenReadOnly.
+var ErrReadOnly = errors.New("database is opened read only")
+
+// WithLockTimeout sets how long Open waits for another process to release the
+// database before giving up with ErrLocked.
+func WithLockTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.lockTimeout = timeout
+	}
+}
+
+func (o options) timeout() time.Duration {
+	if o.lockTimeout == 0 {
+		return defaultLockTimeout
+	}
+	return o.lockTimeout
+}
+
+// acquireLock takes an advisory lock on a file next to the database. The
+// database file itself cannot carry the lock because writes replace it.
+func acquireLock(dbPath string, exclusive bool, timeout time.Duration) (*os.File, error) {
+	lockPath := dbPath + ".lock"
+	file, err := os.OpenFile(lockPath, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		locked, lockErr := tryLock(file, exclusive)
+		if lockErr != nil {
+			file.Close()
+			return nil, lockErr
+		}
+		if locked {
+			return file, nil
+		}
+		if time.Now().After(deadline) {
+			file.Close()
+			return nil, fmt.Errorf("%w: %s is in use by another process (waited %s)", ErrLocked, dbPath, timeout)
+		}
+		time.Sleep(lockRetryInterval)
+	}
+}
+
+func releaseLock(file *os.File) error {
+	unlockErr := unlock(file)
+	closeErr := file.Close()
+	if unlockErr != nil {
+		return unlockErr
+	}
+	return closeErr
+}
@@ -0,0 +1,14 @@
+//go:build !unix && !windows
+
+package jsondatabase
+
+import "os"
+
+// Platforms without advisory locks run unlocked.
+func tryLock(file *os.File, exclusive bool) (bool, error) {
+	return true, nil
+}
+
+func unlock(file *os.File) error {
+	return nil
+}
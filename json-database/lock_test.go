@@ -0,0 +1,169 @@
+package jsondatabase
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	helperPathEnv    = "JSONDB_HELPER_PATH"
+	helperAppendsEnv = "JSONDB_HELPER_APPENDS"
+)
+
+func appendMany(path string, appends int) error {
+	for i := range appends {
+		db := New[MockupStorable](path, WithLockTimeout(time.Minute))
+		if err := db.Open(); err != nil {
+			return err
+		}
+		_, appendErr := db.Append(MockupStorable{StringField: strconv.Itoa(i)})
+		closeErr := db.Close()
+		if appendErr != nil {
+			return appendErr
+		}
+		if closeErr != nil {
+			return closeErr
+		}
+	}
+	return nil
+}
+
+func checkUniqueIDs(t *testing.T, path string, expected int) {
+	t.Helper()
+	items := readItems(t, path)
+	if len(items) != expected {
+		t.Errorf("expected %d items, received %d", expected, len(items))
+	}
+	seen := map[int]bool{}
+	for _, item := range items {
+		if seen[item.Id] {
+			t.Errorf("id %d was assigned twice", item.Id)
+		}
+		seen[item.Id] = true
+	}
+}
+
+func TestConcurrentAppenders(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "stress.json")
+	const workers, appends = 16, 20
+
+	var wg sync.WaitGroup
+	errs := make(chan error, workers)
+	for range workers {
+		wg.Go(func() {
+			if err := appendMany(path, appends); err != nil {
+				errs <- err
+			}
+		})
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+
+	checkUniqueIDs(t, path, workers*appends)
+}
+
+// TestHelperAppender is not a real test. TestConcurrentProcessAppenders runs
+// it in child processes.
+func TestHelperAppender(t *testing.T) {
+	path := os.Getenv(helperPathEnv)
+	if path == "" {
+		t.Skip("helper process only")
+	}
+	appends, _ := strconv.Atoi(os.Getenv(helperAppendsEnv))
+	if err := appendMany(path, appends); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func TestConcurrentProcessAppenders(t *testing.T) {
+	if testing.Short() {
+		t.Skip("spawns processes")
+	}
+	path := filepath.Join(t.TempDir(), "processes.json")
+	const processes, appends = 6, 15
+
+	cmds := make([]*exec.Cmd, processes)
+	for i := range cmds {
+		cmd := exec.Command(os.Args[0], "-test.run=^TestHelperAppender$")
+		cmd.Env = append(os.Environ(), helperPathEnv+"="+path, helperAppendsEnv+"="+strconv.Itoa(appends))
+		cmd.Stderr = os.Stderr
+		if err := cmd.Start(); err != nil {
+			t.Fatal(err)
+		}
+		cmds[i] = cmd
+	}
+	for _, cmd := range cmds {
+		if err := cmd.Wait(); err != nil {
+			t.Errorf("appender process failed: %s", err)
+		}
+	}
+
+	checkUniqueIDs(t, path, processes*appends)
+}
+
+func TestOpenTimesOutWhenLocked(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "locked.json")
+	holder := New[MockupStorable](path)
+	if err := holder.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer holder.Close()
+
+	waiter := New[MockupStorable](path, WithLockTimeout(50*time.Millisecond))
+	err := waiter.OpenReadOnly()
+	if !errors.Is(err, ErrLocked) {
+		t.Fatalf("expected ErrLocked, received %v", err)
+	}
+}
+
+func TestSharedLocksCoexist(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "shared.json")
+	first := New[MockupStorable](path, WithLockTimeout(50*time.Millisecond))
+	second := New[MockupStorable](path, WithLockTimeout(50*time.Millisecond))
+
+	if err := first.OpenReadOnly(); err != nil {
+		t.Fatal(err)
+	}
+	defer first.Close()
+	if err := second.OpenReadOnly(); err != nil {
+		t.Fatalf("second reader should not wait: %s", err)
+	}
+	defer second.Close()
+
+	if err := second.WriteAll(nil); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("expected ErrReadOnly, received %v", err)
+	}
+
+	writer := New[MockupStorable](path, WithLockTimeout(50*time.Millisecond))
+	if err := writer.Open(); !errors.Is(err, ErrLocked) {
+		t.Errorf("writer should wait for readers, received %v", err)
+	}
+}
+
+func TestLockReleasedOnClose(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "release.json")
+	first := New[MockupStorable](path)
+	if err := first.Open(); err != nil {
+		t.Fatal(err)
+	}
+	if err := first.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	second := New[MockupStorable](path, WithLockTimeout(50*time.Millisecond))
+	if err := second.Open(); err != nil {
+		t.Fatalf("lock should be free after Close: %s", err)
+	}
+	second.Close()
+}
@@ -0,0 +1,47 @@
+//go:build windows
+
+package jsondatabase
+
+import (
+	"errors"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	lockfileFailImmediately = 0x1
+	lockfileExclusiveLock   = 0x2
+	errorLockViolation      = syscall.Errno(33)
+)
+
+var (
+	kernel32         = syscall.NewLazyDLL("kernel32.dll")
+	procLockFileEx   = kernel32.NewProc("LockFileEx")
+	procUnlockFileEx = kernel32.NewProc("UnlockFileEx")
+)
+
+func tryLock(file *os.File, exclusive bool) (bool, error) {
+	flags := uintptr(lockfileFailImmediately)
+	if exclusive {
+		flags |= lockfileExclusiveLock
+	}
+	var overlapped syscall.Overlapped
+	r1, _, err := procLockFileEx.Call(file.Fd(), flags, 0, 1, 0, uintptr(unsafe.Pointer(&overlapped)))
+	if r1 != 0 {
+		return true, nil
+	}
+	if errors.Is(err, errorLockViolation) {
+		return false, nil
+	}
+	return false, err
+}
+
+func unlock(file *os.File) error {
+	var overlapped syscall.Overlapped
+	r1, _, err := procUnlockFileEx.Call(file.Fd(), 0, 1, 0, uintptr(unsafe.Pointer(&overlapped)))
+	if r1 == 0 {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,322 @@
+package jsondatabase
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+)
+
+const defaultLogPath = "db.jsonl"
+
+const (
+	opVersion = "version"
+	opPut     = "put"
+	opDelete  = "delete"
+)
+
+// Log is an append-only JSON Lines store. Each change is appended as one line
+// and the items are rebuilt by replaying the file, so a write never touches
+// what is already on disk.
+type Log[T Storable] struct {
+	path     string
+	file     *os.File
+	lock     *os.File
+	readOnly bool
+	options
+}
+
+type logRecord[T any] struct {
+	Op      string `json:"op"`
+	ID      int    `json:"id,omitempty"`
+	Version int    `json:"version,omitempty"`
+	NextID  int    `json:"next_id,omitempty"`
+	Item    *T     `json:"item,omitempty"`
+}
+
+// logContents is the state rebuilt from a log with the items still undecoded.
+type logContents struct {
+	data    []byte
+	version int
+	next    int
+	// size is the length of the valid part of the file. A last line without a
+	// newline is the remains of an interrupted append and is ignored.
+	size  int64
+	ids   []int
+	items []json.RawMessage
+}
+
+// NewLog returns a log stored at path. An empty path means db.jsonl in the
+// working directory.
+func NewLog[T Storable](path string, opts ...Option) *Log[T] {
+	l := &Log[T]{path: path}
+	for _, opt := range opts {
+		opt(&l.options)
+	}
+	return l
+}
+
+// Path returns the location of the log file.
+func (l *Log[T]) Path() string {
+	if l.path == "" {
+		return defaultLogPath
+	}
+	return l.path
+}
+
+func (l *Log[T]) open(exclusive bool) error {
+	file, lock, err := openLocked(l.Path(), l.options, exclusive)
+	if err != nil {
+		return err
+	}
+	l.file = file
+	l.lock = lock
+	l.readOnly = !exclusive
+	return nil
+}
+
+func (l *Log[T]) close() error {
+	closeErr := closeLocked(l.file, l.lock)
+	l.lock = nil
+	return closeErr
+}
+
+// Update works like Database.Update. The changes made by fn are appended to
+// the log as put and delete records. Logs from older schema versions are
+// upgraded first.
+func (l *Log[T]) Update(fn func(tx *Tx[T]) error) (err error) {
+	if openErr := l.open(true); openErr != nil {
+		return openErr
+	}
+	defer func() {
+		if closeErr := l.close(); err == nil {
+			err = closeErr
+		}
+	}()
+
+	if _, upgradeErr := l.upgrade(false); upgradeErr != nil {
+		return upgradeErr
+	}
+	contents, items, readErr := l.readItems()
+	if readErr != nil {
+		return readErr
+	}
+	before := slices.Clone(items)
+	tx := &Tx[T]{items: items, next: contents.next}
+	if fnErr := fn(tx); fnErr != nil {
+		return fnErr
+	}
+	if !tx.dirty {
+		return nil
+	}
+
+	records, diffErr := diffRecords(before, tx.items)
+	if diffErr != nil {
+		return diffErr
+	}
+	if contents.size == 0 {
+		header := logRecord[T]{Op: opVersion, Version: l.schemaVersion()}
+		records = append([]logRecord[T]{header}, records...)
+	}
+	return l.append(records, contents.size)
+}
+
+// View works like Database.View. Logs from older schema versions are
+// migrated in memory.
+func (l *Log[T]) View(fn func(tx *Tx[T]) error) (err error) {
+	if openErr := l.open(false); openErr != nil {
+		return openErr
+	}
+	defer func() {
+		if closeErr := l.close(); err == nil {
+			err = closeErr
+		}
+	}()
+
+	contents, items, readErr := l.readItems()
+	if readErr != nil {
+		return readErr
+	}
+	return fn(&Tx[T]{items: items, next: contents.next, readOnly: true})
+}
+
+// Migrate works like Database.Migrate. An upgrade rewrites the log with one
+// record per item.
+func (l *Log[T]) Migrate(dryRun bool) (*MigrationReport, error) {
+	if openErr := l.open(true); openErr != nil {
+		return nil, openErr
+	}
+	defer l.close()
+	return l.upgrade(dryRun)
+}
+
+func (l *Log[T]) upgrade(dryRun bool) (*MigrationReport, error) {
+	contents, readErr := l.read()
+	if readErr != nil {
+		return nil, readErr
+	}
+	report := &MigrationReport{
+		Path:        l.Path(),
+		FromVersion: contents.version,
+		ToVersion:   l.schemaVersion(),
+		Items:       len(contents.items),
+	}
+	raw, steps, migrateErr := l.migrate(l.Path(), contents.version, contents.items)
+	if migrateErr != nil {
+		return nil, migrateErr
+	}
+	report.Steps = steps
+	if dryRun || report.UpToDate() {
+		return report, nil
+	}
+
+	items, itemsErr := decodeItems[T](raw)
+	if itemsErr != nil {
+		return nil, itemsErr
+	}
+	var data bytes.Buffer
+	encoder := json.NewEncoder(&data)
+	header := logRecord[T]{Op: opVersion, Version: l.schemaVersion(), NextID: contents.next}
+	if err := encoder.Encode(header); err != nil {
+		return nil, err
+	}
+	for i, item := range items {
+		record := logRecord[json.RawMessage]{Op: opPut, ID: item.GetID(), Item: &raw[i]}
+		if err := encoder.Encode(record); err != nil {
+			return nil, err
+		}
+	}
+
+	backup, backupErr := writeBackup(l.Path(), contents.version, contents.data, l.mode())
+	if backupErr != nil {
+		return nil, backupErr
+	}
+	report.Backup = backup
+	return report, replaceFile(osFileSystem{}, l.Path(), &l.file, data.Bytes(), l.mode())
+}
+
+func (l *Log[T]) readItems() (logContents, []T, error) {
+	contents, readErr := l.read()
+	if readErr != nil {
+		return contents, nil, readErr
+	}
+	raw, _, migrateErr := l.migrate(l.Path(), contents.version, contents.items)
+	if migrateErr != nil {
+		return contents, nil, migrateErr
+	}
+	items, itemsErr := decodeItems[T](raw)
+	return contents, items, itemsErr
+}
+
+// read replays the log. Logs written before version records existed start at
+// envelopeVersion. The next free ID is past every ID the log has ever held.
+func (l *Log[T]) read() (logContents, error) {
+	contents := logContents{version: l.schemaVersion(), items: []json.RawMessage{}}
+	if _, seekErr := l.file.Seek(0, io.SeekStart); seekErr != nil {
+		return contents, seekErr
+	}
+	data, readErr := io.ReadAll(l.file)
+	if readErr != nil {
+		return contents, readErr
+	}
+	contents.data = data
+	if len(data) > 0 {
+		contents.version = envelopeVersion
+	}
+
+	lastID := 0
+	rest := data
+	for lineNumber := 1; ; lineNumber++ {
+		line, remaining, complete := bytes.Cut(rest, []byte("\n"))
+		if !complete {
+			break
+		}
+		rest = remaining
+		contents.size += int64(len(line)) + 1
+
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+		var record logRecord[json.RawMessage]
+		if err := json.Unmarshal(line, &record); err != nil {
+			return contents, fmt.Errorf("%s line %d: %w", l.Path(), lineNumber, err)
+		}
+		lastID = max(lastID, record.ID, record.NextID-1)
+
+		index := slices.Index(contents.ids, record.ID)
+		switch {
+		case record.Op == opVersion:
+			contents.version = record.Version
+		case record.Op == opPut && record.Item != nil && index == -1:
+			contents.ids = append(contents.ids, record.ID)
+			contents.items = append(contents.items, *record.Item)
+		case record.Op == opPut && record.Item != nil:
+			contents.items[index] = *record.Item
+		case record.Op == opDelete && index != -1:
+			contents.ids = slices.Delete(contents.ids, index, index+1)
+			contents.items = slices.Delete(contents.items, index, index+1)
+		case record.Op != opDelete:
+			return contents, fmt.Errorf("%s line %d: invalid record", l.Path(), lineNumber)
+		}
+	}
+	contents.next = lastID + 1
+	return contents, nil
+}
+
+// diffRecords returns the records that turn before into after.
+func diffRecords[T Storable](before, after []T) ([]logRecord[T], error) {
+	previous := map[int][]byte{}
+	for _, item := range before {
+		data, err := json.Marshal(item)
+		if err != nil {
+			return nil, err
+		}
+		previous[item.GetID()] = data
+	}
+
+	records := []logRecord[T]{}
+	for _, item := range after {
+		data, err := json.Marshal(item)
+		if err != nil {
+			return nil, err
+		}
+		old, existed := previous[item.GetID()]
+		delete(previous, item.GetID())
+		if existed && bytes.Equal(old, data) {
+			continue
+		}
+		records = append(records, logRecord[T]{Op: opPut, ID: item.GetID(), Item: &item})
+	}
+	for _, item := range before {
+		if _, deleted := previous[item.GetID()]; deleted {
+			records = append(records, logRecord[T]{Op: opDelete, ID: item.GetID()})
+		}
+	}
+	return records, nil
+}
+
+// append writes records after the first size bytes of the log, dropping any
+// partial line an earlier crash left behind.
+func (l *Log[T]) append(records []logRecord[T], size int64) error {
+	if l.readOnly {
+		return ErrReadOnly
+	}
+	var data bytes.Buffer
+	encoder := json.NewEncoder(&data)
+	for _, record := range records {
+		if err := encoder.Encode(record); err != nil {
+			return err
+		}
+	}
+
+	if err := l.file.Truncate(size); err != nil {
+		return err
+	}
+	if _, err := l.file.WriteAt(data.Bytes(), size); err != nil {
+		return err
+	}
+	return l.file.Sync()
+}
@@ -0,0 +1,104 @@
+package jsondatabase
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func readLog(t *testing.T, l *Log[MockupStorable]) []MockupStorable {
+	t.Helper()
+	var items []MockupStorable
+	err := l.View(func(tx *Tx[MockupStorable]) error {
+		items = tx.All()
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return items
+}
+
+func TestLogReplaysChanges(t *testing.T) {
+	l := NewLog[MockupStorable](filepath.Join(t.TempDir(), "items.jsonl"))
+
+	err := l.Update(func(tx *Tx[MockupStorable]) error {
+		tx.Insert(MockupStorable{StringField: "first"})
+		tx.Insert(MockupStorable{StringField: "second"})
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = l.Update(func(tx *Tx[MockupStorable]) error {
+		if err := tx.Put(MockupStorable{1, "changed"}); err != nil {
+			return err
+		}
+		return tx.Delete(2)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	items := readLog(t, l)
+	if len(items) != 1 || items[0].StringField != "changed" {
+		t.Errorf("unexpected items after replay: %v", items)
+	}
+
+	data, _ := os.ReadFile(l.Path())
+	if lines := strings.Count(string(data), "\n"); lines != 5 {
+		t.Errorf("expected a version record and 4 changes in the log, found %d lines:\n%s", lines, data)
+	}
+}
+
+func TestLogOnlyAppendsChangedItems(t *testing.T) {
+	l := NewLog[MockupStorable](filepath.Join(t.TempDir(), "items.jsonl"))
+	l.Update(func(tx *Tx[MockupStorable]) error {
+		tx.Insert(MockupStorable{StringField: "first"})
+		return nil
+	})
+	before, _ := os.ReadFile(l.Path())
+
+	l.Update(func(tx *Tx[MockupStorable]) error {
+		item, _ := tx.Get(1)
+		return tx.Put(item)
+	})
+
+	after, _ := os.ReadFile(l.Path())
+	if string(before) != string(after) {
+		t.Errorf("putting an unchanged item should not append:\n%s", after)
+	}
+}
+
+func TestLogIgnoresTornTail(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "items.jsonl")
+	l := NewLog[MockupStorable](path)
+	l.Update(func(tx *Tx[MockupStorable]) error {
+		tx.Insert(MockupStorable{StringField: "first"})
+		return nil
+	})
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.WriteString(`{"op":"put","id":2,"item":{"Id":2,"Str`)
+	file.Close()
+
+	if items := readLog(t, l); len(items) != 1 {
+		t.Fatalf("torn record should be ignored, received %v", items)
+	}
+
+	err = l.Update(func(tx *Tx[MockupStorable]) error {
+		_, err := tx.Insert(MockupStorable{StringField: "second"})
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	items := readLog(t, l)
+	if len(items) != 2 || items[1].StringField != "second" {
+		t.Errorf("append after torn tail should succeed, received %v", items)
+	}
+}
@@ -0,0 +1,217 @@
+package jsondatabase
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+)
+
+// Migration upgrades stored items from schema Version-1 to Version. Up gets
+// the items as raw JSON so it can change field types, not just values.
+type Migration struct {
+	Version     int
+	Description string
+	Up          func(items []json.RawMessage) ([]json.RawMessage, error)
+}
+
+// envelopeVersion is the schema version of the first envelope format. Files
+// holding a bare array are version 0, and migrations registered with
+// WithMigrations continue from envelopeVersion+1.
+const envelopeVersion = 1
+
+var builtinMigrations = []Migration{
+	{
+		Version:     envelopeVersion,
+		Description: "wrap items in a versioned envelope with a persistent next_id",
+		Up: func(items []json.RawMessage) ([]json.RawMessage, error) {
+			return items, nil
+		},
+	},
+}
+
+// WithMigrations registers the schema migrations of the stored type. Their
+// versions must follow each other starting at 2. Opening a database for
+// writing upgrades older files and keeps a backup of the original.
+func WithMigrations(migrations ...Migration) Option {
+	return func(o *options) {
+		o.migrations = append(o.migrations, migrations...)
+	}
+}
+
+type ErrSchemaTooNew struct {
+	Path      string
+	Found     int
+	Supported int
+}
+
+func (e *ErrSchemaTooNew) Error() string {
+	return fmt.Sprintf("%s has schema version %d but this build only supports up to %d", e.Path, e.Found, e.Supported)
+}
+
+type ErrMigrationOrder struct {
+	Expected int
+	Received int
+}
+
+func (e *ErrMigrationOrder) Error() string {
+	return fmt.Sprintf("migrations must be numbered in order. Expected version %d, received %d", e.Expected, e.Received)
+}
+
+// MigrationStep describes one migration of a MigrationReport.
+type MigrationStep struct {
+	Version     int
+	Description string
+	// Changed is the number of items the migration rewrote.
+	Changed int
+}
+
+// MigrationReport describes an upgrade, or with a dry run the upgrade that
+// would happen.
+type MigrationReport struct {
+	Path        string
+	FromVersion int
+	ToVersion   int
+	Items       int
+	Steps       []MigrationStep
+	// Backup is the copy of the file made before it was rewritten. It is empty
+	// for dry runs and when nothing had to change.
+	Backup string
+}
+
+func (r *MigrationReport) UpToDate() bool {
+	return r.FromVersion == r.ToVersion
+}
+
+func (o options) migrationPlan() ([]Migration, error) {
+	plan := slices.Concat(builtinMigrations, o.migrations)
+	for i, migration := range plan {
+		if migration.Version != i+1 {
+			return nil, &ErrMigrationOrder{i + 1, migration.Version}
+		}
+	}
+	return plan, nil
+}
+
+// schemaVersion is the version files are written with.
+func (o options) schemaVersion() int {
+	return len(builtinMigrations) + len(o.migrations)
+}
+
+// migrate runs the migrations that take items from version to the latest
+// schema.
+func (o options) migrate(path string, version int, items []json.RawMessage) ([]json.RawMessage, []MigrationStep, error) {
+	plan, planErr := o.migrationPlan()
+	if planErr != nil {
+		return nil, nil, planErr
+	}
+	if version > len(plan) {
+		return nil, nil, &ErrSchemaTooNew{path, version, len(plan)}
+	}
+
+	steps := []MigrationStep{}
+	for _, migration := range plan[version:] {
+		migrated, err := migration.Up(slices.Clone(items))
+		if err != nil {
+			return nil, nil, fmt.Errorf("migration to version %d (%s): %w", migration.Version, migration.Description, err)
+		}
+		steps = append(steps, MigrationStep{
+			Version:     migration.Version,
+			Description: migration.Description,
+			Changed:     countChanged(items, migrated),
+		})
+		items = migrated
+	}
+	return items, steps, nil
+}
+
+func countChanged(before, after []json.RawMessage) int {
+	changed := max(len(before), len(after)) - min(len(before), len(after))
+	for i := range min(len(before), len(after)) {
+		if !jsonEqual(before[i], after[i]) {
+			changed++
+		}
+	}
+	return changed
+}
+
+func jsonEqual(a, b json.RawMessage) bool {
+	var compactA, compactB bytes.Buffer
+	if json.Compact(&compactA, a) != nil || json.Compact(&compactB, b) != nil {
+		return bytes.Equal(a, b)
+	}
+	return bytes.Equal(compactA.Bytes(), compactB.Bytes())
+}
+
+func backupPath(path string, version int) string {
+	return fmt.Sprintf("%s.v%d.bak", path, version)
+}
+
+// writeBackup stores the pre-migration contents next to the database.
+func writeBackup(path string, version int, data []byte, mode os.FileMode) (string, error) {
+	backup := backupPath(path, version)
+	var handle *os.File
+	if err := replaceFile(osFileSystem{}, backup, &handle, data, mode); err != nil {
+		return "", err
+	}
+	return backup, handle.Close()
+}
+
+// Migrate upgrades the database file to the latest schema version. With
+// dryRun it only reports what would change. Open runs the same upgrade, so
+// Migrate is mostly useful to preview it.
+func (db *Database[T]) Migrate(dryRun bool) (*MigrationReport, error) {
+	file, lock, err := openLocked(db.path, db.options, true)
+	if err != nil {
+		return nil, err
+	}
+	db.file, db.lock, db.readOnly = file, lock, false
+	defer db.Close()
+	return db.upgrade(dryRun)
+}
+
+func (db *Database[T]) upgrade(dryRun bool) (*MigrationReport, error) {
+	data, readErr := db.readFile()
+	if readErr != nil {
+		return nil, readErr
+	}
+	contents, decodeErr := decodeEnvelope(data, db.schemaVersion())
+	if decodeErr != nil {
+		return nil, decodeErr
+	}
+
+	report := &MigrationReport{
+		Path:        db.Path(),
+		FromVersion: contents.Version,
+		ToVersion:   db.schemaVersion(),
+		Items:       len(contents.Items),
+	}
+	items, steps, migrateErr := db.migrate(db.Path(), contents.Version, contents.Items)
+	if migrateErr != nil {
+		return nil, migrateErr
+	}
+	report.Steps = steps
+	if dryRun || report.UpToDate() {
+		return report, nil
+	}
+
+	backup, backupErr := writeBackup(db.Path(), contents.Version, data, db.mode())
+	if backupErr != nil {
+		return nil, backupErr
+	}
+	report.Backup = backup
+
+	typed, typeErr := decodeItems[T](items)
+	if typeErr != nil {
+		return nil, typeErr
+	}
+	contents.Version = db.schemaVersion()
+	contents.NextID = max(contents.NextID, nextID(typed))
+	contents.Items = items
+	encoded, marshalErr := json.MarshalIndent(contents, "", "    ")
+	if marshalErr != nil {
+		return nil, marshalErr
+	}
+	return report, db.replaceFile(encoded)
+}
@@ -0,0 +1,193 @@
+package jsondatabase
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// renameField is a version 2 migration that renames Name to StringField.
+var renameField = Migration{
+	Version:     2,
+	Description: "rename Name to StringField",
+	Up: func(items []json.RawMessage) ([]json.RawMessage, error) {
+		for i, item := range items {
+			var fields map[string]any
+			if err := json.Unmarshal(item, &fields); err != nil {
+				return nil, err
+			}
+			fields["StringField"] = fields["Name"]
+			delete(fields, "Name")
+			migrated, err := json.Marshal(fields)
+			if err != nil {
+				return nil, err
+			}
+			items[i] = migrated
+		}
+		return items, nil
+	},
+}
+
+const versionOneFile = `{"version": 1, "next_id": 3, "items": [{"Id": 1, "Name": "one"}, {"Id": 2, "Name": "two"}]}`
+
+func writeFile(t *testing.T, name, contents string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(contents), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestMigrateDryRun(t *testing.T) {
+	path := writeFile(t, "dry.json", versionOneFile)
+	db := New[MockupStorable](path, WithMigrations(renameField))
+
+	report, err := db.Migrate(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.FromVersion != 1 || report.ToVersion != 2 || len(report.Steps) != 1 {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+	if report.Steps[0].Changed != 2 {
+		t.Errorf("expected 2 changed items, received %d", report.Steps[0].Changed)
+	}
+
+	data, _ := os.ReadFile(path)
+	if string(data) != versionOneFile {
+		t.Errorf("dry run should not touch the file:\n%s", data)
+	}
+	if _, err := os.Stat(backupPath(path, 1)); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("dry run should not write a backup")
+	}
+}
+
+func TestOpenUpgradesWithBackup(t *testing.T) {
+	path := writeFile(t, "upgrade.json", versionOneFile)
+	db := New[MockupStorable](path, WithMigrations(renameField))
+
+	if err := db.Open(); err != nil {
+		t.Fatal(err)
+	}
+	items, err := db.GetAll()
+	db.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 2 || items[1].StringField != "two" {
+		t.Errorf("unexpected items after upgrade: %v", items)
+	}
+
+	backup, err := os.ReadFile(backupPath(path, 1))
+	if err != nil {
+		t.Fatalf("expected a backup of the version 1 file: %s", err)
+	}
+	if string(backup) != versionOneFile {
+		t.Errorf("backup should hold the original file:\n%s", backup)
+	}
+
+	var file envelope[json.RawMessage]
+	data, _ := os.ReadFile(path)
+	json.Unmarshal(data, &file)
+	if file.Version != 2 || file.NextID != 3 {
+		t.Errorf("expected version 2 with next_id 3, received %d and %d", file.Version, file.NextID)
+	}
+
+	report, err := db.Migrate(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.UpToDate() || report.Backup != "" {
+		t.Errorf("second migration should be a no-op: %+v", report)
+	}
+}
+
+func TestViewMigratesInMemory(t *testing.T) {
+	path := writeFile(t, "view.json", versionOneFile)
+	db := New[MockupStorable](path, WithMigrations(renameField))
+
+	err := db.View(func(tx *Tx[MockupStorable]) error {
+		item, err := tx.Get(1)
+		if item.StringField != "one" {
+			t.Errorf("expected migrated field, received %+v", item)
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, _ := os.ReadFile(path)
+	if string(data) != versionOneFile {
+		t.Errorf("reading should not rewrite the file:\n%s", data)
+	}
+}
+
+func TestNewerSchemaIsRejected(t *testing.T) {
+	path := writeFile(t, "future.json", `{"version": 9, "next_id": 1, "items": []}`)
+	db := New[MockupStorable](path)
+
+	expectedErrType := &ErrSchemaTooNew{}
+	if err := db.Open(); !errors.As(err, &expectedErrType) {
+		t.Errorf("expected ErrSchemaTooNew, received %v", err)
+	}
+	if err := db.View(func(tx *Tx[MockupStorable]) error { return nil }); !errors.As(err, &expectedErrType) {
+		t.Errorf("expected ErrSchemaTooNew from View, received %v", err)
+	}
+}
+
+func TestMigrationsMustBeOrdered(t *testing.T) {
+	skipped := renameField
+	skipped.Version = 3
+	db := New[MockupStorable](filepath.Join(t.TempDir(), "order.json"), WithMigrations(skipped))
+
+	expectedErrType := &ErrMigrationOrder{}
+	if err := db.Open(); !errors.As(err, &expectedErrType) {
+		t.Errorf("expected ErrMigrationOrder, received %v", err)
+	}
+}
+
+func TestLogMigration(t *testing.T) {
+	legacy := `{"op":"put","id":1,"item":{"Id":1,"Name":"one"}}
+{"op":"put","id":2,"item":{"Id":2,"Name":"two"}}
+{"op":"delete","id":2}
+`
+	path := writeFile(t, "items.jsonl", legacy)
+	l := NewLog[MockupStorable](path, WithMigrations(renameField))
+
+	report, err := l.Migrate(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.FromVersion != 1 || report.ToVersion != 2 || report.Items != 1 {
+		t.Errorf("unexpected dry run report: %+v", report)
+	}
+
+	var inserted MockupStorable
+	err = l.Update(func(tx *Tx[MockupStorable]) error {
+		inserted, err = tx.Insert(MockupStorable{StringField: "three"})
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if inserted.Id != 3 {
+		t.Errorf("ids of deleted items must survive the rewrite, received id %d", inserted.Id)
+	}
+
+	items := readLog(t, l)
+	if len(items) != 2 || items[0].StringField != "one" {
+		t.Errorf("unexpected items after log migration: %v", items)
+	}
+	data, _ := os.ReadFile(path)
+	if !strings.HasPrefix(string(data), `{"op":"version","version":2`) {
+		t.Errorf("upgraded log should start with a version record:\n%s", data)
+	}
+	if backup, _ := os.ReadFile(backupPath(path, 1)); string(backup) != legacy {
+		t.Errorf("backup should hold the original log:\n%s", backup)
+	}
+}
@@ -0,0 +1,146 @@
+package jsondatabase
+
+import (
+	"fmt"
+	"slices"
+)
+
+type NotFoundError struct {
+	ID int
+}
+
+func (e *NotFoundError) Error() string {
+	return fmt.Sprintf("item with id %d not found", e.ID)
+}
+
+// Tx is a snapshot of the database handed to Update and View callbacks.
+// Changes are only written when the Update callback returns nil.
+type Tx[T Storable] struct {
+	items    []T
+	next     int
+	readOnly bool
+	dirty    bool
+}
+
+// Update opens the database under an exclusive lock and runs fn. If fn returns
+// nil the changes it made are written atomically; otherwise they are dropped
+// and fn's error is returned.
+func (db *Database[T]) Update(fn func(tx *Tx[T]) error) (err error) {
+	if openErr := db.Open(); openErr != nil {
+		return openErr
+	}
+	defer func() {
+		if closeErr := db.Close(); err == nil {
+			err = closeErr
+		}
+	}()
+
+	items, getErr := db.GetAll()
+	if getErr != nil {
+		return getErr
+	}
+	tx := &Tx[T]{items: items, next: db.nextID}
+	if fnErr := fn(tx); fnErr != nil {
+		return fnErr
+	}
+	if !tx.dirty {
+		return nil
+	}
+	db.nextID = tx.NextID()
+	return db.WriteAll(tx.items)
+}
+
+// View opens the database under a shared lock and runs fn with a read only
+// transaction.
+func (db *Database[T]) View(fn func(tx *Tx[T]) error) (err error) {
+	if openErr := db.OpenReadOnly(); openErr != nil {
+		return openErr
+	}
+	defer func() {
+		if closeErr := db.Close(); err == nil {
+			err = closeErr
+		}
+	}()
+
+	items, getErr := db.GetAll()
+	if getErr != nil {
+		return getErr
+	}
+	return fn(&Tx[T]{items: items, next: db.nextID, readOnly: true})
+}
+
+func (tx *Tx[T]) indexOf(id int) int {
+	return slices.IndexFunc(tx.items, func(item T) bool {
+		return item.GetID() == id
+	})
+}
+
+// All returns every item in storage order.
+func (tx *Tx[T]) All() []T {
+	return slices.Clone(tx.items)
+}
+
+func (tx *Tx[T]) Get(id int) (T, error) {
+	index := tx.indexOf(id)
+	if index == -1 {
+		var zero T
+		return zero, &NotFoundError{id}
+	}
+	return tx.items[index], nil
+}
+
+// Put stores item under its ID, replacing the item that had that ID before.
+// IDs up to item's are never handed out by Insert afterwards.
+func (tx *Tx[T]) Put(item T) error {
+	if tx.readOnly {
+		return ErrReadOnly
+	}
+	index := tx.indexOf(item.GetID())
+	if index == -1 {
+		tx.items = append(tx.items, item)
+	} else {
+		tx.items[index] = item
+	}
+	tx.dirty = true
+	return nil
+}
+
+// NextID returns the ID the next Insert will assign.
+func (tx *Tx[T]) NextID() int {
+	return max(tx.next, nextID(tx.items))
+}
+
+// Insert stores item under a new ID and returns it with that ID set.
+func (tx *Tx[T]) Insert(item T) (T, error) {
+	if tx.readOnly {
+		var zero T
+		return zero, ErrReadOnly
+	}
+	inserted := item.SetID(tx.NextID()).(T)
+	tx.next = inserted.GetID() + 1
+	tx.items = append(tx.items, inserted)
+	tx.dirty = true
+	return inserted, nil
+}
+
+func (tx *Tx[T]) Delete(id int) error {
+	if tx.readOnly {
+		return ErrReadOnly
+	}
+	index := tx.indexOf(id)
+	if index == -1 {
+		return &NotFoundError{id}
+	}
+	tx.next = tx.NextID()
+	tx.items = slices.Delete(tx.items, index, index+1)
+	tx.dirty = true
+	return nil
+}
+
+func nextID[T Storable](items []T) int {
+	lastID := 0
+	for _, item := range items {
+		lastID = max(lastID, item.GetID())
+	}
+	return lastID + 1
+}
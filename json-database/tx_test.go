@@ -0,0 +1,117 @@
+package jsondatabase
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func newTxDatabase(t *testing.T, items []MockupStorable) *Database[MockupStorable] {
+	t.Helper()
+	path, _ := seedDatabase(t, items)
+	return New[MockupStorable](path)
+}
+
+func TestUpdateCommits(t *testing.T) {
+	db := newTxDatabase(t, []MockupStorable{{1, "first"}, {2, "second"}})
+
+	err := db.Update(func(tx *Tx[MockupStorable]) error {
+		item, err := tx.Get(1)
+		if err != nil {
+			return err
+		}
+		item.StringField = "changed"
+		if err := tx.Put(item); err != nil {
+			return err
+		}
+		inserted, err := tx.Insert(MockupStorable{StringField: "third"})
+		if err != nil {
+			return err
+		}
+		if inserted.Id != 3 {
+			t.Errorf("expected inserted id 3, received %d", inserted.Id)
+		}
+		return tx.Delete(2)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	items := readItems(t, db.Path())
+	if len(items) != 2 || items[0].StringField != "changed" || items[1].Id != 3 {
+		t.Errorf("unexpected items after commit: %v", items)
+	}
+}
+
+func TestUpdateRollsBackOnError(t *testing.T) {
+	db := newTxDatabase(t, []MockupStorable{{1, "first"}})
+	errAbort := errors.New("abort")
+
+	err := db.Update(func(tx *Tx[MockupStorable]) error {
+		tx.Put(MockupStorable{1, "changed"})
+		tx.Insert(MockupStorable{StringField: "new"})
+		return errAbort
+	})
+	if !errors.Is(err, errAbort) {
+		t.Fatalf("expected callback error, received %v", err)
+	}
+
+	items := readItems(t, db.Path())
+	if len(items) != 1 || items[0].StringField != "first" {
+		t.Errorf("changes should be discarded, received %v", items)
+	}
+}
+
+func TestUpdateReleasesLock(t *testing.T) {
+	db := newTxDatabase(t, nil)
+
+	db.Update(func(tx *Tx[MockupStorable]) error {
+		return errors.New("fail")
+	})
+	if err := db.Update(func(tx *Tx[MockupStorable]) error { return nil }); err != nil {
+		t.Errorf("lock should be released after a failed update: %s", err)
+	}
+}
+
+func TestViewIsReadOnly(t *testing.T) {
+	db := newTxDatabase(t, []MockupStorable{{1, "first"}})
+
+	err := db.View(func(tx *Tx[MockupStorable]) error {
+		if len(tx.All()) != 1 {
+			t.Errorf("expected 1 item, received %d", len(tx.All()))
+		}
+		if err := tx.Put(MockupStorable{1, "changed"}); !errors.Is(err, ErrReadOnly) {
+			t.Errorf("Put: expected ErrReadOnly, received %v", err)
+		}
+		if _, err := tx.Insert(MockupStorable{}); !errors.Is(err, ErrReadOnly) {
+			t.Errorf("Insert: expected ErrReadOnly, received %v", err)
+		}
+		if err := tx.Delete(1); !errors.Is(err, ErrReadOnly) {
+			t.Errorf("Delete: expected ErrReadOnly, received %v", err)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTxNotFound(t *testing.T) {
+	db := New[MockupStorable](filepath.Join(t.TempDir(), "empty.json"))
+	target := &NotFoundError{}
+
+	err := db.Update(func(tx *Tx[MockupStorable]) error {
+		_, err := tx.Get(5)
+		return err
+	})
+	if !errors.As(err, &target) || target.ID != 5 {
+		t.Errorf("Get: expected NotFoundError for id 5, received %v", err)
+	}
+
+	err = db.Update(func(tx *Tx[MockupStorable]) error {
+		return tx.Delete(7)
+	})
+	if !errors.As(err, &target) || target.ID != 7 {
+		t.Errorf("Delete: expected NotFoundError for id 7, received %v", err)
+	}
+}
@@ -2,228 +2,1175 @@ package main
 
 import (
 	"backend/tasks"
+	"backend/tui"
+	"bufio"
+	"cmp"
 	"errors"
 	"fmt"
 	"os"
+	"slices"
 	"strconv"
+	"strings"
+	"time"
 )
 
-type InvalidArgNumberError struct {
-	commandName  string
-	expectedArgs int
-	receivedArgs int
+type AddCommand Command
+
+func (com *AddCommand) task(in *Input) (tasks.Task, error) {
+	task := tasks.Task{
+		Description: in.Arg("description"),
+		Project:     in.Flag("project"),
+		Parent:      in.FlagInt("parent"),
+	}
+	var err error
+	if in.FlagSet("priority") {
+		task.Priority, err = tasks.ParsePriority(in.Flag("priority"))
+		if err != nil {
+			return task, err
+		}
+	}
+	if in.FlagSet("recur") {
+		recurrence, recurErr := tasks.ParseRecurrence(in.Flag("recur"))
+		if recurErr != nil {
+			return task, recurErr
+		}
+		task.Recur = &recurrence
+	}
+	if in.FlagSet("due") {
+		task.Due, err = tasks.ParseDueDate(in.Flag("due"), time.Now())
+	}
+	return task, err
 }
 
-func (e *InvalidArgNumberError) Error() string {
-	return fmt.Sprintf("%s command expects %d args, but received %d", e.commandName, e.expectedArgs, e.receivedArgs)
+func (com *AddCommand) Verify(in *Input) error {
+	_, err := com.task(in)
+
+	return err
 }
 
-func validateArgs(commandName string, expected int, received int) error {
-	if expected != received {
-		return &InvalidArgNumberError{commandName, expected, received}
+func (com *AddCommand) Execute(in *Input) error {
+	newTask, err := com.task(in)
+	if err != nil {
+		return err
+	}
+	task, addTaskErr := com.store.Add(newTask)
+	if addTaskErr != nil {
+		return addTaskErr
 	}
+	fmt.Printf("Task added sucessfully (ID: %d)\n", task.ID)
 	return nil
 }
 
-type Command struct {
-	argNumber int
-	data      map[string]any
+type UpdateCommand Command
+
+func (com *UpdateCommand) Execute(in *Input) error {
+	_, err := com.store.UpdateTask(in.Int("id"), in.Arg("description"))
+	return err
 }
 
-type Executable interface {
-	Verify(args []string) error
-	Execute(args []string) error
+type DeleteCommand Command
+
+func (com *DeleteCommand) Verify(in *Input) error {
+	if in.Switch("cascade") && in.Switch("orphan") {
+		return errors.New("delete command accepts either --cascade or --orphan, not both")
+	}
+	return nil
 }
 
-type AddCommand Command
+// askDeleteMode asks what to do with the subtasks of a task about to be
+// deleted.
+func askDeleteMode(id int, subtasks []tasks.Task) (tasks.DeleteMode, error) {
+	fmt.Printf("Task %d has %d subtasks. Delete them too (c), keep them as top level tasks (o) or cancel (n)? ", id, len(subtasks))
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && answer == "" {
+		return tasks.DeleteRefuse, errors.New("delete cancelled. Use --cascade or --orphan to choose without asking")
+	}
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "c", "cascade":
+		return tasks.DeleteCascade, nil
+	case "o", "orphan":
+		return tasks.DeleteOrphan, nil
+	}
+	return tasks.DeleteRefuse, errors.New("delete cancelled")
+}
 
-func (com *AddCommand) Verify(args []string) error {
-	err := validateArgs("add", com.argNumber, len(args))
+func (com *DeleteCommand) Execute(in *Input) error {
+	taskId := in.Int("id")
+	mode := tasks.DeleteRefuse
+	switch {
+	case in.Switch("cascade"):
+		mode = tasks.DeleteCascade
+	case in.Switch("orphan"):
+		mode = tasks.DeleteOrphan
+	default:
+		subtasks, err := com.store.Children(taskId)
+		if err != nil {
+			return err
+		}
+		if len(subtasks) > 0 {
+			mode, err = askDeleteMode(taskId, subtasks)
+			if err != nil {
+				return err
+			}
+		}
+	}
 
-	return err
+	deleted, err := com.store.DeleteTaskMode(taskId, mode)
+	if err != nil {
+		return err
+	}
+	if len(deleted) > 1 {
+		fmt.Printf("Moved %d tasks to the trash\n", len(deleted))
+	}
+	return nil
 }
 
-func (com *AddCommand) Execute(args []string) error {
-	task, addTaskErr := tasks.AddTask(args[0])
-	if addTaskErr != nil {
-		return addTaskErr
+// UpdateStatusCommand sets the status in data["status"].
+type UpdateStatusCommand Command
+
+func (com *UpdateStatusCommand) Verify(in *Input) error {
+	status, ok := com.data["status"].(tasks.TaskStatus)
+	if !ok {
+		return errors.New("command data doesnt have a status entry")
+	}
+	if _, ok := tasks.CurrentWorkflow().Definition(status); !ok {
+		return fmt.Errorf("invalid status. Received %d, expected one of %s", status, strings.Join(tasks.CurrentWorkflow().Names(), ", "))
 	}
-	fmt.Printf("Task added sucessfully (ID: %d)", task.ID)
+
 	return nil
 }
 
-type UpdateCommand Command
+func (com *UpdateStatusCommand) Execute(in *Input) error {
+	taskId := in.Int("id")
+	status := com.data["status"].(tasks.TaskStatus)
+
+	var task tasks.Task
+	var err error
+	if in.Switch("force") {
+		task, err = com.store.ForceTaskStatus(taskId, status)
+	} else {
+		task, err = com.store.UpdateTaskStatus(taskId, status)
+	}
+	if err != nil || task.Series == 0 || status != tasks.Done {
+		return err
+	}
+
+	series, err := com.store.Series(taskId)
+	if err != nil {
+		return err
+	}
+	if next := series[len(series)-1]; next.ID != task.ID && next.Status == tasks.Todo {
+		fmt.Printf("Next occurrence added (ID: %d, due %s)\n", next.ID, tasks.DueLabel(next.Due, time.Now()))
+	}
+	return nil
+}
+
+type MarkCommand Command
+
+// update reads the status name of mark into an UpdateStatusCommand, which
+// does the rest.
+func (com *MarkCommand) update(in *Input) (*UpdateStatusCommand, error) {
+	status, err := tasks.ParseStatus(in.Arg("status"))
+	if err != nil {
+		return nil, err
+	}
+	return &UpdateStatusCommand{com.spec, map[string]any{"status": status}, com.store, com.config}, nil
+}
+
+func (com *MarkCommand) Verify(in *Input) error {
+	update, err := com.update(in)
+	if err != nil {
+		return err
+	}
+	return update.Verify(in)
+}
+
+func (com *MarkCommand) Execute(in *Input) error {
+	update, err := com.update(in)
+	if err != nil {
+		return err
+	}
+	return update.Execute(in)
+}
+
+type StatusesCommand Command
+
+func (com *StatusesCommand) Execute(in *Input) error {
+	fmt.Println("== Statuses ==")
+	for _, status := range tasks.CurrentWorkflow().Statuses {
+		moves := "any status"
+		if len(status.Transitions) > 0 {
+			moves = strings.Join(status.Transitions, ", ")
+		}
+		closed := ""
+		if status.Closed {
+			closed = ", closed"
+		}
+		fmt.Printf(" %s (%s%s) - can move to %s\n", status.Name, status.Label, closed, moves)
+	}
+	fmt.Println("====")
+	return nil
+}
+
+type HistoryCommand Command
+
+func (com *HistoryCommand) Execute(in *Input) error {
+	taskId := in.Int("id")
+	events, err := com.store.History(taskId)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("== History of task %d ==\n", taskId)
+	for _, event := range events {
+		fmt.Println(event)
+	}
+	fmt.Println("====")
+	return nil
+}
+
+// ageFlag reads an age flag as the time that long ago, or returns fallback
+// when the flag is not set.
+func ageFlag(in *Input, name string, fallback time.Time) (time.Time, error) {
+	if in.Flag(name) == "" {
+		return fallback, nil
+	}
+	age, err := tasks.ParseAge(in.Flag(name))
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Now().Add(-age), nil
+}
+
+type LogCommand Command
 
-func (com *UpdateCommand) Verify(args []string) error {
-	err := validateArgs("update", com.argNumber, len(args))
+// since reads the --since age of log. Without it log shows everything.
+func (com *LogCommand) since(in *Input) (time.Time, error) {
+	return ageFlag(in, "since", time.Time{})
+}
+
+func (com *LogCommand) Verify(in *Input) error {
+	_, err := com.since(in)
 
 	return err
 }
 
-func (com *UpdateCommand) Execute(args []string) error {
-	taskId, err := strconv.Atoi(args[0])
+func (com *LogCommand) Execute(in *Input) error {
+	since, err := com.since(in)
+	if err != nil {
+		return err
+	}
+	events, err := com.store.Activity(since)
 	if err != nil {
 		return err
 	}
-	desc := args[1]
-	_,err = tasks.UpdateTask(taskId, desc)
+	fmt.Println("== Activity ==")
+	for _, event := range events {
+		fmt.Println(event)
+	}
+	fmt.Println("====")
+	return nil
+}
+
+type RecurCommand Command
+
+func (com *RecurCommand) Verify(in *Input) error {
+	if in.Arg("rule") == "stop" {
+		return nil
+	}
+	_, err := tasks.ParseRecurrence(in.Arg("rule"))
 	return err
 }
 
-type DeleteCommand Command
+func (com *RecurCommand) Execute(in *Input) error {
+	taskId := in.Int("id")
+	if in.Arg("rule") == "stop" {
+		stopped, err := com.store.StopRecurrence(taskId)
+		if err != nil {
+			return err
+		}
+		if len(stopped) == 0 {
+			fmt.Printf("Task %d does not repeat\n", taskId)
+		}
+		return nil
+	}
+
+	recurrence, err := tasks.ParseRecurrence(in.Arg("rule"))
+	if err != nil {
+		return err
+	}
+	task, err := com.store.SetRecurrence(taskId, recurrence)
+	if err != nil {
+		return err
+	}
+	fmt.Println(task)
+	return nil
+}
+
+type ParentCommand Command
 
-func (com *DeleteCommand) Verify(args []string) error {
-	err := validateArgs("delete", com.argNumber, len(args))
+// parentArg reads a task ID, or none for no parent.
+func parentArg(value string) (int, error) {
+	if value == "none" {
+		return 0, nil
+	}
+	return strconv.Atoi(value)
+}
 
+func (com *ParentCommand) Verify(in *Input) error {
+	_, err := parentArg(in.Arg("parent"))
 	return err
 }
 
-func (com *DeleteCommand) Execute(args []string) error {
-	taskId, err := strconv.Atoi(args[0])
+func (com *ParentCommand) Execute(in *Input) error {
+	parent, err := parentArg(in.Arg("parent"))
 	if err != nil {
 		return err
 	}
-	return tasks.DeleteTask(taskId)
+	_, err = com.store.SetParent(in.Int("id"), parent)
+	return err
 }
 
-type UpdateStatusCommand Command
+type PriorityCommand Command
+
+func (com *PriorityCommand) Verify(in *Input) error {
+	_, err := tasks.ParsePriority(in.Arg("level"))
+	return err
+}
 
-func (com *UpdateStatusCommand) Verify(args []string) error {
-	err := validateArgs("update status", com.argNumber, len(args))
+func (com *PriorityCommand) Execute(in *Input) error {
+	priority, err := tasks.ParsePriority(in.Arg("level"))
 	if err != nil {
 		return err
 	}
-	status, ok := com.data["status"].(tasks.TaskStatus)
-	if !ok {
-		return errors.New("command data doesnt have a status entry")
+	_, err = com.store.UpdateTaskPriority(in.Int("id"), priority)
+	return err
+}
+
+type DueCommand Command
+
+func (com *DueCommand) Verify(in *Input) error {
+	_, err := tasks.ParseDueDate(in.Arg("date"), time.Now())
+	return err
+}
+
+func (com *DueCommand) Execute(in *Input) error {
+	due, err := tasks.ParseDueDate(in.Arg("date"), time.Now())
+	if err != nil {
+		return err
 	}
-	if status > tasks.Done {
-		return fmt.Errorf("invalid status. Received %d, expected %d or %d", status, tasks.InProgress, tasks.Done)
+	task, err := com.store.UpdateTaskDue(in.Int("id"), due)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Task %d is due %s\n", task.ID, tasks.DueLabel(task.Due, time.Now()))
+	return nil
+}
+
+// splitTagArgs moves +tag args to include and -tag args to exclude, returning
+// the remaining args.
+func splitTagArgs(args []string, include, exclude *[]string) ([]string, error) {
+	rest := []string{}
+	for _, arg := range args {
+		target := include
+		if strings.HasPrefix(arg, "-") && len(arg) > 1 {
+			target = exclude
+		} else if !strings.HasPrefix(arg, "+") || len(arg) == 1 {
+			rest = append(rest, arg)
+			continue
+		}
+		tag, err := tasks.NormalizeTag(arg[1:])
+		if err != nil {
+			return nil, err
+		}
+		*target = append(*target, tag)
+	}
+	return rest, nil
+}
+
+type TagCommand Command
+
+func (com *TagCommand) Verify(in *Input) error {
+	var add, remove []string
+	rest, err := splitTagArgs(in.Words("tags"), &add, &remove)
+	if err != nil {
+		return err
+	}
+	if len(rest) > 0 {
+		return fmt.Errorf("invalid option for tag command. Expected +tag or -tag. Received %s", rest[0])
 	}
+	return nil
+}
 
+func (com *TagCommand) Execute(in *Input) error {
+	var add, remove []string
+	if _, err := splitTagArgs(in.Words("tags"), &add, &remove); err != nil {
+		return err
+	}
+	task, err := com.store.TagTask(in.Int("id"), add, remove)
+	if err != nil {
+		return err
+	}
+	fmt.Println(task)
 	return nil
 }
 
-func (com *UpdateStatusCommand) Execute(args []string) error {
-	taskId, err := strconv.Atoi(args[0])
+type TagsCommand Command
+
+func (com *TagsCommand) Execute(in *Input) error {
+	tags, err := com.store.Tags()
 	if err != nil {
 		return err
 	}
-	status := com.data["status"].(tasks.TaskStatus)
+	fmt.Println("== Tags ==")
+	for _, tag := range tags {
+		fmt.Printf(" +%s (%d)\n", tag.Tag, tag.Count)
+	}
+	fmt.Println("====")
+	return nil
+}
+
+type ProjectCommand Command
 
-	_,err = tasks.UpdateTaskStatus(taskId, status)
+func (com *ProjectCommand) Verify(in *Input) error {
+	_, err := tasks.NormalizeProject(projectArg(in.Arg("name")))
 	return err
 }
 
-type ListCommand Command
+// projectArg maps none to the empty project.
+func projectArg(name string) string {
+	if name == "none" {
+		return ""
+	}
+	return name
+}
+
+func (com *ProjectCommand) Execute(in *Input) error {
+	_, err := com.store.UpdateTaskProject(in.Int("id"), projectArg(in.Arg("name")))
+	return err
+}
+
+type ProjectsCommand Command
 
-func (com *ListCommand) Verify(args []string) error {
-	err := validateArgs("list", com.argNumber, len(args))
+func (com *ProjectsCommand) Verify(in *Input) error {
+	if !in.Has("action") {
+		return nil
+	}
+	if in.Arg("action") != "rename" {
+		return fmt.Errorf("invalid action for projects command. Expected rename. Received %s", in.Arg("action"))
+	}
+	if !in.Has("new") {
+		return errors.New("projects rename expects the old and the new name")
+	}
+	return nil
+}
+
+func (com *ProjectsCommand) Execute(in *Input) error {
+	if in.Has("action") {
+		renamed, err := com.store.RenameProject(in.Arg("old"), in.Arg("new"))
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Moved %d tasks from %s to %s\n", renamed, in.Arg("old"), in.Arg("new"))
+		return nil
+	}
+
+	projects, err := com.store.Projects()
 	if err != nil {
 		return err
 	}
+	fmt.Println("== Projects ==")
+	for _, project := range projects {
+		other := ""
+		if project.Other > 0 {
+			other = fmt.Sprintf(" | other: %d", project.Other)
+		}
+		fmt.Printf(" %s (todo: %d | in progress: %d | done: %d%s)\n", project.Project, project.Todo, project.InProgress, project.Done, other)
+	}
+	fmt.Println("====")
+	return nil
+}
+
+type ListCommand Command
+
+// query builds the list query from the input. The first filter that is not
+// a tag is a status or a view name.
+func (com *ListCommand) query(in *Input) (tasks.Query, string, error) {
+	query := tasks.Query{Status: tasks.AllTasks}
+	var include, exclude []string
+	positional, err := splitTagArgs(in.Words("filters"), &include, &exclude)
+	if err != nil {
+		return query, "", err
+	}
+	if len(positional) > 1 {
+		return query, "", &InvalidArgNumberError{"list", 0, 1, len(positional)}
+	}
 
-	if com.argNumber == 1 {
-		statusArg := args[0]
-		if statusArg != "todo" && statusArg != "in-progress" && statusArg != "done" {
-			return fmt.Errorf("invalid action for list command. Expected, todo, in-progress or done. Received %s", statusArg)
+	statusArg := ""
+	if len(positional) == 1 {
+		statusArg = positional[0]
+		view, isView := tasks.ViewQuery(statusArg, time.Now())
+		switch {
+		case isView:
+			query = view
+		case tasks.StatusNameToValue(statusArg) != -1:
+			query.Status = int(tasks.StatusNameToValue(statusArg))
+		default:
+			names := slices.Concat(tasks.CurrentWorkflow().Names(), tasks.Views)
+			return query, "", fmt.Errorf("invalid action for list command. Expected %s. Received %s", strings.Join(names, ", "), statusArg)
 		}
 	}
 
-	return nil
+	query.Tags = include
+	query.ExcludeTags = exclude
+	query.Project = in.Flag("project")
+	query.SortBy = tasks.SortKey(in.Flag("sort"))
+	if !slices.Contains(tasks.SortKeys, query.SortBy) && query.SortBy != "" {
+		return query, "", &tasks.ErrInvalidSortKey{Key: in.Flag("sort")}
+	}
+	if in.FlagSet("priority") {
+		for name := range strings.SplitSeq(in.Flag("priority"), ",") {
+			priority, priorityErr := tasks.ParsePriority(name)
+			if priorityErr != nil {
+				return query, "", priorityErr
+			}
+			query.Priorities = append(query.Priorities, priority)
+		}
+	}
+	since := map[string]*time.Time{"created-since": &query.CreatedAfter, "updated-since": &query.UpdatedAfter}
+	for name, target := range since {
+		if *target, err = ageFlag(in, name, time.Time{}); err != nil {
+			return query, "", err
+		}
+	}
+	return query, statusArg, nil
 }
 
-func (com *ListCommand) Execute(args []string) error {
-	status := -1
-	if com.argNumber == 1 {
-		statusName := args[0]
-		status = int(tasks.StatusNameToValue(statusName))
-		fmt.Println("==", statusName, "==")
-	} else {
-		fmt.Println("== All tasks ==")
+func (com *ListCommand) Verify(in *Input) error {
+	if _, _, err := com.query(in); err != nil {
+		return err
+	}
+	_, err := tasks.NewFormatter(in.Flag("format"))
+
+	return err
+}
+
+func (com *ListCommand) Execute(in *Input) error {
+	query, statusArg, err := com.query(in)
+	if err != nil {
+		return err
+	}
+	format, err := tasks.NewFormatter(in.Flag("format"))
+	if err != nil {
+		return err
 	}
 
-	err := tasks.ListTasks(status)
+	var list []tasks.Task
+	if in.Switch("archived") {
+		list, err = com.store.QueryArchive(query)
+	} else {
+		list, err = com.store.Query(query)
+	}
 	if err != nil {
 		return err
 	}
+	// Only the table is framed; the other formats are read by programs.
+	if in.Flag("format") != tasks.FormatTable {
+		return format(os.Stdout, list)
+	}
+	title := cmp.Or(statusArg, "All tasks")
+	if in.Switch("archived") {
+		title += " (archived)"
+	}
+	fmt.Println("==", title, "==")
+	if err := format(os.Stdout, list); err != nil {
+		return err
+	}
 	fmt.Println("====")
 
 	return nil
 }
 
-type HelpCommand Command
+type ShowCommand Command
+
+func (com *ShowCommand) Verify(in *Input) error {
+	_, err := tasks.NewFormatter(in.Flag("format"))
+	return err
+}
+
+func (com *ShowCommand) Execute(in *Input) error {
+	format, err := tasks.NewFormatter(in.Flag("format"))
+	if err != nil {
+		return err
+	}
+	task, err := com.store.GetTask(in.Int("id"))
+	if err != nil {
+		return err
+	}
+	return format(os.Stdout, []tasks.Task{task})
+}
+
+type TuiCommand Command
+
+func (com *TuiCommand) Execute(in *Input) error {
+	return tui.Run(com.store, os.Stdin, os.Stdout)
+}
+
+type ArchiveCommand Command
 
-func (com *HelpCommand) Verify(args []string) error {
-	err := validateArgs("list", com.argNumber, len(args))
+// before reads the --older-than age of archive. Without it every closed task
+// is archived.
+func (com *ArchiveCommand) before(in *Input) (time.Time, error) {
+	return ageFlag(in, "older-than", time.Now())
+}
+
+func (com *ArchiveCommand) Verify(in *Input) error {
+	_, err := com.before(in)
+
+	return err
+}
+
+func (com *ArchiveCommand) Execute(in *Input) error {
+	before, err := com.before(in)
+	if err != nil {
+		return err
+	}
+	archived, err := com.store.Archive(before)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Archived %d tasks\n", len(archived))
+	return nil
+}
+
+// BlockCommand is both block and unblock, told apart by the spec name.
+type BlockCommand Command
+
+func (com *BlockCommand) Verify(in *Input) error {
+	if !in.FlagSet("by") {
+		return fmt.Errorf("%s command expects --by [id]", com.spec.Name)
+	}
+	return nil
+}
+
+func (com *BlockCommand) Execute(in *Input) error {
+	var err error
+	if com.spec.Name == "unblock" {
+		_, err = com.store.Unblock(in.Int("id"), in.FlagInt("by"))
+	} else {
+		_, err = com.store.Block(in.Int("id"), in.FlagInt("by"))
+	}
+	return err
+}
+
+type GraphCommand Command
+
+func (com *GraphCommand) Verify(in *Input) error {
+	if format := in.Flag("format"); format != "text" && format != "dot" {
+		return fmt.Errorf("invalid format for graph command. Expected text or dot. Received %s", format)
+	}
+	return nil
+}
+
+func (com *GraphCommand) Execute(in *Input) error {
+	list, err := com.store.Query(tasks.Query{Status: tasks.AllTasks})
+	if err != nil {
+		return err
+	}
+	if in.Flag("format") == "dot" {
+		fmt.Print(tasks.GraphDOT(list))
+	} else {
+		fmt.Print(tasks.GraphText(list))
+	}
+	return nil
+}
+
+type DbCommand Command
+
+func (com *DbCommand) Verify(in *Input) error {
+	if in.Arg("action") != "migrate" {
+		return fmt.Errorf("invalid action for db command. Expected migrate. Received %s", in.Arg("action"))
+	}
+	return nil
+}
+
+func (com *DbCommand) Execute(in *Input) error {
+	dryRun := in.Switch("dry-run")
+	report, err := com.store.Migrate(dryRun)
 	if err != nil {
 		return err
 	}
 
+	if report.UpToDate() {
+		fmt.Printf("%s is up to date (schema version %d)\n", report.Path, report.ToVersion)
+		return nil
+	}
+	fmt.Printf("%s: schema version %d -> %d, %d items\n", report.Path, report.FromVersion, report.ToVersion, report.Items)
+	for _, step := range report.Steps {
+		fmt.Printf(" v%d: %s (%d items changed)\n", step.Version, step.Description, step.Changed)
+	}
+	if dryRun {
+		fmt.Println("Dry run, nothing was written")
+	} else {
+		fmt.Println("Backup of the previous file written to", report.Backup)
+	}
+	return nil
+}
+
+type HelpCommand Command
+
+func (com *HelpCommand) Verify(in *Input) error {
+	if !in.Has("command") {
+		return nil
+	}
+	if _, ok := commands.Lookup(in.Arg("command")); !ok {
+		return &UnknownCommandError{in.Arg("command"), commands.Suggest(in.Arg("command"))}
+	}
 	return nil
 }
 
-func (com *HelpCommand) Execute(args []string) error {
+func (com *HelpCommand) Execute(in *Input) error {
+	if spec, ok := commands.Lookup(in.Arg("command")); ok {
+		fmt.Print(spec.Usage())
+		return nil
+	}
 	fmt.Println("== Commands ==")
-	fmt.Println(" list                              - lists all tasks")
-	fmt.Println(" list [todo | in-progress | done]  - lists all tasks in a particular status")
-	fmt.Println(" add [description]                 - adds a task to the todo list")
-	fmt.Println(" delete [id]                       - removes a task")
-	fmt.Println(" update [id] [description]         - updates the description of a task")
-	fmt.Println(" mark-in-progress [id]             - sets a task status as in progress")
-	fmt.Println(" mark-done [id]                    - sets a task status as done")
-	fmt.Println(" help                              - shows info for each command")
+	for _, spec := range commands.Visible() {
+		fmt.Printf(" %-33s - %s\n", spec.Synopsis(), spec.Summary)
+	}
+	fmt.Println("== Options, before the command ==")
+	fmt.Println(" --db [path]                       - database file to use. Defaults to $TASK_TRACKER_DB,")
+	fmt.Println("                                     then the config file, then")
+	fmt.Println("                                     $XDG_DATA_HOME/task-tracker/tasks.json")
+	fmt.Println(" --backend [json | jsonl | memory] - storage format. Defaults to $TASK_TRACKER_BACKEND,")
+	fmt.Println("                                     then the config file, then json")
+	fmt.Println(" --error-format [text | json]      - how errors are written to stderr. Exit codes are 2 for")
+	fmt.Println("                                     usage, 3 for not found, 4 for invalid values and 5 for")
+	fmt.Println("                                     storage errors")
+	fmt.Println(" [command] --help                  - shows the args and options of a command")
 	fmt.Println("====")
 	return nil
 }
 
-func main() {
-	receivedArgs := os.Args[1:]
-	if len(receivedArgs) == 0 {
-		fmt.Println("No commands received")
-		return
+type TrashCommand Command
+
+func (com *TrashCommand) Execute(in *Input) error {
+	trash, err := com.store.Trash()
+	if err != nil {
+		return err
+	}
+	fmt.Println("== Trash ==")
+	for _, task := range trash {
+		fmt.Println(task)
 	}
+	fmt.Println("====")
+	return nil
+}
 
-	commName := receivedArgs[0]
-	var comm Executable
-	var err error
-	commandData := map[string]any{}
-
-	switch commName {
-	case "add":
-		comm = &AddCommand{1, commandData}
-	case "update":
-		comm = &UpdateCommand{2, commandData}
-	case "delete":
-		comm = &DeleteCommand{1, commandData}
-	case "mark-in-progress":
-		commandData["status"] = tasks.InProgress
-		comm = &UpdateStatusCommand{1, commandData}
-	case "mark-done":
-		commandData["status"] = tasks.Done
-		comm = &UpdateStatusCommand{1, commandData}
-	case "list":
-		if len(receivedArgs) > 1 {
-			comm = &ListCommand{1, commandData}
-		} else {
-			comm = &ListCommand{0, commandData}
-		}
-	case "help":
-		comm = &HelpCommand{0, commandData}
-	default:
-		fmt.Println("no command named " + commName)
-		return
+type RestoreCommand Command
+
+func (com *RestoreCommand) Execute(in *Input) error {
+	task, err := com.store.RestoreTask(in.Int("id"))
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Task %d restored from the trash\n", task.ID)
+	return nil
+}
+
+type PurgeCommand Command
+
+// before reads the --older-than age of purge. Without it purge empties the
+// whole trash.
+func (com *PurgeCommand) before(in *Input) (time.Time, error) {
+	return ageFlag(in, "older-than", time.Now())
+}
+
+func (com *PurgeCommand) Verify(in *Input) error {
+	_, err := com.before(in)
+
+	return err
+}
+
+func (com *PurgeCommand) Execute(in *Input) error {
+	before, err := com.before(in)
+	if err != nil {
+		return err
+	}
+	purged, err := com.store.Purge(before)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Purged %d tasks\n", len(purged))
+	return nil
+}
+
+// UndoCommand is both undo and redo, told apart by the spec name.
+type UndoCommand Command
+
+// count reads the optional number of operations, which defaults to one.
+func (com *UndoCommand) count(in *Input) (int, error) {
+	if !in.Has("n") {
+		return 1, nil
+	}
+	if n := in.Int("n"); n < 1 {
+		return 0, fmt.Errorf("%s expects a positive number of operations, but received %d", com.spec.Name, n)
+	}
+	return in.Int("n"), nil
+}
+
+func (com *UndoCommand) Verify(in *Input) error {
+	_, err := com.count(in)
+
+	return err
+}
+
+func (com *UndoCommand) Execute(in *Input) error {
+	n, err := com.count(in)
+	if err != nil {
+		return err
+	}
+	undo, verb := com.store.Undo, "Undid"
+	if com.spec.Name == "redo" {
+		undo, verb = com.store.Redo, "Redid"
+	}
+	operations, err := undo(n)
+	for _, operation := range operations {
+		fmt.Printf("%s: %s\n", verb, operation.Summary())
+	}
+	return err
+}
+
+var taskID = Arg{Name: "id", Kind: KindInt}
+
+// commands is every command of the program, in the order help lists them.
+var commands = NewRegistry(
+	&CommandSpec{
+		Name:    "list",
+		Aliases: []string{"ls"},
+		Args:    []Arg{{Name: "filters", Optional: true, Rest: true}},
+		Flags: []Flag{
+			{Name: "sort", Value: "key", Help: "orders tasks by id, created, updated, priority or due, newest or most urgent first"},
+			{Name: "priority", Value: "level,...", Help: "only tasks with one of the given priorities"},
+			{Name: "project", Value: "name", Help: "only tasks of a project and its subprojects"},
+			{Name: "created-since", Value: "age", Help: "only tasks created in the last age, e.g. 2d, 1w or 12h"},
+			{Name: "updated-since", Value: "age", Help: "only tasks changed in the last age"},
+			{Name: "archived", Kind: KindBool, Help: "lists archived tasks instead"},
+			{Name: "format", Value: "format", Default: tasks.FormatTable, Help: "table, json, jsonl, csv, markdown or a template like '{{.ID}} {{.Description}}'"},
+		},
+		Summary: "lists tasks, with subtasks indented below their parent",
+		Help: "Filters are a status (todo, in-progress, done or one from the config file), a view\n" +
+			"(ready, overdue, due-today or due-this-week) and +tag or -tag words, as in list todo +work -later.",
+		New: func(com *Command) Executable { return (*ListCommand)(com) },
+	},
+	&CommandSpec{
+		Name:    "show",
+		Args:    []Arg{taskID},
+		Flags:   []Flag{{Name: "format", Value: "format", Default: tasks.FormatTable, Help: "same formats as list"}},
+		Summary: "prints one task, archived or not",
+		New:     func(com *Command) Executable { return (*ShowCommand)(com) },
+	},
+	&CommandSpec{
+		Name:    "tui",
+		Summary: "opens a board of the tasks by status to browse and change them with the keyboard",
+		New:     func(com *Command) Executable { return (*TuiCommand)(com) },
+	},
+	&CommandSpec{
+		Name:    "shell",
+		Summary: "runs commands at a prompt on one copy of the database, written back on save and exit",
+		Help: "Lines are commands as on the command line, without the program name. Tab completes\n" +
+			"commands, options and task IDs, and the arrow keys browse the lines typed before.\n" +
+			"save writes the changes so far; exit, quit or Ctrl-D save and leave.",
+		New: func(com *Command) Executable { return (*ShellCommand)(com) },
+	},
+	&CommandSpec{
+		Name: "add",
+		Args: []Arg{{Name: "description", Rest: true}},
+		Flags: []Flag{
+			{Name: "priority", Value: "level", Help: "none, low, medium, high or critical"},
+			{Name: "due", Value: "date", Help: "a due date, see due --help"},
+			{Name: "project", Value: "name", Help: "a project. Subprojects are dotted, like web.frontend"},
+			{Name: "parent", Kind: KindInt, Value: "id", Help: "makes the task a subtask"},
+			{Name: "recur", Value: "rule", Help: "makes the task repeat, see recur --help"},
+		},
+		Summary: "adds a task to the todo list. +tag words become tags",
+		New:     func(com *Command) Executable { return (*AddCommand)(com) },
+	},
+	&CommandSpec{
+		Name:    "update",
+		Args:    []Arg{taskID, {Name: "description", Rest: true}},
+		Summary: "updates the description of a task",
+		New:     func(com *Command) Executable { return (*UpdateCommand)(com) },
+	},
+	&CommandSpec{
+		Name:    "delete",
+		Aliases: []string{"rm"},
+		Args:    []Arg{taskID},
+		Flags: []Flag{
+			{Name: "cascade", Kind: KindBool, Help: "trashes the subtasks too"},
+			{Name: "orphan", Kind: KindBool, Help: "keeps the subtasks as top level tasks"},
+		},
+		Summary: "moves a task to the trash, asking what to do with its subtasks",
+		New:     func(com *Command) Executable { return (*DeleteCommand)(com) },
+	},
+	&CommandSpec{
+		Name:    "trash",
+		Summary: "lists deleted tasks",
+		New:     func(com *Command) Executable { return (*TrashCommand)(com) },
+	},
+	&CommandSpec{
+		Name:    "restore",
+		Args:    []Arg{taskID},
+		Summary: "brings a task back from the trash with its ID",
+		New:     func(com *Command) Executable { return (*RestoreCommand)(com) },
+	},
+	&CommandSpec{
+		Name:    "purge",
+		Flags:   []Flag{{Name: "older-than", Value: "age", Help: "only tasks trashed before age, e.g. 30d"}},
+		Summary: "removes trashed tasks for good",
+		New:     func(com *Command) Executable { return (*PurgeCommand)(com) },
+	},
+	&CommandSpec{
+		Name:    "mark-in-progress",
+		Args:    []Arg{taskID},
+		Flags:   []Flag{{Name: "force", Kind: KindBool, Help: "starts the task even while it is blocked"}},
+		Summary: "sets a task status as in progress",
+		Data:    map[string]any{"status": tasks.InProgress},
+		New:     func(com *Command) Executable { return (*UpdateStatusCommand)(com) },
+	},
+	&CommandSpec{
+		Name:    "mark-done",
+		Args:    []Arg{taskID},
+		Flags:   []Flag{{Name: "force", Kind: KindBool, Help: "finishes the task even while subtasks are open"}},
+		Summary: "sets a task status as done",
+		Data:    map[string]any{"status": tasks.Done},
+		New:     func(com *Command) Executable { return (*UpdateStatusCommand)(com) },
+	},
+	&CommandSpec{
+		Name:    "mark",
+		Args:    []Arg{taskID, {Name: "status"}},
+		Flags:   []Flag{{Name: "force", Kind: KindBool, Help: "skips the workflow's transition rules"}},
+		Summary: "sets any status of the workflow",
+		New:     func(com *Command) Executable { return (*MarkCommand)(com) },
+	},
+	&CommandSpec{
+		Name:    "statuses",
+		Summary: "lists the statuses and the moves the workflow allows",
+		New:     func(com *Command) Executable { return (*StatusesCommand)(com) },
+	},
+	&CommandSpec{
+		Name:    "priority",
+		Args:    []Arg{taskID, {Name: "level"}},
+		Summary: "sets the priority of a task: none, low, medium, high or critical",
+		New:     func(com *Command) Executable { return (*PriorityCommand)(com) },
+	},
+	&CommandSpec{
+		Name:    "due",
+		Args:    []Arg{taskID, {Name: "date", Rest: true}},
+		Summary: "sets the due date of a task, or clears it with none",
+		Help:    "Dates can be today, tomorrow, friday, next week, 2006-01-02, in 3 days or 2w.",
+		New:     func(com *Command) Executable { return (*DueCommand)(com) },
+	},
+	&CommandSpec{
+		Name:    "tag",
+		Args:    []Arg{taskID, {Name: "tags", Rest: true}},
+		Summary: "adds +tag and removes -tag tags of a task",
+		New:     func(com *Command) Executable { return (*TagCommand)(com) },
+	},
+	&CommandSpec{
+		Name:    "tags",
+		Summary: "lists every tag with its number of tasks",
+		New:     func(com *Command) Executable { return (*TagsCommand)(com) },
+	},
+	&CommandSpec{
+		Name:    "project",
+		Args:    []Arg{taskID, {Name: "name"}},
+		Summary: "moves a task to a project, or out of it with none",
+		New:     func(com *Command) Executable { return (*ProjectCommand)(com) },
+	},
+	&CommandSpec{
+		Name:    "projects",
+		Args:    []Arg{{Name: "action", Optional: true}, {Name: "old", Optional: true}, {Name: "new", Optional: true}},
+		Summary: "lists projects with their task counts per status",
+		Help:    "projects rename old new renames a project and its subprojects.",
+		New:     func(com *Command) Executable { return (*ProjectsCommand)(com) },
+	},
+	&CommandSpec{
+		Name:    "parent",
+		Args:    []Arg{taskID, {Name: "parent"}},
+		Summary: "makes a task a subtask of another, or a top level task with none",
+		New:     func(com *Command) Executable { return (*ParentCommand)(com) },
+	},
+	&CommandSpec{
+		Name:    "recur",
+		Args:    []Arg{taskID, {Name: "rule", Rest: true}},
+		Summary: "makes a task repeat, or ends its series with stop",
+		Help:    "Rules are daily, weekly, weekly mon,thu, monthly, monthly 15 or every 3 days (counted from when it is done).",
+		New:     func(com *Command) Executable { return (*RecurCommand)(com) },
+	},
+	&CommandSpec{
+		Name:    "block",
+		Args:    []Arg{taskID},
+		Flags:   []Flag{{Name: "by", Kind: KindInt, Value: "id", Help: "the task that has to be done first"}},
+		Summary: "marks a task as unable to start until another is done",
+		New:     func(com *Command) Executable { return (*BlockCommand)(com) },
+	},
+	&CommandSpec{
+		Name:    "unblock",
+		Args:    []Arg{taskID},
+		Flags:   []Flag{{Name: "by", Kind: KindInt, Value: "id", Help: "the blocker to remove"}},
+		Summary: "removes a blocker from a task",
+		New:     func(com *Command) Executable { return (*BlockCommand)(com) },
+	},
+	&CommandSpec{
+		Name:    "graph",
+		Flags:   []Flag{{Name: "format", Value: "format", Default: "text", Help: "text, or dot for Graphviz"}},
+		Summary: "prints the dependencies between tasks",
+		New:     func(com *Command) Executable { return (*GraphCommand)(com) },
+	},
+	&CommandSpec{
+		Name:    "history",
+		Args:    []Arg{taskID},
+		Summary: "shows every change made to a task",
+		New:     func(com *Command) Executable { return (*HistoryCommand)(com) },
+	},
+	&CommandSpec{
+		Name:    "log",
+		Flags:   []Flag{{Name: "since", Value: "age", Help: "only changes in the last age, e.g. 2d"}},
+		Summary: "shows recent changes to all tasks",
+		New:     func(com *Command) Executable { return (*LogCommand)(com) },
+	},
+	&CommandSpec{
+		Name:    "undo",
+		Args:    []Arg{{Name: "n", Kind: KindInt, Optional: true}},
+		Summary: "reverts the last n changes, one by default",
+		New:     func(com *Command) Executable { return (*UndoCommand)(com) },
+	},
+	&CommandSpec{
+		Name:    "redo",
+		Args:    []Arg{{Name: "n", Kind: KindInt, Optional: true}},
+		Summary: "applies again the last n undone changes",
+		New:     func(com *Command) Executable { return (*UndoCommand)(com) },
+	},
+	&CommandSpec{
+		Name:    "archive",
+		Flags:   []Flag{{Name: "older-than", Value: "age", Help: "only tasks done before age, e.g. 30d"}},
+		Summary: "moves done tasks to the archive file",
+		New:     func(com *Command) Executable { return (*ArchiveCommand)(com) },
+	},
+	&CommandSpec{
+		Name:    "db",
+		Args:    []Arg{{Name: "action"}},
+		Flags:   []Flag{{Name: "dry-run", Kind: KindBool, Help: "only reports what would change"}},
+		Summary: "db migrate upgrades the database file to the current schema",
+		New:     func(com *Command) Executable { return (*DbCommand)(com) },
+	},
+	&CommandSpec{
+		Name:    "completion",
+		Args:    []Arg{{Name: "shell"}},
+		Summary: "prints the tab completion script for bash, zsh or fish",
+		Help: "Load it with source <(task-tracker completion bash) in ~/.bashrc, the same with zsh in\n" +
+			"~/.zshrc, or task-tracker completion fish | source in the fish config.",
+		New: func(com *Command) Executable { return (*CompletionCommand)(com) },
+	},
+	&CommandSpec{
+		Name:    "__complete",
+		Args:    []Arg{{Name: "words", Optional: true, Rest: true}},
+		Summary: "prints the completions of a command line, for the completion scripts",
+		Hidden:  true,
+		New:     func(com *Command) Executable { return (*CompleteCommand)(com) },
+	},
+	&CommandSpec{
+		Name:    "help",
+		Args:    []Arg{{Name: "command", Optional: true}},
+		Summary: "shows info for each command, or for one",
+		New:     func(com *Command) Executable { return (*HelpCommand)(com) },
+	},
+)
+
+// parseCommand looks up the command named by the first of args and parses
+// the rest for it. It returns ErrHelpRequested with the spec for --help.
+func parseCommand(args []string) (*CommandSpec, *Input, error) {
+	if len(args) == 0 {
+		return nil, nil, &UsageError{Err: errors.New("No commands received")}
+	}
+
+	commName := args[0]
+	if commName == "-h" || commName == "--help" {
+		commName = "help"
+	}
+	spec, ok := commands.Lookup(commName)
+	if !ok {
+		return nil, nil, &UsageError{Err: &UnknownCommandError{commName, commands.Suggest(commName)}}
+	}
+	in, err := spec.Parse(args[1:])
+	if err != nil && !errors.Is(err, ErrHelpRequested) {
+		return spec, nil, &UsageError{spec.Name, err}
+	}
+	return spec, in, err
+}
+
+// execute builds the command of spec on store and runs it.
+func execute(spec *CommandSpec, in *Input, config Config, store *tasks.Store) error {
+	comm := spec.New(&Command{spec, spec.Data, store, config})
+	if verifier, ok := comm.(Verifier); ok {
+		err := verifier.Verify(in)
+		// Checks of the args that are not about tasks are usage errors.
+		if err != nil && exitCode(err) == ExitFailure {
+			return &UsageError{spec.Name, err}
+		}
+		if err != nil {
+			return err
+		}
 	}
 
-	err = comm.Verify(receivedArgs[1:])
+	return comm.Execute(in)
+}
+
+// run runs the command in args and returns what went wrong, if anything.
+func run(flags globalFlags, args []string) error {
+	spec, in, err := parseCommand(args)
+	if errors.Is(err, ErrHelpRequested) {
+		fmt.Print(spec.Usage())
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	config, err := loadConfig()
+	if err == nil {
+		config, err = config.resolve(flags)
+	}
+	if err != nil {
+		return err
+	}
+	workflow, err := tasks.NewWorkflow(config.Statuses)
+	if err != nil {
+		return err
+	}
+	tasks.SetWorkflow(workflow)
+	repo, err := tasks.NewRepository(config.Backend, config.DB)
+	if err != nil {
+		return err
+	}
+	store, err := config.openStore(repo)
 	if err != nil {
-		fmt.Println(err.Error())
-		return
+		return err
 	}
 
-	err = comm.Execute(receivedArgs[1:])
+	return execute(spec, in, config, store)
+}
+
+func main() {
+	flags, receivedArgs := extractGlobalFlags(os.Args[1:])
+	var err error
+	switch flags.errorFormat {
+	case ErrorFormatText, ErrorFormatJSON:
+		err = run(flags, receivedArgs)
+	default:
+		err = &UsageError{Err: fmt.Errorf("invalid error format. Expected text or json. Received %s", flags.errorFormat)}
+	}
 	if err != nil {
-		fmt.Println(err.Error())
-		return
+		os.Exit(reportError(os.Stderr, flags.errorFormat, err))
 	}
 }
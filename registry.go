@@ -0,0 +1,412 @@
+package main
+
+import (
+	"backend/tasks"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ValueKind is the type of a positional arg or flag value, checked while
+// parsing.
+type ValueKind int
+
+const (
+	KindString ValueKind = iota
+	KindInt
+	KindBool
+)
+
+// Arg is a positional argument of a command.
+type Arg struct {
+	Name     string
+	Kind     ValueKind
+	Optional bool
+	// Rest takes every remaining word, so descriptions need no quotes. Only
+	// the last arg can have it.
+	Rest bool
+}
+
+// Flag is a --name option of a command. Bool flags take no value.
+type Flag struct {
+	Name    string
+	Kind    ValueKind
+	Default string
+	// Value names the value in usage, like level in --priority level.
+	Value string
+	Help  string
+}
+
+// CommandSpec declares a command: how it is called, what it accepts, its
+// help and how it is built.
+type CommandSpec struct {
+	Name    string
+	Aliases []string
+	Args    []Arg
+	Flags   []Flag
+	Summary string
+	// Help is shown by --help below the summary.
+	Help string
+	// Hidden commands are left out of help.
+	Hidden bool
+	// Data is handed to the command, for commands sharing a type.
+	Data map[string]any
+	New  func(com *Command) Executable
+}
+
+// Command is what every command type is made of.
+type Command struct {
+	spec   *CommandSpec
+	data   map[string]any
+	store  *tasks.Store
+	config Config
+}
+
+type Executable interface {
+	Execute(in *Input) error
+}
+
+// Verifier is implemented by commands that check their input beyond what the
+// spec declares, before anything runs.
+type Verifier interface {
+	Verify(in *Input) error
+}
+
+var ErrHelpRequested = errors.New("help requested")
+
+type InvalidArgNumberError struct {
+	commandName string
+	min, max    int
+	received    int
+}
+
+func (e *InvalidArgNumberError) Error() string {
+	expected := fmt.Sprintf("%d args", e.min)
+	switch {
+	case e.max == -1:
+		expected = fmt.Sprintf("at least %d args", e.min)
+	case e.min != e.max:
+		expected = fmt.Sprintf("%d to %d args", e.min, e.max)
+	}
+	return fmt.Sprintf("%s command expects %s, but received %d", e.commandName, expected, e.received)
+}
+
+type UnknownCommandError struct {
+	Name       string
+	Suggestion string
+}
+
+func (e *UnknownCommandError) Error() string {
+	if e.Suggestion != "" {
+		return fmt.Sprintf("no command named %s. Did you mean %s?", e.Name, e.Suggestion)
+	}
+	return "no command named " + e.Name
+}
+
+type UnknownFlagError struct {
+	Command    string
+	Flag       string
+	Suggestion string
+}
+
+func (e *UnknownFlagError) Error() string {
+	message := fmt.Sprintf("invalid option for %s command: --%s", e.Command, e.Flag)
+	if e.Suggestion != "" {
+		message += fmt.Sprintf(". Did you mean --%s?", e.Suggestion)
+	}
+	return message
+}
+
+// Input is a parsed command line. Flags without a value on the command line
+// hold their default.
+type Input struct {
+	spec  *CommandSpec
+	args  []string
+	flags map[string]string
+}
+
+// Arg returns a positional arg by name, with the words of a Rest arg joined
+// by spaces. Missing optional args are empty.
+func (in *Input) Arg(name string) string {
+	return strings.Join(in.Words(name), " ")
+}
+
+// Words returns the words of a positional arg, several for a Rest arg.
+func (in *Input) Words(name string) []string {
+	index := slices.IndexFunc(in.spec.Args, func(arg Arg) bool {
+		return arg.Name == name
+	})
+	if index == -1 || index >= len(in.args) {
+		return nil
+	}
+	if in.spec.Args[index].Rest {
+		return in.args[index:]
+	}
+	return in.args[index : index+1]
+}
+
+// Has reports whether a positional arg was given.
+func (in *Input) Has(name string) bool {
+	return len(in.Words(name)) > 0
+}
+
+// Int returns an int arg, which Parse has already checked.
+func (in *Input) Int(name string) int {
+	value, _ := strconv.Atoi(in.Arg(name))
+	return value
+}
+
+func (in *Input) Flag(name string) string {
+	return in.flags[name]
+}
+
+// FlagSet reports whether a flag was given or has a default.
+func (in *Input) FlagSet(name string) bool {
+	_, ok := in.flags[name]
+	return ok
+}
+
+func (in *Input) FlagInt(name string) int {
+	value, _ := strconv.Atoi(in.flags[name])
+	return value
+}
+
+func (in *Input) Switch(name string) bool {
+	value, _ := strconv.ParseBool(in.flags[name])
+	return value
+}
+
+func (spec *CommandSpec) flag(name string) (Flag, bool) {
+	index := slices.IndexFunc(spec.Flags, func(flag Flag) bool {
+		return flag.Name == name
+	})
+	if index == -1 {
+		return Flag{}, false
+	}
+	return spec.Flags[index], true
+}
+
+// argRange returns how many positional args the command takes, with -1 as
+// the maximum when the last arg is Rest.
+func (spec *CommandSpec) argRange() (int, int) {
+	required := 0
+	for _, arg := range spec.Args {
+		if !arg.Optional {
+			required++
+		}
+	}
+	if len(spec.Args) > 0 && spec.Args[len(spec.Args)-1].Rest {
+		return required, -1
+	}
+	return required, len(spec.Args)
+}
+
+var kindNames = map[ValueKind]string{KindString: "a value", KindInt: "a number", KindBool: "true or false"}
+
+func checkKind(kind ValueKind, value string) error {
+	var err error
+	switch kind {
+	case KindInt:
+		_, err = strconv.Atoi(value)
+	case KindBool:
+		_, err = strconv.ParseBool(value)
+	}
+	return err
+}
+
+// Parse reads args against the spec. Flags are --name value or --name=value
+// and can appear anywhere; words starting with a single dash, like -tag, are
+// positional, and so is everything after --. -h and --help return
+// ErrHelpRequested.
+func (spec *CommandSpec) Parse(args []string) (*Input, error) {
+	in := &Input{spec: spec, flags: map[string]string{}}
+	for _, flag := range spec.Flags {
+		if flag.Default != "" {
+			in.flags[flag.Name] = flag.Default
+		}
+	}
+
+	onlyArgs := false
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case onlyArgs || !strings.HasPrefix(arg, "-") || (!strings.HasPrefix(arg, "--") && arg != "-h"):
+			in.args = append(in.args, arg)
+		case arg == "--":
+			onlyArgs = true
+		case arg == "-h" || arg == "--help":
+			return nil, ErrHelpRequested
+		default:
+			name, value, hasValue := strings.Cut(arg[2:], "=")
+			flag, ok := spec.flag(name)
+			if !ok {
+				return nil, &UnknownFlagError{spec.Name, name, suggest(name, spec.flagNames())}
+			}
+			switch {
+			case flag.Kind == KindBool && !hasValue:
+				value = "true"
+			case !hasValue && i+1 == len(args):
+				return nil, fmt.Errorf("option --%s of %s command expects a value", name, spec.Name)
+			case !hasValue:
+				value = args[i+1]
+				i++
+			}
+			if err := checkKind(flag.Kind, value); err != nil {
+				return nil, fmt.Errorf("option --%s of %s command expects %s. Received %s", name, spec.Name, kindNames[flag.Kind], value)
+			}
+			in.flags[name] = value
+		}
+	}
+
+	least, most := spec.argRange()
+	if len(in.args) < least || (most != -1 && len(in.args) > most) {
+		return nil, &InvalidArgNumberError{spec.Name, least, most, len(in.args)}
+	}
+	for i, arg := range spec.Args {
+		if i < len(in.args) && checkKind(arg.Kind, in.args[i]) != nil {
+			return nil, fmt.Errorf("%s of %s command must be a number. Received %s", arg.Name, spec.Name, in.args[i])
+		}
+	}
+	return in, nil
+}
+
+func (spec *CommandSpec) flagNames() []string {
+	names := make([]string, len(spec.Flags))
+	for i, flag := range spec.Flags {
+		names[i] = flag.Name
+	}
+	return names
+}
+
+// Synopsis is the command with its positional args, like
+// "add <description...>".
+func (spec *CommandSpec) Synopsis() string {
+	parts := []string{spec.Name}
+	for _, arg := range spec.Args {
+		name := arg.Name
+		if arg.Rest {
+			name += "..."
+		}
+		if arg.Optional {
+			parts = append(parts, "["+name+"]")
+		} else {
+			parts = append(parts, "<"+name+">")
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// Usage is the --help text of the command.
+func (spec *CommandSpec) Usage() string {
+	var usage strings.Builder
+	synopsis := spec.Synopsis()
+	if len(spec.Flags) > 0 {
+		synopsis += " [options]"
+	}
+	fmt.Fprintf(&usage, "Usage: %s %s\n", programName(), synopsis)
+	fmt.Fprintln(&usage, spec.Summary)
+	if spec.Help != "" {
+		fmt.Fprintln(&usage, spec.Help)
+	}
+	if len(spec.Aliases) > 0 {
+		fmt.Fprintln(&usage, "Aliases:", strings.Join(spec.Aliases, ", "))
+	}
+	if len(spec.Flags) > 0 {
+		fmt.Fprintln(&usage, "Options:")
+		for _, flag := range spec.Flags {
+			name := "--" + flag.Name
+			if flag.Kind != KindBool {
+				name += " " + flag.Value
+			}
+			help := flag.Help
+			if flag.Default != "" {
+				help += fmt.Sprintf(" (default %s)", flag.Default)
+			}
+			fmt.Fprintf(&usage, " %-33s - %s\n", name, help)
+		}
+	}
+	return usage.String()
+}
+
+// Registry holds the commands of the program.
+type Registry struct {
+	specs []*CommandSpec
+}
+
+func NewRegistry(specs ...*CommandSpec) *Registry {
+	return &Registry{specs}
+}
+
+// Lookup finds a command by name or alias.
+func (r *Registry) Lookup(name string) (*CommandSpec, bool) {
+	for _, spec := range r.specs {
+		if spec.Name == name || slices.Contains(spec.Aliases, name) {
+			return spec, true
+		}
+	}
+	return nil, false
+}
+
+// Visible returns the commands shown in help, in declaration order.
+func (r *Registry) Visible() []*CommandSpec {
+	return slices.DeleteFunc(slices.Clone(r.specs), func(spec *CommandSpec) bool {
+		return spec.Hidden
+	})
+}
+
+// Names returns the names and aliases of the visible commands.
+func (r *Registry) Names() []string {
+	names := []string{}
+	for _, spec := range r.Visible() {
+		names = append(names, spec.Name)
+		names = append(names, spec.Aliases...)
+	}
+	return names
+}
+
+// Suggest returns the visible command name closest to a mistyped one, or ""
+// when none is close.
+func (r *Registry) Suggest(name string) string {
+	return suggest(name, r.Names())
+}
+
+// suggest returns the candidate within two edits of name, preferring the
+// closest.
+func suggest(name string, candidates []string) string {
+	best, bestDistance := "", 3
+	for _, candidate := range candidates {
+		if distance := editDistance(name, candidate); distance < bestDistance {
+			best, bestDistance = candidate, distance
+		}
+	}
+	return best
+}
+
+// editDistance is the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	previous := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current := make([]int, len(b)+1)
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous = current
+	}
+	return previous[len(b)]
+}
+
+func programName() string {
+	return filepath.Base(os.Args[0])
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"errors"
+	"maps"
+	"slices"
+	"testing"
+)
+
+var testSpec = &CommandSpec{
+	Name: "tag",
+	Args: []Arg{{Name: "id", Kind: KindInt}, {Name: "tags", Optional: true, Rest: true}},
+	Flags: []Flag{
+		{Name: "force", Kind: KindBool},
+		{Name: "by", Kind: KindInt},
+		{Name: "format", Default: "table"},
+	},
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		args      []string
+		wantArgs  []string
+		wantFlags map[string]string
+		wantErr   string
+	}{
+		{[]string{"1"}, []string{"1"}, map[string]string{"format": "table"}, ""},
+		{[]string{"1", "a", "b"}, []string{"1", "a", "b"}, map[string]string{"format": "table"}, ""},
+		{[]string{"--force", "1"}, []string{"1"}, map[string]string{"force": "true", "format": "table"}, ""},
+		{[]string{"1", "--force=false"}, []string{"1"}, map[string]string{"force": "false", "format": "table"}, ""},
+		{[]string{"1", "--by", "2", "--format=json"}, []string{"1"}, map[string]string{"by": "2", "format": "json"}, ""},
+		{[]string{"1", "-urgent"}, []string{"1", "-urgent"}, map[string]string{"format": "table"}, ""},
+		{[]string{"1", "--", "--force", "-h"}, []string{"1", "--force", "-h"}, map[string]string{"format": "table"}, ""},
+		{[]string{"1", "--by"}, nil, nil, "option --by of tag command expects a value"},
+		{[]string{"1", "--by", "two"}, nil, nil, "option --by of tag command expects a number. Received two"},
+		{[]string{"1", "--force=maybe"}, nil, nil, "option --force of tag command expects true or false. Received maybe"},
+		{[]string{"1", "--forse"}, nil, nil, "invalid option for tag command: --forse. Did you mean --force?"},
+		{[]string{"1", "--color"}, nil, nil, "invalid option for tag command: --color"},
+		{[]string{"one"}, nil, nil, "id of tag command must be a number. Received one"},
+		{nil, nil, nil, "tag command expects at least 1 args, but received 0"},
+	}
+	for _, test := range tests {
+		in, err := testSpec.Parse(test.args)
+		if test.wantErr != "" {
+			if err == nil || err.Error() != test.wantErr {
+				t.Errorf("Parse(%q): expected error %q, received %v", test.args, test.wantErr, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Parse(%q): unexpected error %v", test.args, err)
+			continue
+		}
+		if !slices.Equal(in.args, test.wantArgs) || !maps.Equal(in.flags, test.wantFlags) {
+			t.Errorf("Parse(%q): expected %q %v, received %q %v", test.args, test.wantArgs, test.wantFlags, in.args, in.flags)
+		}
+	}
+}
+
+func TestParseHelp(t *testing.T) {
+	for _, args := range [][]string{{"-h"}, {"1", "--help"}, {"--help", "--color"}} {
+		if _, err := testSpec.Parse(args); !errors.Is(err, ErrHelpRequested) {
+			t.Errorf("Parse(%q): expected ErrHelpRequested, received %v", args, err)
+		}
+	}
+}
+
+func TestInvalidArgNumber(t *testing.T) {
+	tests := []struct {
+		spec *CommandSpec
+		args []string
+		want string
+	}{
+		{&CommandSpec{Name: "undo"}, []string{"1"}, "undo command expects 0 args, but received 1"},
+		{&CommandSpec{Name: "mark", Args: []Arg{{Name: "id"}, {Name: "status"}}}, []string{"1"}, "mark command expects 2 args, but received 1"},
+		{
+			&CommandSpec{Name: "projects", Args: []Arg{{Name: "action", Optional: true}, {Name: "old", Optional: true}}},
+			[]string{"a", "b", "c"},
+			"projects command expects 0 to 2 args, but received 3",
+		},
+	}
+	for _, test := range tests {
+		_, err := test.spec.Parse(test.args)
+		var argErr *InvalidArgNumberError
+		if !errors.As(err, &argErr) || err.Error() != test.want {
+			t.Errorf("%s %q: expected %q, received %v", test.spec.Name, test.args, test.want, err)
+		}
+	}
+}
+
+func TestSuggest(t *testing.T) {
+	candidates := []string{"list", "add", "delete", "mark-done", "ls"}
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"list", "list"},
+		{"lsit", "list"},
+		{"ad", "add"},
+		{"delte", "delete"},
+		{"mark-dne", "mark-done"},
+		{"l", "ls"},
+		{"remove", ""},
+	}
+	for _, test := range tests {
+		if got := suggest(test.name, candidates); got != test.want {
+			t.Errorf("suggest(%q): expected %q, received %q", test.name, test.want, got)
+		}
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"add", "", 3},
+		{"", "add", 3},
+		{"list", "list", 0},
+		{"list", "lsit", 2},
+		{"kitten", "sitting", 3},
+		{"mark-done", "mark-dne", 1},
+	}
+	for _, test := range tests {
+		if got := editDistance(test.a, test.b); got != test.want {
+			t.Errorf("editDistance(%q, %q): expected %d, received %d", test.a, test.b, test.want, got)
+		}
+	}
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"backend/tasks"
+	"backend/tui"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// maxShellHistory is how many lines the shell history file keeps.
+const maxShellHistory = 500
+
+// shellCommands are the commands only the shell knows.
+var shellCommands = []string{"save", "exit", "quit"}
+
+// ShellCommand reads commands at a prompt and runs them on a session, a copy
+// of the database in memory that is written back on save and on exit. The
+// history, undo and archive record the changes once they are written.
+type ShellCommand Command
+
+func (com *ShellCommand) Execute(in *Input) error {
+	repo, err := tasks.NewRepository(com.config.Backend, com.config.DB)
+	if err != nil {
+		return err
+	}
+	session, err := tasks.OpenSession(repo)
+	if err != nil {
+		return err
+	}
+	store, err := com.config.openStore(session)
+	if err != nil {
+		return err
+	}
+
+	historyPath := com.config.shellHistory()
+	editor := &tui.LineEditor{
+		Prompt:   programName() + "> ",
+		History:  readShellHistory(historyPath),
+		Complete: shellCompleter(store),
+	}
+	fmt.Println("Type help for the commands, save to write the changes and exit to save and leave")
+	readLine := func() (string, error) {
+		return editor.ReadLine(os.Stdin, os.Stdout)
+	}
+	err = com.loop(readLine, os.Stderr, session, store)
+	if historyErr := writeShellHistory(historyPath, editor.History); historyErr != nil && err == nil {
+		err = historyErr
+	}
+
+	saved, saveErr := session.Save()
+	if saveErr != nil {
+		return errors.Join(err, saveErr)
+	}
+	if saved > 0 {
+		fmt.Printf("Saved %d tasks\n", saved)
+	}
+	return err
+}
+
+// loop runs the lines readLine returns until exit or the end of the input.
+// Failed commands are reported to errOut and the loop goes on.
+func (com *ShellCommand) loop(readLine func() (string, error), errOut io.Writer, session *tasks.Session, store *tasks.Store) error {
+	for {
+		line, err := readLine()
+		if errors.Is(err, tui.ErrInterrupted) {
+			continue
+		}
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		args, err := splitWords(line)
+		if err != nil || len(args) == 0 {
+			if err != nil {
+				reportError(errOut, com.config.errorFormat, &UsageError{Err: err})
+			}
+			continue
+		}
+		switch args[0] {
+		case "exit", "quit":
+			return nil
+		case "save":
+			saved, saveErr := session.Save()
+			err = saveErr
+			if err == nil {
+				fmt.Printf("Saved %d tasks\n", saved)
+			}
+		case com.spec.Name:
+			err = &UsageError{Err: errors.New("already in the shell")}
+		default:
+			err = com.run(args, store)
+		}
+		if err != nil {
+			reportError(errOut, com.config.errorFormat, err)
+		}
+	}
+}
+
+// run runs one command line of the shell.
+func (com *ShellCommand) run(args []string, store *tasks.Store) error {
+	spec, in, err := parseCommand(args)
+	if errors.Is(err, ErrHelpRequested) {
+		fmt.Print(spec.Usage())
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return execute(spec, in, com.config, store)
+}
+
+// splitWords splits a line into words at spaces. Single or double quotes
+// keep the spaces inside them. A backslash takes the next character as is,
+// except between single quotes.
+func splitWords(line string) ([]string, error) {
+	var words []string
+	var word strings.Builder
+	inWord, escaped := false, false
+	var quote rune
+	for _, r := range line {
+		switch {
+		case escaped:
+			word.WriteRune(r)
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped, inWord = true, true
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote != 0:
+			word.WriteRune(r)
+		case r == '\'' || r == '"':
+			quote, inWord = r, true
+		case r == ' ' || r == '\t':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	if escaped {
+		return nil, errors.New("missing character after \\")
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("missing closing %c", quote)
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
+
+// shellCompleter completes the lines of the shell like the completion
+// scripts complete command lines, adding the commands of the shell.
+func shellCompleter(store *tasks.Store) func(before string) []string {
+	return func(before string) []string {
+		words := strings.Fields(before)
+		current := ""
+		if len(words) > 0 && !strings.HasSuffix(before, " ") {
+			current = words[len(words)-1]
+			words = words[:len(words)-1]
+		}
+		values := []string{}
+		for _, candidate := range completions(store, words, current) {
+			values = append(values, candidate.Value)
+		}
+		if len(words) == 0 {
+			values = append(values, shellCommands...)
+		}
+		return values
+	}
+}
+
+// readShellHistory returns the lines of the history file. A missing file is
+// an empty history.
+func readShellHistory(path string) []string {
+	if path == "" {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	return strings.FieldsFunc(string(data), func(r rune) bool {
+		return r == '\n'
+	})
+}
+
+func writeShellHistory(path string, history []string) error {
+	if path == "" || len(history) == 0 {
+		return nil
+	}
+	history = history[max(len(history)-maxShellHistory, 0):]
+	return os.WriteFile(path, []byte(strings.Join(history, "\n")+"\n"), 0o644)
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"backend/tasks"
+	"io"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestSplitWords(t *testing.T) {
+	tests := []struct {
+		line    string
+		want    []string
+		wantErr string
+	}{
+		{"", nil, ""},
+		{"  list   --sort due ", []string{"list", "--sort", "due"}, ""},
+		{"add 'buy milk' now", []string{"add", "buy milk", "now"}, ""},
+		{`add "it's done"`, []string{"add", "it's done"}, ""},
+		{`add ""`, []string{"add", ""}, ""},
+		{"add a''b", []string{"add", "ab"}, ""},
+		{`add buy\ milk`, []string{"add", "buy milk"}, ""},
+		{`add \"quoted\"`, []string{"add", `"quoted"`}, ""},
+		{`add "say \"hi\""`, []string{"add", `say "hi"`}, ""},
+		{`add 'a\b'`, []string{"add", `a\b`}, ""},
+		{"add 'buy milk", nil, "missing closing '"},
+		{`add "buy milk`, nil, `missing closing "`},
+		{`add milk\`, nil, `missing character after \`},
+	}
+	for _, test := range tests {
+		got, err := splitWords(test.line)
+		switch {
+		case test.wantErr != "" && (err == nil || err.Error() != test.wantErr):
+			t.Errorf("splitWords(%q): expected error %q, received %v", test.line, test.wantErr, err)
+		case test.wantErr == "" && err != nil:
+			t.Errorf("splitWords(%q): unexpected error %v", test.line, err)
+		case !slices.Equal(got, test.want):
+			t.Errorf("splitWords(%q): expected %q, received %q", test.line, test.want, got)
+		}
+	}
+}
+
+// openShell returns a shell on a new database, the session it runs on and a
+// store reading the database itself.
+func openShell(t *testing.T) (*ShellCommand, *tasks.Session, *tasks.Store, *tasks.Store) {
+	t.Helper()
+	config := Config{Backend: tasks.BackendJSON, DB: filepath.Join(t.TempDir(), "tasks.json"), User: "ana", errorFormat: ErrorFormatText}
+	repo, err := tasks.NewRepository(config.Backend, config.DB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	session, err := tasks.OpenSession(repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store, err := config.openStore(session)
+	if err != nil {
+		t.Fatal(err)
+	}
+	spec, _ := commands.Lookup("shell")
+	return &ShellCommand{spec: spec, config: config}, session, store, tasks.NewStore(repo)
+}
+
+func storedDescriptions(t *testing.T, store *tasks.Store) string {
+	t.Helper()
+	list, err := store.Query(tasks.Query{Status: tasks.AllTasks})
+	if err != nil {
+		t.Fatal(err)
+	}
+	descriptions := make([]string, len(list))
+	for i, task := range list {
+		descriptions[i] = task.Description
+	}
+	return strings.Join(descriptions, ", ")
+}
+
+func TestShellLoop(t *testing.T) {
+	shell, session, store, stored := openShell(t)
+	lines := []string{
+		"add first",
+		"add 'second task'",
+		"mark-done 9",
+		"shell",
+		`add "unterminated`,
+		"save",
+		"add third",
+		"exit",
+		"add never",
+	}
+	read := 0
+	readLine := func() (string, error) {
+		if read == len(lines) {
+			return "", io.EOF
+		}
+		line := lines[read]
+		read++
+		switch line {
+		case "save":
+			if got := storedDescriptions(t, stored); got != "" {
+				t.Errorf("nothing should be written before save, received %q", got)
+			}
+		case "add third":
+			if got := storedDescriptions(t, stored); got != "first, second task" {
+				t.Errorf("expected save to write the first two tasks, received %q", got)
+			}
+		}
+		return line, nil
+	}
+
+	var errOut strings.Builder
+	if err := shell.loop(readLine, &errOut, session, store); err != nil {
+		t.Fatal(err)
+	}
+	if read != len(lines)-1 {
+		t.Errorf("expected exit to stop the loop, %d lines read", read)
+	}
+	for _, want := range []string{"id 9 not found", "already in the shell", "missing closing \""} {
+		if !strings.Contains(errOut.String(), want) {
+			t.Errorf("expected %q reported, received\n%s", want, errOut.String())
+		}
+	}
+	if got := storedDescriptions(t, stored); got != "first, second task" {
+		t.Errorf("the lines after the last save should wait for it, received %q", got)
+	}
+	if _, err := session.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if got := storedDescriptions(t, stored); got != "first, second task, third" {
+		t.Errorf("expected the third task written on the last save, received %q", got)
+	}
+}
+
+func TestShellLoopEndsAtEndOfInput(t *testing.T) {
+	shell, session, store, _ := openShell(t)
+	readLine := func() (string, error) { return "", io.EOF }
+	if err := shell.loop(readLine, io.Discard, session, store); err != nil {
+		t.Errorf("the end of the input should end the loop without an error, received %v", err)
+	}
+}
@@ -0,0 +1,116 @@
+package tasks
+
+import (
+	"errors"
+	"slices"
+	"time"
+)
+
+var ErrNoArchive = errors.New("this store has no archive")
+
+// WithArchive moves archived tasks to archive, a repository of its own so the
+// main one stays small. With autoAfter set, every change also archives the
+// tasks closed for longer than autoAfter.
+func WithArchive(archive Repository, autoAfter time.Duration) StoreOption {
+	return func(s *Store) {
+		s.archive = archive
+		if autoAfter <= 0 {
+			return
+		}
+		s.observers = append(s.observers, func(changes []TaskChange) error {
+			_, err := s.Archive(time.Now().Add(-autoAfter))
+			return err
+		})
+	}
+}
+
+// archivable returns the closed tasks last changed before a time. A task
+// stays while any of its subtasks stays, so no subtask is left without its
+// parent.
+func archivable(tasks []Task, before time.Time) []Task {
+	candidates := map[int]bool{}
+	for _, task := range tasks {
+		if task.Status.Closed() && task.LastChange().Before(before) {
+			candidates[task.ID] = true
+		}
+	}
+	for changed := true; changed; {
+		changed = false
+		for _, task := range tasks {
+			if task.Parent != 0 && candidates[task.Parent] && !candidates[task.ID] {
+				delete(candidates, task.Parent)
+				changed = true
+			}
+		}
+	}
+	return slices.DeleteFunc(slices.Clone(tasks), func(task Task) bool {
+		return !candidates[task.ID]
+	})
+}
+
+// Archive moves the tasks closed before a time to the archive and returns
+// their IDs. Archived tasks keep their ID and can still be read with GetTask
+// and QueryArchive, but no longer changed, so operations on them can no longer
+// be undone.
+func (s *Store) Archive(before time.Time) ([]int, error) {
+	if s.archive == nil {
+		return nil, ErrNoArchive
+	}
+	var archived []int
+	var changes []TaskChange
+	err := s.trash.Repository.Atomic(func(repo Repository) error {
+		tasks, listErr := (&trashRepository{repo}).List()
+		if listErr != nil {
+			return listErr
+		}
+		moved := archivable(tasks, before)
+		if len(moved) == 0 {
+			return nil
+		}
+		// The archive is written first: if removing the tasks fails they are
+		// in both places, and GetTask prefers the main repository.
+		archiveErr := s.beforeSave(func() error {
+			return s.archive.Atomic(func(archive Repository) error {
+				for _, task := range moved {
+					if restoreErr := archive.Restore(task); restoreErr != nil {
+						return restoreErr
+					}
+				}
+				return nil
+			})
+		})
+		if archiveErr != nil {
+			return archiveErr
+		}
+		for _, task := range moved {
+			if deleteErr := repo.Delete(task.ID); deleteErr != nil {
+				return deleteErr
+			}
+			archived = append(archived, task.ID)
+			changes = append(changes, TaskChange{Before: &task, Archived: true})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(changes) > 0 && s.observed() {
+		if notifyErr := s.notify(changes); notifyErr != nil {
+			return archived, notifyErr
+		}
+	}
+	if s.undo != nil && len(archived) > 0 {
+		if forgetErr := s.undo.forget(archived); forgetErr != nil {
+			return archived, forgetErr
+		}
+	}
+	return archived, nil
+}
+
+// QueryArchive works like Query on the archived tasks.
+func (s *Store) QueryArchive(q Query) ([]Task, error) {
+	if s.archive == nil {
+		return nil, ErrNoArchive
+	}
+	return queryRepository(s.archive, q)
+}
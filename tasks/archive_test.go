@@ -0,0 +1,114 @@
+package tasks
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// forEachArchive runs test with every backend archiving into a repository of
+// the same kind.
+func forEachArchive(t *testing.T, test func(t *testing.T, store *Store)) {
+	for _, backend := range Backends {
+		t.Run(backend, func(t *testing.T) {
+			dir := t.TempDir()
+			repo, repoErr := NewRepository(backend, filepath.Join(dir, "tasks."+backend))
+			archive, archiveErr := NewRepository(backend, filepath.Join(dir, "tasks.archive."+backend))
+			if err := errors.Join(repoErr, archiveErr); err != nil {
+				t.Fatal(err)
+			}
+			test(t, NewStore(repo, WithArchive(archive, 0)))
+		})
+	}
+}
+
+func TestArchive(t *testing.T) {
+	forEachArchive(t, func(t *testing.T, store *Store) {
+		store.AddTask("done")
+		store.AddTask("open")
+		store.UpdateTaskStatus(1, Done)
+
+		if archived, _ := store.Archive(time.Now().Add(-time.Hour)); len(archived) != 0 {
+			t.Errorf("nothing was done an hour ago, received %v", archived)
+		}
+		archived, err := store.Archive(time.Now().Add(time.Second))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(archived) != 1 || archived[0] != 1 {
+			t.Errorf("expected task 1 archived, received %v", archived)
+		}
+
+		if tasks, _ := store.Query(Query{Status: AllTasks}); taskIDs(tasks) != "2" {
+			t.Errorf("expected only the open task left, received %v", tasks)
+		}
+		if ids := archivedIDs(t, store); ids != "1" {
+			t.Errorf("expected the done task in the archive, received %s", ids)
+		}
+		if task, err := store.GetTask(1); err != nil || task.Description != "done" {
+			t.Errorf("GetTask should fall back to the archive, received %v %v", task, err)
+		}
+		if task, _ := store.AddTask("new"); task.ID != 3 {
+			t.Errorf("archived IDs should not be handed out again, received %d", task.ID)
+		}
+	})
+}
+
+func TestArchiveKeepsParentsOfOpenSubtasks(t *testing.T) {
+	forEachArchive(t, func(t *testing.T, store *Store) {
+		addTree(t, store)
+		store.UpdateTaskStatus(2, Done)
+		store.ForceTaskStatus(3, Done)
+
+		archived, _ := store.Archive(time.Now().Add(time.Second))
+		if archivedIDs(t, store) != "2" || len(archived) != 1 {
+			t.Errorf("expected task 3 kept for its open subtask, received %v", archived)
+		}
+	})
+}
+
+func TestAutoArchive(t *testing.T) {
+	store := NewStore(NewMemoryRepository(), WithArchive(NewMemoryRepository(), time.Nanosecond))
+	store.AddTask("first")
+	store.UpdateTaskStatus(1, Done)
+	time.Sleep(time.Millisecond)
+	store.AddTask("second")
+
+	if _, err := store.repo.Get(1); err == nil {
+		t.Error("a write should archive the tasks done for longer than the policy")
+	}
+	if _, err := NewStore(NewMemoryRepository()).Archive(time.Now()); !errors.Is(err, ErrNoArchive) {
+		t.Errorf("expected ErrNoArchive, received %v", err)
+	}
+}
+
+func archivedIDs(t *testing.T, store *Store) string {
+	t.Helper()
+	tasks, err := store.QueryArchive(Query{Status: AllTasks})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return taskIDs(tasks)
+}
+
+func TestArchiveIsRecorded(t *testing.T) {
+	history, undo := NewMemoryHistory(), NewMemoryUndoLog()
+	store := NewStore(NewMemoryRepository(), WithHistory(history, "ana"), WithUndo(undo, 0), WithArchive(NewMemoryRepository(), 0))
+	store.AddTask("first")
+	store.AddTask("second")
+	store.UpdateTaskStatus(1, Done)
+	if _, err := store.Archive(time.Now().Add(time.Second)); err != nil {
+		t.Fatal(err)
+	}
+
+	events, _ := store.History(1)
+	if len(events) != 3 || events[2].Action != ActionArchive {
+		t.Errorf("expected the archiving recorded last, received %v", events)
+	}
+	// The operations on task 1 are dropped, leaving the add of task 2 to
+	// undo.
+	if undone, err := store.Undo(1); err != nil || undone[0].Summary() != "created task 2" {
+		t.Errorf("expected the operations on the archived task dropped, received %v %v", undone, err)
+	}
+}
@@ -0,0 +1,244 @@
+package tasks
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+type ErrBlocked struct {
+	ID       int
+	Blockers []int
+}
+
+func (e *ErrBlocked) Error() string {
+	return fmt.Sprintf("task %d is blocked by %s. Finish them first or force it", e.ID, joinIDs(e.Blockers))
+}
+
+type ErrDependencyCycle struct {
+	// Cycle lists the tasks in order, each blocked by the next, and ends with
+	// the task it starts with.
+	Cycle []int
+}
+
+func (e *ErrDependencyCycle) Error() string {
+	steps := make([]string, len(e.Cycle))
+	for i, id := range e.Cycle {
+		steps[i] = strconv.Itoa(id)
+	}
+	return fmt.Sprintf("dependency cycle: %s (each task is blocked by the next)", strings.Join(steps, " -> "))
+}
+
+// openTasks returns the IDs of the tasks whose status is not closed.
+func openTasks(tasks []Task) map[int]bool {
+	open := map[int]bool{}
+	for _, task := range tasks {
+		if !task.Status.Closed() {
+			open[task.ID] = true
+		}
+	}
+	return open
+}
+
+// openBlockers returns the blockers of task that are not done yet. Blockers
+// that no longer exist do not count.
+func openBlockers(repo Repository, task Task) ([]int, error) {
+	tasks, err := repo.List()
+	if err != nil {
+		return nil, err
+	}
+	open := openTasks(tasks)
+	return slices.DeleteFunc(slices.Clone(task.BlockedBy), func(id int) bool {
+		return !open[id]
+	}), nil
+}
+
+// checkCanStart refuses to start or finish a task while it is blocked.
+func checkCanStart(repo Repository, task Task) error {
+	blockers, err := openBlockers(repo, task)
+	if err != nil {
+		return err
+	}
+	if len(blockers) > 0 {
+		return &ErrBlocked{task.ID, blockers}
+	}
+	return nil
+}
+
+// dependencyPath returns the chain of tasks from one to another following
+// BlockedBy, or nil if to is not reached.
+func dependencyPath(tasks []Task, from, to int) []int {
+	blockedBy := map[int][]int{}
+	for _, task := range tasks {
+		blockedBy[task.ID] = task.BlockedBy
+	}
+	visited := map[int]bool{}
+	var walk func(id int) []int
+	walk = func(id int) []int {
+		if id == to {
+			return []int{id}
+		}
+		if visited[id] {
+			return nil
+		}
+		visited[id] = true
+		for _, next := range blockedBy[id] {
+			if path := walk(next); path != nil {
+				return append([]int{id}, path...)
+			}
+		}
+		return nil
+	}
+	return walk(from)
+}
+
+// Block records that id cannot start until by is done.
+func (s *Store) Block(id, by int) (Task, error) {
+	var modified Task
+	err := s.repo.Atomic(func(repo Repository) error {
+		task, getErr := repo.Get(id)
+		if getErr != nil {
+			return getErr
+		}
+		if _, blockerErr := repo.Get(by); blockerErr != nil {
+			return blockerErr
+		}
+		tasks, listErr := repo.List()
+		if listErr != nil {
+			return listErr
+		}
+		if path := dependencyPath(tasks, by, id); path != nil {
+			return &ErrDependencyCycle{append([]int{id}, path...)}
+		}
+		if !slices.Contains(task.BlockedBy, by) {
+			task.BlockedBy = append(slices.Clone(task.BlockedBy), by)
+			slices.Sort(task.BlockedBy)
+		}
+		setUpdatedDate(&task)
+		modified = task
+		return repo.Update(task)
+	})
+	if err != nil {
+		return ZeroTask, err
+	}
+	return modified, nil
+}
+
+// Unblock removes by from the blockers of id.
+func (s *Store) Unblock(id, by int) (Task, error) {
+	return s.modifyTask(id, func(task *Task) {
+		task.BlockedBy = slices.DeleteFunc(slices.Clone(task.BlockedBy), func(blocker int) bool {
+			return blocker == by
+		})
+		setUpdatedDate(task)
+	})
+}
+
+// removeBlocker drops deleted tasks from the blockers of the rest.
+func removeBlocker(repo Repository, deleted []int) error {
+	tasks, err := repo.List()
+	if err != nil {
+		return err
+	}
+	for _, task := range tasks {
+		if !slices.ContainsFunc(task.BlockedBy, func(id int) bool { return slices.Contains(deleted, id) }) {
+			continue
+		}
+		task.BlockedBy = slices.DeleteFunc(slices.Clone(task.BlockedBy), func(id int) bool {
+			return slices.Contains(deleted, id)
+		})
+		if updateErr := repo.Update(task); updateErr != nil {
+			return updateErr
+		}
+	}
+	return nil
+}
+
+// dependencyOrder returns the tasks that block or are blocked by others,
+// each after its blockers. Ties keep the order of tasks.
+func dependencyOrder(tasks []Task) []Task {
+	present := map[int]bool{}
+	for _, task := range tasks {
+		present[task.ID] = true
+	}
+	involved := map[int]bool{}
+	for _, task := range tasks {
+		for _, blocker := range task.BlockedBy {
+			if present[blocker] {
+				involved[task.ID] = true
+				involved[blocker] = true
+			}
+		}
+	}
+
+	ordered := []Task{}
+	placed := map[int]bool{}
+	for progress := true; progress; {
+		progress = false
+		for _, task := range tasks {
+			if !involved[task.ID] || placed[task.ID] {
+				continue
+			}
+			waiting := slices.ContainsFunc(task.BlockedBy, func(id int) bool {
+				return involved[id] && !placed[id]
+			})
+			if !waiting {
+				ordered = append(ordered, task)
+				placed[task.ID] = true
+				progress = true
+			}
+		}
+	}
+	return ordered
+}
+
+// GraphText renders the dependencies between tasks as one line per task,
+// blockers first.
+func GraphText(tasks []Task) string {
+	var graph strings.Builder
+	for _, task := range dependencyOrder(tasks) {
+		fmt.Fprintf(&graph, "#%d %s [%s]", task.ID, task.Description, task.Status.Name())
+		if len(task.BlockedBy) > 0 {
+			ids := make([]string, len(task.BlockedBy))
+			for i, id := range task.BlockedBy {
+				ids[i] = "#" + strconv.Itoa(id)
+			}
+			fmt.Fprintf(&graph, " waits for %s", strings.Join(ids, ", "))
+		}
+		graph.WriteString("\n")
+	}
+	return graph.String()
+}
+
+// dotEscaper escapes the characters that end or escape a quoted DOT string.
+var dotEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+
+// GraphDOT renders the dependencies between tasks in the Graphviz DOT
+// language, with an edge from each blocker to the tasks it blocks. Blockers
+// that are not among tasks get no edge, so the graph has no unlabeled nodes.
+func GraphDOT(tasks []Task) string {
+	var graph strings.Builder
+	graph.WriteString("digraph dependencies {\n\trankdir=LR;\n\tnode [shape=box];\n")
+	ordered := dependencyOrder(tasks)
+	listed := map[int]bool{}
+	for _, task := range ordered {
+		listed[task.ID] = true
+		label := dotEscaper.Replace(fmt.Sprintf("#%d %s", task.ID, task.Description))
+		style := ""
+		if task.Status.Closed() {
+			style = ", style=dashed"
+		}
+		fmt.Fprintf(&graph, "\t%d [label=\"%s\"%s];\n", task.ID, label, style)
+	}
+	for _, task := range ordered {
+		for _, blocker := range task.BlockedBy {
+			if !listed[blocker] {
+				continue
+			}
+			fmt.Fprintf(&graph, "\t%d -> %d;\n", blocker, task.ID)
+		}
+	}
+	graph.WriteString("}\n")
+	return graph.String()
+}
@@ -0,0 +1,177 @@
+package tasks
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// addPipeline adds 1 build, 2 test blocked by 1, 3 release blocked by 2 and
+// 4 docs blocked by nothing.
+func addPipeline(t *testing.T, store *Store) {
+	t.Helper()
+	for _, description := range []string{"build", "test", "release", "docs"} {
+		store.AddTask(description)
+	}
+	for _, edge := range [][2]int{{2, 1}, {3, 2}} {
+		if _, err := store.Block(edge[0], edge[1]); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestBlockedTaskCannotStart(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, store *Store) {
+		addPipeline(t, store)
+
+		store.Block(3, 1)
+		task, _ := store.GetTask(3)
+		if !slices.Equal(task.BlockedBy, []int{1, 2}) {
+			t.Errorf("expected blockers 1 and 2, received %v", task.BlockedBy)
+		}
+
+		expectedErrType := &ErrBlocked{}
+		_, err := store.UpdateTaskStatus(2, InProgress)
+		if !errors.As(err, &expectedErrType) || !slices.Equal(expectedErrType.Blockers, []int{1}) {
+			t.Errorf("expected ErrBlocked by 1, received %v", err)
+		}
+		if _, err := store.ForceTaskStatus(2, InProgress); err != nil {
+			t.Errorf("forcing should skip the check: %s", err)
+		}
+
+		store.UpdateTaskStatus(1, Done)
+		if _, err := store.UpdateTaskStatus(2, Done); err != nil {
+			t.Errorf("a task whose blockers are done can finish: %s", err)
+		}
+	})
+}
+
+func TestUnblockKeepsRecordedChanges(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, store *Store) {
+		store = NewStore(store.repo, WithHistory(NewMemoryHistory(), "ana"), WithUndo(NewMemoryUndoLog(), 0))
+		addPipeline(t, store)
+		if _, err := store.Unblock(2, 1); err != nil {
+			t.Fatal(err)
+		}
+
+		events, _ := store.History(2)
+		last := events[len(events)-1]
+		if last.Field != "BlockedBy" || string(last.Old) != "[1]" {
+			t.Errorf("expected the removed blocker recorded, received %s", last)
+		}
+		if _, err := store.Undo(1); err != nil {
+			t.Fatal(err)
+		}
+		if task, _ := store.GetTask(2); !slices.Equal(task.BlockedBy, []int{1}) {
+			t.Errorf("expected undo to put blocker 1 back, received %v", task.BlockedBy)
+		}
+
+		store.DeleteTask(2)
+		events, _ = store.History(3)
+		last = events[len(events)-1]
+		if last.Field != "BlockedBy" || string(last.Old) != "[2]" {
+			t.Errorf("expected the deleted blocker recorded, received %s", last)
+		}
+	})
+}
+
+func TestReadyView(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, store *Store) {
+		addPipeline(t, store)
+		query, _ := ViewQuery(ViewReady, dueNow)
+
+		ready, err := store.Query(query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ids := taskIDs(ready); ids != "1 4" {
+			t.Errorf("expected 1 and 4 ready, received %s", ids)
+		}
+
+		store.UpdateTaskStatus(1, Done)
+		store.DeleteTask(2)
+		ready, _ = store.Query(query)
+		if ids := taskIDs(ready); ids != "3 4" {
+			t.Errorf("expected 3 and 4 ready, received %s", ids)
+		}
+		if task, _ := store.GetTask(3); len(task.BlockedBy) != 0 {
+			t.Errorf("deleted tasks should stop blocking, received %v", task.BlockedBy)
+		}
+	})
+}
+
+func TestBlockRejectsCycles(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, store *Store) {
+		addPipeline(t, store)
+
+		expectedErrType := &ErrDependencyCycle{}
+		_, err := store.Block(1, 3)
+		if !errors.As(err, &expectedErrType) {
+			t.Fatalf("expected ErrDependencyCycle, received %v", err)
+		}
+		if !slices.Equal(expectedErrType.Cycle, []int{1, 3, 2, 1}) {
+			t.Errorf("expected cycle 1 3 2 1, received %v", expectedErrType.Cycle)
+		}
+		if !strings.Contains(err.Error(), "1 -> 3 -> 2 -> 1") {
+			t.Errorf("the error should name the cycle: %s", err)
+		}
+		if _, err := store.Block(4, 4); !errors.As(err, &expectedErrType) {
+			t.Errorf("a task cannot block itself, received %v", err)
+		}
+
+		unblocked, err := store.Unblock(3, 2)
+		if err != nil || len(unblocked.BlockedBy) != 0 {
+			t.Errorf("expected no blockers left, received %v (%v)", unblocked.BlockedBy, err)
+		}
+		if _, err := store.Block(1, 3); err != nil {
+			t.Errorf("without the cycle 1 can wait for 3: %s", err)
+		}
+	})
+}
+
+func TestGraph(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, store *Store) {
+		addPipeline(t, store)
+		tasks, _ := store.Query(Query{Status: AllTasks})
+
+		store.Block(3, 1)
+		tasks, _ = store.Query(Query{Status: AllTasks})
+		expectedText := "#1 build [TO DO]\n#2 test [TO DO] waits for #1\n#3 release [TO DO] waits for #1, #2\n"
+		if text := GraphText(tasks); text != expectedText {
+			t.Errorf("expected\n%s\nreceived\n%s", expectedText, text)
+		}
+
+		dot := GraphDOT(tasks)
+		for _, line := range []string{"digraph dependencies {", `1 [label="#1 build"];`, "1 -> 3;", "2 -> 3;"} {
+			if !strings.Contains(dot, line) {
+				t.Errorf("expected %q in\n%s", line, dot)
+			}
+		}
+		if strings.Contains(dot, "docs") {
+			t.Errorf("tasks without dependencies should be left out:\n%s", dot)
+		}
+	})
+}
+
+func TestGraphDOTLabelsAndMissingBlockers(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, store *Store) {
+		addPipeline(t, store)
+		store.UpdateTask(2, `run "unit" tests in C:\src café`)
+		tasks, _ := store.Query(Query{Status: AllTasks})
+
+		dot := GraphDOT(tasks)
+		if label := `2 [label="#2 run \"unit\" tests in C:\\src café"];`; !strings.Contains(dot, label) {
+			t.Errorf("expected %q in\n%s", label, dot)
+		}
+
+		withoutBuild := slices.DeleteFunc(tasks, func(task Task) bool { return task.ID == 1 })
+		dot = GraphDOT(withoutBuild)
+		if strings.Contains(dot, "1 ->") {
+			t.Errorf("blockers that are not listed should get no edge:\n%s", dot)
+		}
+		if !strings.Contains(dot, "2 -> 3;") {
+			t.Errorf("expected the edges between listed tasks kept:\n%s", dot)
+		}
+	})
+}
@@ -0,0 +1,141 @@
+package tasks
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type ErrInvalidDueDate struct {
+	Value string
+}
+
+func (e *ErrInvalidDueDate) Error() string {
+	return fmt.Sprintf("invalid due date %q. Expected today, tomorrow, a weekday, next week, a date like 2006-01-02 or an offset like \"in 3 days\"", e.Value)
+}
+
+// startOfDay returns midnight of the day t falls on, in t's location.
+func startOfDay(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, t.Location())
+}
+
+// startOfWeek returns midnight of the Monday of the week t falls on.
+func startOfWeek(t time.Time) time.Time {
+	daysSinceMonday := (int(t.Weekday()) + 6) % 7
+	return startOfDay(t).AddDate(0, 0, -daysSinceMonday)
+}
+
+func parseWeekday(name string) (time.Weekday, bool) {
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		full := strings.ToLower(day.String())
+		if name == full || name == full[:3] {
+			return day, true
+		}
+	}
+	return 0, false
+}
+
+// parseOffset reads "3d", "+2w" and "3 days" style offsets.
+func parseOffset(value string) (years, months, days int, ok bool) {
+	value = strings.TrimPrefix(value, "+")
+	number := strings.TrimRight(value, "abcdefghijklmnopqrstuvwxyz ")
+	unit := strings.TrimSpace(value[len(number):])
+	count, err := strconv.Atoi(number)
+	if err != nil || count < 0 {
+		return 0, 0, 0, false
+	}
+	switch unit {
+	case "d", "day", "days":
+		return 0, 0, count, true
+	case "w", "week", "weeks":
+		return 0, 0, 7 * count, true
+	case "m", "month", "months":
+		return 0, count, 0, true
+	case "y", "year", "years":
+		return count, 0, 0, true
+	}
+	return 0, 0, 0, false
+}
+
+// ParseDueDate reads a due date relative to now and returns midnight of that
+// day in now's location. It understands
+//
+//   - today, tomorrow and yesterday
+//   - weekday names like friday or fri, meaning the next such day with today
+//     included, and next friday, meaning the one a week after that
+//   - next week (its Monday) and next month (its first day)
+//   - ISO dates like 2006-01-02
+//   - offsets like "in 3 days", "2w" or "+1 month"
+//
+// "none" returns the zero time, which clears a due date.
+func ParseDueDate(value string, now time.Time) (time.Time, error) {
+	today := startOfDay(now)
+	normalized := strings.Join(strings.Fields(strings.ToLower(value)), " ")
+
+	switch normalized {
+	case "none":
+		return time.Time{}, nil
+	case "today":
+		return today, nil
+	case "tomorrow":
+		return today.AddDate(0, 0, 1), nil
+	case "yesterday":
+		return today.AddDate(0, 0, -1), nil
+	case "next week":
+		return startOfWeek(today).AddDate(0, 0, 7), nil
+	case "next month":
+		return today.AddDate(0, 0, 1-today.Day()).AddDate(0, 1, 0), nil
+	}
+
+	name, next := strings.CutPrefix(normalized, "next ")
+	if weekday, ok := parseWeekday(name); ok {
+		days := (int(weekday) - int(today.Weekday()) + 7) % 7
+		if next {
+			days += 7
+		}
+		return today.AddDate(0, 0, days), nil
+	}
+
+	if date, err := time.ParseInLocation(time.DateOnly, normalized, now.Location()); err == nil {
+		return date, nil
+	}
+	if years, months, days, ok := parseOffset(strings.TrimPrefix(normalized, "in ")); ok {
+		return today.AddDate(years, months, days), nil
+	}
+	return time.Time{}, &ErrInvalidDueDate{value}
+}
+
+// DueLabel describes a due date relative to now, such as "today", "tomorrow"
+// or "2026-03-02 (overdue)".
+func DueLabel(due, now time.Time) string {
+	if due.IsZero() {
+		return "none"
+	}
+	due = due.In(now.Location())
+	switch days := daysBetween(now, due); {
+	case days == 0:
+		return "today"
+	case days == 1:
+		return "tomorrow"
+	case days < 0:
+		return due.Format("Mon 2006-01-02") + " (overdue)"
+	}
+	return due.Format("Mon 2006-01-02")
+}
+
+// daysBetween counts the calendar days from a to b, ignoring daylight saving
+// changes.
+func daysBetween(a, b time.Time) int {
+	dateA := time.Date(a.Year(), a.Month(), a.Day(), 0, 0, 0, 0, time.UTC)
+	dateB := time.Date(b.Year(), b.Month(), b.Day(), 0, 0, 0, 0, time.UTC)
+	return int(dateB.Sub(dateA) / (24 * time.Hour))
+}
+
+func (s *Store) UpdateTaskDue(id int, due time.Time) (Task, error) {
+	return s.modifyTask(id, func(task *Task) {
+		task.Due = due
+		setUpdatedDate(task)
+	})
+}
@@ -0,0 +1,112 @@
+package tasks
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// dueNow is Wednesday 2026-05-20 in the afternoon.
+var dueNow = time.Date(2026, 5, 20, 15, 30, 0, 0, time.FixedZone("ART", -3*3600))
+
+func dueDate(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, dueNow.Location())
+}
+
+func TestParseDueDate(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Time
+	}{
+		{"today", dueDate(2026, 5, 20)},
+		{"Tomorrow", dueDate(2026, 5, 21)},
+		{"yesterday", dueDate(2026, 5, 19)},
+		{"friday", dueDate(2026, 5, 22)},
+		{"fri", dueDate(2026, 5, 22)},
+		{"wednesday", dueDate(2026, 5, 20)},
+		{"monday", dueDate(2026, 5, 25)},
+		{"next friday", dueDate(2026, 5, 29)},
+		{"next week", dueDate(2026, 5, 25)},
+		{"next month", dueDate(2026, 6, 1)},
+		{"2026-07-04", dueDate(2026, 7, 4)},
+		{"in 3 days", dueDate(2026, 5, 23)},
+		{"in  1 day", dueDate(2026, 5, 21)},
+		{"2w", dueDate(2026, 6, 3)},
+		{"+1 month", dueDate(2026, 6, 20)},
+		{"in 1 year", dueDate(2027, 5, 20)},
+		{"none", time.Time{}},
+	}
+	for _, test := range tests {
+		got, err := ParseDueDate(test.value, dueNow)
+		if err != nil {
+			t.Errorf("ParseDueDate(%q): %s", test.value, err)
+			continue
+		}
+		if !got.Equal(test.want) {
+			t.Errorf("ParseDueDate(%q): expected %s, received %s", test.value, test.want, got)
+		}
+	}
+}
+
+func TestParseDueDateErrors(t *testing.T) {
+	expectedErrType := &ErrInvalidDueDate{}
+	for _, value := range []string{"", "someday", "next", "in -3 days", "2026-13-01", "3 fortnights"} {
+		if _, err := ParseDueDate(value, dueNow); !errors.As(err, &expectedErrType) {
+			t.Errorf("ParseDueDate(%q): expected ErrInvalidDueDate, received %v", value, err)
+		}
+	}
+}
+
+func TestDueLabel(t *testing.T) {
+	tests := []struct {
+		due  time.Time
+		want string
+	}{
+		{dueDate(2026, 5, 20), "today"},
+		{dueDate(2026, 5, 21), "tomorrow"},
+		{dueDate(2026, 5, 29), "Fri 2026-05-29"},
+		{dueDate(2026, 5, 18), "Mon 2026-05-18 (overdue)"},
+		{time.Time{}, "none"},
+	}
+	for _, test := range tests {
+		if got := DueLabel(test.due, dueNow); got != test.want {
+			t.Errorf("DueLabel(%s): expected %q, received %q", test.due, test.want, got)
+		}
+	}
+}
+
+func TestDueViews(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, store *Store) {
+		store.Add(Task{Description: "late", Due: dueDate(2026, 5, 18)})
+		store.Add(Task{Description: "now", Due: dueDate(2026, 5, 20)})
+		store.Add(Task{Description: "sunday", Due: dueDate(2026, 5, 24)})
+		store.Add(Task{Description: "next week", Due: dueDate(2026, 5, 25)})
+		store.AddTask("whenever")
+		done, _ := store.Add(Task{Description: "late but done", Due: dueDate(2026, 5, 1)})
+		store.UpdateTaskStatus(done.ID, Done)
+
+		expected := map[string]string{
+			ViewOverdue:     "1",
+			ViewDueToday:    "2",
+			ViewDueThisWeek: "2 3",
+		}
+		for view, want := range expected {
+			query, ok := ViewQuery(view, dueNow)
+			if !ok {
+				t.Fatalf("%s should be a due view", view)
+			}
+			tasks, err := store.Query(query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ids := taskIDs(tasks); ids != want {
+				t.Errorf("%s: expected %s, received %s", view, want, ids)
+			}
+		}
+
+		sorted, _ := store.Query(Query{Status: AllTasks, SortBy: SortByDue})
+		if ids := taskIDs(sorted); ids != "6 1 2 3 4 5" {
+			t.Errorf("sort by due: expected 6 1 2 3 4 5, received %s", ids)
+		}
+	})
+}
@@ -0,0 +1,193 @@
+package tasks
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"text/template"
+	"time"
+)
+
+const (
+	FormatTable     = "table"
+	FormatJSON      = "json"
+	FormatJSONLines = "jsonl"
+	FormatCSV       = "csv"
+	FormatMarkdown  = "markdown"
+)
+
+var Formats = []string{FormatTable, FormatJSON, FormatJSONLines, FormatCSV, FormatMarkdown}
+
+type ErrInvalidFormat struct {
+	Format string
+	Err    error
+}
+
+func (e *ErrInvalidFormat) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("invalid output template %q: %s", e.Format, e.Err)
+	}
+	return fmt.Sprintf("invalid output format %q. Expected %s or a template like '{{.ID}} {{.Description}}'", e.Format, strings.Join(Formats, ", "))
+}
+
+func (e *ErrInvalidFormat) Unwrap() error {
+	return e.Err
+}
+
+// Formatter writes a list of tasks.
+type Formatter func(w io.Writer, tasks []Task) error
+
+// NewFormatter returns the formatter for one of Formats, or for a
+// text/template run once per task when format contains "{{". An empty format
+// is a table.
+func NewFormatter(format string) (Formatter, error) {
+	if strings.Contains(format, "{{") {
+		tmpl, err := template.New("format").Parse(format)
+		if err != nil {
+			return nil, &ErrInvalidFormat{format, err}
+		}
+		return templateFormatter(tmpl), nil
+	}
+	switch format {
+	case FormatTable, "":
+		return writeTable, nil
+	case FormatJSON:
+		return writeJSON, nil
+	case FormatJSONLines:
+		return writeJSONLines, nil
+	case FormatCSV:
+		return writeCSV, nil
+	case FormatMarkdown:
+		return writeMarkdown, nil
+	}
+	return nil, &ErrInvalidFormat{Format: format}
+}
+
+// taskColumns are the columns of the table, CSV and Markdown formats.
+var taskColumns = []string{"ID", "Status", "Priority", "Due", "Project", "Tags", "Description"}
+
+// taskRow returns the columns of a task as text.
+func taskRow(task Task, description string) []string {
+	due := ""
+	if !task.Due.IsZero() {
+		due = task.Due.Format(time.DateOnly)
+	}
+	priority := ""
+	if task.Priority != PriorityNone {
+		priority = task.Priority.String()
+	}
+	tags := ""
+	if len(task.Tags) > 0 {
+		tags = "+" + strings.Join(task.Tags, " +")
+	}
+	return []string{strconv.Itoa(task.ID), task.Status.Name(), priority, due, task.Project, tags, description}
+}
+
+// tableColumns are the columns of the table: taskColumns with how long ago
+// the task was updated before the description.
+var tableColumns = slices.Insert(slices.Clone(taskColumns), len(taskColumns)-1, "Updated")
+
+// writeTable writes an aligned table with subtasks indented below their
+// parent.
+func writeTable(w io.Writer, tasks []Task) error {
+	now := time.Now()
+	table := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(table, strings.ToUpper(strings.Join(tableColumns, "\t")))
+	for _, entry := range Tree(tasks) {
+		description := strings.Repeat("  ", entry.Depth) + entry.Task.Description
+		row := taskRow(entry.Task, description)
+		row = slices.Insert(row, len(row)-1, RelativeTime(entry.Task.UpdatedAt, now))
+		fmt.Fprintln(table, strings.Join(row, "\t"))
+	}
+	return table.Flush()
+}
+
+func writeJSON(w io.Writer, tasks []Task) error {
+	if tasks == nil {
+		tasks = []Task{}
+	}
+	data, err := json.MarshalIndent(tasks, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, string(data))
+	return err
+}
+
+func writeJSONLines(w io.Writer, tasks []Task) error {
+	encoder := json.NewEncoder(w)
+	for _, task := range tasks {
+		if err := encoder.Encode(task); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeCSV writes one row per task with a header. Parent and dates are added
+// to the table columns so rows can be imported elsewhere.
+func writeCSV(w io.Writer, tasks []Task) error {
+	writer := csv.NewWriter(w)
+	header := slices.Concat(taskColumns, []string{"Parent", "Created", "Updated"})
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+	for _, task := range tasks {
+		updated := ""
+		if !task.UpdatedAt.IsZero() {
+			updated = task.UpdatedAt.Format(time.RFC3339)
+		}
+		parent := ""
+		if task.Parent != 0 {
+			parent = strconv.Itoa(task.Parent)
+		}
+		row := append(taskRow(task, task.Description), parent, task.CreatedAt.Format(time.RFC3339), updated)
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+var markdownEscaper = strings.NewReplacer("|", `\|`, "\n", " ")
+
+func writeMarkdown(w io.Writer, tasks []Task) error {
+	separators := make([]string, len(taskColumns))
+	for i := range separators {
+		separators[i] = "---"
+	}
+	lines := []string{
+		"| " + strings.Join(taskColumns, " | ") + " |",
+		"| " + strings.Join(separators, " | ") + " |",
+	}
+	for _, task := range tasks {
+		row := taskRow(task, task.Description)
+		for i, cell := range row {
+			row[i] = markdownEscaper.Replace(cell)
+		}
+		lines = append(lines, "| "+strings.Join(row, " | ")+" |")
+	}
+	_, err := fmt.Fprintln(w, strings.Join(lines, "\n"))
+	return err
+}
+
+// templateFormatter runs tmpl on each task, one per line.
+func templateFormatter(tmpl *template.Template) Formatter {
+	return func(w io.Writer, tasks []Task) error {
+		for _, task := range tasks {
+			if err := tmpl.Execute(w, task); err != nil {
+				return err
+			}
+			if _, err := fmt.Fprintln(w); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
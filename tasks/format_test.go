@@ -0,0 +1,99 @@
+package tasks
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func formatFixture() []Task {
+	created := time.Date(2026, 5, 20, 9, 0, 0, 0, time.UTC)
+	return []Task{
+		{ID: 1, Description: "release", Status: InProgress, Priority: PriorityHigh, Project: "web", CreatedAt: created},
+		{ID: 2, Description: "write | notes", Parent: 1, Tags: []string{"docs"}, Due: dueDate(2026, 5, 22), CreatedAt: created},
+	}
+}
+
+func format(t *testing.T, format string, tasks []Task) string {
+	t.Helper()
+	formatter, err := NewFormatter(format)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	if err := formatter(&out, tasks); err != nil {
+		t.Fatal(err)
+	}
+	return out.String()
+}
+
+func TestFormatTable(t *testing.T) {
+	lines := strings.Split(strings.TrimSpace(format(t, "", formatFixture())), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[0], "ID  STATUS") {
+		t.Fatalf("expected a header and 2 rows, received\n%s", strings.Join(lines, "\n"))
+	}
+	if !strings.HasSuffix(lines[2], "  write | notes") || !strings.Contains(lines[2], "2026-05-22") {
+		t.Errorf("expected the subtask indented with its due date, received %q", lines[2])
+	}
+	if !strings.Contains(lines[0], "UPDATED  DESCRIPTION") || !strings.Contains(lines[2], "never") {
+		t.Errorf("expected when the task was updated, received\n%s", strings.Join(lines, "\n"))
+	}
+
+	updated := formatFixture()[:1]
+	updated[0].UpdatedAt = time.Now().Add(-2 * time.Hour)
+	if out := format(t, "", updated); !strings.Contains(out, "2h ago") {
+		t.Errorf("expected the update time relative to now, received\n%s", out)
+	}
+}
+
+func TestFormatJSON(t *testing.T) {
+	var decoded []Task
+	if err := json.Unmarshal([]byte(format(t, FormatJSON, formatFixture())), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded) != 2 || decoded[1].Tags[0] != "docs" {
+		t.Errorf("expected the tasks back, received %v", decoded)
+	}
+	if out := format(t, FormatJSON, nil); strings.TrimSpace(out) != "[]" {
+		t.Errorf("expected an empty array, received %q", out)
+	}
+	if lines := strings.Split(strings.TrimSpace(format(t, FormatJSONLines, formatFixture())), "\n"); len(lines) != 2 {
+		t.Errorf("expected one line per task, received %v", lines)
+	}
+}
+
+func TestFormatCSV(t *testing.T) {
+	expected := "ID,Status,Priority,Due,Project,Tags,Description,Parent,Created,Updated\n" +
+		"1,IN PROGRESS,high,,web,,release,,2026-05-20T09:00:00Z,\n" +
+		"2,TO DO,,2026-05-22,,+docs,write | notes,1,2026-05-20T09:00:00Z,\n"
+	if out := format(t, FormatCSV, formatFixture()); out != expected {
+		t.Errorf("expected\n%s\nreceived\n%s", expected, out)
+	}
+}
+
+func TestFormatMarkdown(t *testing.T) {
+	out := format(t, FormatMarkdown, formatFixture())
+	if !strings.Contains(out, "| --- |") || !strings.Contains(out, `| write \| notes |`) {
+		t.Errorf("expected a Markdown table with escaped pipes, received\n%s", out)
+	}
+}
+
+func TestFormatTemplate(t *testing.T) {
+	out := format(t, "{{.ID}}: {{.Description}} ({{.Status.Name}})", formatFixture())
+	if out != "1: release (IN PROGRESS)\n2: write | notes (TO DO)\n" {
+		t.Errorf("unexpected template output %q", out)
+	}
+}
+
+func TestInvalidFormat(t *testing.T) {
+	var invalid *ErrInvalidFormat
+	if _, err := NewFormatter("yaml"); !errors.As(err, &invalid) {
+		t.Errorf("expected ErrInvalidFormat, received %v", err)
+	}
+	if _, err := NewFormatter("{{.ID"); !errors.As(err, &invalid) || invalid.Err == nil {
+		t.Errorf("expected ErrInvalidFormat with the template error, received %v", err)
+	}
+}
@@ -0,0 +1,246 @@
+package tasks
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+type ErrOpenChildren struct {
+	ID       int
+	Children []int
+}
+
+func (e *ErrOpenChildren) Error() string {
+	return fmt.Sprintf("task %d still has open subtasks %s. Finish them first or force it", e.ID, joinIDs(e.Children))
+}
+
+type ErrHasChildren struct {
+	ID       int
+	Children []int
+}
+
+func (e *ErrHasChildren) Error() string {
+	return fmt.Sprintf("task %d has subtasks %s. Delete them too or orphan them", e.ID, joinIDs(e.Children))
+}
+
+type ErrHierarchyCycle struct {
+	ID     int
+	Parent int
+}
+
+func (e *ErrHierarchyCycle) Error() string {
+	if e.ID == e.Parent {
+		return fmt.Sprintf("task %d cannot be its own parent", e.ID)
+	}
+	return fmt.Sprintf("task %d cannot be a subtask of %d because %d is already below it", e.ID, e.Parent, e.Parent)
+}
+
+func joinIDs(ids []int) string {
+	names := make([]string, len(ids))
+	for i, id := range ids {
+		names[i] = strconv.Itoa(id)
+	}
+	return strings.Join(names, ", ")
+}
+
+// DeleteMode says what happens to the subtasks of a deleted task.
+type DeleteMode int
+
+const (
+	// DeleteRefuse fails with ErrHasChildren if the task has subtasks.
+	DeleteRefuse DeleteMode = iota
+	// DeleteCascade deletes every task below the task too.
+	DeleteCascade
+	// DeleteOrphan turns the direct subtasks into top level tasks.
+	DeleteOrphan
+)
+
+// children returns the direct subtasks of id.
+func children(repo Repository, id int) ([]Task, error) {
+	tasks, err := repo.List()
+	if err != nil {
+		return nil, err
+	}
+	return slices.DeleteFunc(tasks, func(task Task) bool {
+		return task.Parent != id
+	}), nil
+}
+
+// descendants returns the IDs of every task below id, closest first.
+func descendants(repo Repository, id int) ([]int, error) {
+	tasks, err := repo.List()
+	if err != nil {
+		return nil, err
+	}
+	found := []int{id}
+	for i := 0; i < len(found); i++ {
+		for _, task := range tasks {
+			if task.Parent == found[i] && !slices.Contains(found, task.ID) {
+				found = append(found, task.ID)
+			}
+		}
+	}
+	return found[1:], nil
+}
+
+// checkParent makes sure parent exists and is not id or one of its subtasks.
+func checkParent(repo Repository, id, parent int) error {
+	if parent == 0 {
+		return nil
+	}
+	if parent == id {
+		return &ErrHierarchyCycle{id, parent}
+	}
+	if _, err := repo.Get(parent); err != nil {
+		return err
+	}
+	below, err := descendants(repo, id)
+	if err != nil {
+		return err
+	}
+	if slices.Contains(below, parent) {
+		return &ErrHierarchyCycle{id, parent}
+	}
+	return nil
+}
+
+// checkCanFinish refuses to mark task done while it has open subtasks.
+func checkCanFinish(repo Repository, task Task) error {
+	subtasks, err := children(repo, task.ID)
+	if err != nil {
+		return err
+	}
+	open := []int{}
+	for _, subtask := range subtasks {
+		if !subtask.Status.Closed() {
+			open = append(open, subtask.ID)
+		}
+	}
+	if len(open) > 0 {
+		return &ErrOpenChildren{task.ID, open}
+	}
+	return nil
+}
+
+// SetParent makes id a subtask of parent. A parent of 0 makes it a top level
+// task.
+func (s *Store) SetParent(id, parent int) (Task, error) {
+	var modified Task
+	err := s.repo.Atomic(func(repo Repository) error {
+		task, getErr := repo.Get(id)
+		if getErr != nil {
+			return getErr
+		}
+		if parentErr := checkParent(repo, id, parent); parentErr != nil {
+			return parentErr
+		}
+		task.Parent = parent
+		setUpdatedDate(&task)
+		modified = task
+		return repo.Update(task)
+	})
+	if err != nil {
+		return ZeroTask, err
+	}
+	return modified, nil
+}
+
+// Children returns the direct subtasks of id.
+func (s *Store) Children(id int) ([]Task, error) {
+	return children(s.repo, id)
+}
+
+// DeleteTaskMode deletes a task, handling its subtasks as mode says, and
+// returns the IDs of every deleted task.
+func (s *Store) DeleteTaskMode(id int, mode DeleteMode) ([]int, error) {
+	var deleted []int
+	err := s.repo.Atomic(func(repo Repository) error {
+		if _, getErr := repo.Get(id); getErr != nil {
+			return getErr
+		}
+		subtasks, childrenErr := children(repo, id)
+		if childrenErr != nil {
+			return childrenErr
+		}
+		deleted = []int{id}
+
+		switch {
+		case len(subtasks) == 0:
+		case mode == DeleteCascade:
+			below, belowErr := descendants(repo, id)
+			if belowErr != nil {
+				return belowErr
+			}
+			deleted = append(deleted, below...)
+		case mode == DeleteOrphan:
+			for _, subtask := range subtasks {
+				subtask.Parent = 0
+				setUpdatedDate(&subtask)
+				if updateErr := repo.Update(subtask); updateErr != nil {
+					return updateErr
+				}
+			}
+		default:
+			ids := make([]int, len(subtasks))
+			for i, subtask := range subtasks {
+				ids[i] = subtask.ID
+			}
+			return &ErrHasChildren{id, ids}
+		}
+
+		for _, deletedID := range deleted {
+			if deleteErr := repo.Delete(deletedID); deleteErr != nil {
+				return deleteErr
+			}
+		}
+		return removeBlocker(repo, deleted)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return deleted, nil
+}
+
+// TreeEntry is a task with its depth in the hierarchy.
+type TreeEntry struct {
+	Task  Task
+	Depth int
+}
+
+// Tree orders tasks so every task follows its parent, keeping the order of
+// tasks otherwise. Tasks whose parent is not in tasks are shown at the top
+// level.
+func Tree(tasks []Task) []TreeEntry {
+	present := map[int]bool{}
+	for _, task := range tasks {
+		present[task.ID] = true
+	}
+
+	entries := make([]TreeEntry, 0, len(tasks))
+	visited := map[int]bool{}
+	var visit func(task Task, depth int)
+	visit = func(task Task, depth int) {
+		if visited[task.ID] {
+			return
+		}
+		visited[task.ID] = true
+		entries = append(entries, TreeEntry{task, depth})
+		for _, child := range tasks {
+			if child.Parent == task.ID {
+				visit(child, depth+1)
+			}
+		}
+	}
+	for _, task := range tasks {
+		if task.Parent == 0 || !present[task.Parent] {
+			visit(task, 0)
+		}
+	}
+	// Tasks caught in a cycle have no root to start from.
+	for _, task := range tasks {
+		visit(task, 0)
+	}
+	return entries
+}
@@ -0,0 +1,137 @@
+package tasks
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+// addTree adds 1 with subtasks 2 and 3, 3 with subtask 4, and a separate 5.
+func addTree(t *testing.T, store *Store) {
+	t.Helper()
+	for _, task := range []Task{
+		{Description: "release"},
+		{Description: "write notes", Parent: 1},
+		{Description: "build", Parent: 1},
+		{Description: "sign binaries", Parent: 3},
+		{Description: "unrelated"},
+	} {
+		if _, err := store.Add(task); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestAddSubtaskNeedsParent(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, store *Store) {
+		expectedErrType := &ErrTaskNotFound{}
+		if _, err := store.Add(Task{Description: "orphan", Parent: 9}); !errors.As(err, &expectedErrType) {
+			t.Errorf("expected ErrTaskNotFound, received %v", err)
+		}
+	})
+}
+
+func TestTree(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, store *Store) {
+		addTree(t, store)
+		tasks, _ := store.Query(Query{Status: AllTasks})
+		tree := Tree(tasks)
+
+		var ids []int
+		var depths []int
+		for _, entry := range tree {
+			ids = append(ids, entry.Task.ID)
+			depths = append(depths, entry.Depth)
+		}
+		if !slices.Equal(ids, []int{1, 2, 3, 4, 5}) || !slices.Equal(depths, []int{0, 1, 1, 2, 0}) {
+			t.Errorf("unexpected tree: ids %v depths %v", ids, depths)
+		}
+
+		filtered := Tree(slices.DeleteFunc(tasks, func(task Task) bool { return task.ID == 3 }))
+		if filtered[2].Task.ID != 4 || filtered[2].Depth != 0 {
+			t.Errorf("subtasks of missing parents should be at the top, received %+v", filtered[2])
+		}
+	})
+}
+
+func TestMarkParentDone(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, store *Store) {
+		addTree(t, store)
+
+		expectedErrType := &ErrOpenChildren{}
+		_, err := store.UpdateTaskStatus(1, Done)
+		if !errors.As(err, &expectedErrType) || !slices.Equal(expectedErrType.Children, []int{2, 3}) {
+			t.Errorf("expected ErrOpenChildren for 2 and 3, received %v", err)
+		}
+
+		store.UpdateTaskStatus(2, Done)
+		store.UpdateTaskStatus(4, Done)
+		if _, err := store.UpdateTaskStatus(3, Done); err != nil {
+			t.Errorf("a task with finished subtasks can be done: %s", err)
+		}
+		if _, err := store.UpdateTaskStatus(1, Done); err != nil {
+			t.Errorf("a task with finished subtasks can be done: %s", err)
+		}
+
+		store.AddTask("another")
+		store.Add(Task{Description: "open subtask", Parent: 6})
+		if task, err := store.ForceTaskStatus(6, Done); err != nil || task.Status != Done {
+			t.Errorf("forcing should skip the check, received %v", err)
+		}
+	})
+}
+
+func TestDeleteParent(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, store *Store) {
+		addTree(t, store)
+
+		expectedErrType := &ErrHasChildren{}
+		if err := store.DeleteTask(1); !errors.As(err, &expectedErrType) {
+			t.Errorf("expected ErrHasChildren, received %v", err)
+		}
+
+		deleted, err := store.DeleteTaskMode(3, DeleteCascade)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(deleted, []int{3, 4}) {
+			t.Errorf("expected 3 and 4 deleted, received %v", deleted)
+		}
+
+		if _, err := store.DeleteTaskMode(1, DeleteOrphan); err != nil {
+			t.Fatal(err)
+		}
+		orphan, _ := store.GetTask(2)
+		if orphan.Parent != 0 {
+			t.Errorf("subtasks should be orphaned, received parent %d", orphan.Parent)
+		}
+		if remaining, _ := store.Query(Query{Status: AllTasks}); taskIDs(remaining) != "2 5" {
+			t.Errorf("expected 2 and 5 left, received %s", taskIDs(remaining))
+		}
+	})
+}
+
+func TestSetParentRejectsCycles(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, store *Store) {
+		addTree(t, store)
+
+		expectedErrType := &ErrHierarchyCycle{}
+		for parent, message := range map[int]string{
+			1: "task 1 cannot be its own parent",
+			4: "task 1 cannot be a subtask of 4 because 4 is already below it",
+		} {
+			_, err := store.SetParent(1, parent)
+			if !errors.As(err, &expectedErrType) || err.Error() != message {
+				t.Errorf("SetParent(1, %d): expected ErrHierarchyCycle %q, received %v", parent, message, err)
+			}
+		}
+
+		moved, err := store.SetParent(4, 5)
+		if err != nil || moved.Parent != 5 {
+			t.Errorf("expected 4 under 5, received %+v (%v)", moved, err)
+		}
+		if moved, err := store.SetParent(4, 0); err != nil || moved.Parent != 0 {
+			t.Errorf("expected 4 at the top level, received %+v (%v)", moved, err)
+		}
+	})
+}
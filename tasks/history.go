@@ -0,0 +1,273 @@
+package tasks
+
+import (
+	"backend/jsondatabase"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	ActionCreate  = "create"
+	ActionUpdate  = "update"
+	ActionDelete  = "delete"
+	ActionRestore = "restore"
+	ActionArchive = "archive"
+)
+
+// Event is one entry of the history of a task. Updates record one event per
+// changed field with the old and new values as JSON.
+type Event struct {
+	ID     int             `json:"id"`
+	TaskID int             `json:"task"`
+	Time   time.Time       `json:"time"`
+	User   string          `json:"user"`
+	Action string          `json:"action"`
+	Field  string          `json:"field,omitempty"`
+	Old    json.RawMessage `json:"old,omitempty"`
+	New    json.RawMessage `json:"new,omitempty"`
+}
+
+func (e Event) GetID() int {
+	return e.ID
+}
+
+func (e Event) SetID(id int) jsondatabase.Storable {
+	e.ID = id
+	return e
+}
+
+func (e Event) String() string {
+	prefix := fmt.Sprintf("%s %s task %d", LocalTime(e.Time), e.User, e.TaskID)
+	switch e.Action {
+	case ActionCreate:
+		return fmt.Sprintf("%s created: %s", prefix, formatValue(e.Field, e.New))
+	case ActionDelete:
+		return fmt.Sprintf("%s deleted: %s", prefix, formatValue(e.Field, e.Old))
+	case ActionRestore:
+		return fmt.Sprintf("%s restored: %s", prefix, formatValue(e.Field, e.New))
+	case ActionArchive:
+		return fmt.Sprintf("%s archived: %s", prefix, formatValue(e.Field, e.Old))
+	}
+	return fmt.Sprintf("%s %s: %s -> %s", prefix, e.Field, formatValue(e.Field, e.Old), formatValue(e.Field, e.New))
+}
+
+// formatValue shows a recorded value of field, with statuses and priorities
+// by name.
+func formatValue(field string, value json.RawMessage) string {
+	if len(value) == 0 || string(value) == "null" {
+		return "none"
+	}
+	var status TaskStatus
+	if field == "Status" && json.Unmarshal(value, &status) == nil {
+		return status.Name()
+	}
+	var priority Priority
+	if field == "Priority" && json.Unmarshal(value, &priority) == nil {
+		return priority.String()
+	}
+	var text string
+	if json.Unmarshal(value, &text) == nil {
+		return strconv.Quote(text)
+	}
+	return string(value)
+}
+
+// History stores the events of a Store.
+type History interface {
+	Append(events []Event) error
+	Events() ([]Event, error)
+}
+
+var ErrNoHistory = errors.New("this store does not record history")
+
+// FileHistory keeps events in an append-only JSON Lines file.
+type FileHistory struct {
+	log *jsondatabase.Log[Event]
+}
+
+func NewFileHistory(path string) *FileHistory {
+	return &FileHistory{jsondatabase.NewLog[Event](path)}
+}
+
+func (h *FileHistory) Append(events []Event) error {
+	return h.log.Update(func(tx *jsondatabase.Tx[Event]) error {
+		for _, event := range events {
+			if _, err := tx.Insert(event); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (h *FileHistory) Events() ([]Event, error) {
+	var events []Event
+	err := h.log.View(func(tx *jsondatabase.Tx[Event]) error {
+		events = tx.All()
+		return nil
+	})
+	return events, err
+}
+
+// MemoryHistory keeps events in memory, for the memory backend and tests.
+type MemoryHistory struct {
+	mu     sync.Mutex
+	events []Event
+}
+
+func NewMemoryHistory() *MemoryHistory {
+	return &MemoryHistory{}
+}
+
+func (h *MemoryHistory) Append(events []Event) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, event := range events {
+		event.ID = len(h.events) + 1
+		h.events = append(h.events, event)
+	}
+	return nil
+}
+
+func (h *MemoryHistory) Events() ([]Event, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return slices.Clone(h.events), nil
+}
+
+// WithHistory records every change the store makes in history, signed by
+// user.
+func WithHistory(history History, user string) StoreOption {
+	return func(s *Store) {
+		s.history = history
+		s.observers = append(s.observers, func(changes []TaskChange) error {
+			return recordHistory(history, user, changes)
+		})
+	}
+}
+
+func recordHistory(history History, user string, changes []TaskChange) error {
+	events := []Event{}
+	for _, change := range changes {
+		switch {
+		case change.Before == nil:
+			description, _ := json.Marshal(change.After.Description)
+			events = append(events, Event{TaskID: change.After.ID, Action: ActionCreate, Field: "Description", New: description})
+		case change.Archived:
+			description, _ := json.Marshal(change.Before.Description)
+			events = append(events, Event{TaskID: change.Before.ID, Action: ActionArchive, Field: "Description", Old: description})
+		case change.After == nil || (change.After.Trashed() && !change.Before.Trashed()):
+			description, _ := json.Marshal(change.Before.Description)
+			events = append(events, Event{TaskID: change.Before.ID, Action: ActionDelete, Field: "Description", Old: description})
+		case change.Before.Trashed() && !change.After.Trashed():
+			description, _ := json.Marshal(change.After.Description)
+			events = append(events, Event{TaskID: change.After.ID, Action: ActionRestore, Field: "Description", New: description})
+			// What else changed on the way out of the trash, like a parent
+			// that is gone.
+			restored := *change.Before
+			restored.DeletedAt = time.Time{}
+			updates, diffErr := diffTasks(restored, *change.After)
+			if diffErr != nil {
+				return diffErr
+			}
+			events = append(events, updates...)
+		default:
+			updates, diffErr := diffTasks(*change.Before, *change.After)
+			if diffErr != nil {
+				return diffErr
+			}
+			events = append(events, updates...)
+		}
+	}
+	if len(events) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	for i := range events {
+		events[i].Time = now
+		events[i].User = user
+	}
+	if appendErr := history.Append(events); appendErr != nil {
+		return fmt.Errorf("the change was saved but its history was not: %w", appendErr)
+	}
+	return nil
+}
+
+// untrackedFields change on every update and are left out of the history.
+var untrackedFields = []string{"ID", "CreatedAt", "UpdatedAt"}
+
+// taskFields returns the fields of task as they are stored.
+func taskFields(task Task) (map[string]json.RawMessage, error) {
+	data, err := json.Marshal(task)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]json.RawMessage
+	err = json.Unmarshal(data, &fields)
+	return fields, err
+}
+
+// diffTasks returns an update event for each field that differs.
+func diffTasks(before, after Task) ([]Event, error) {
+	oldFields, oldErr := taskFields(before)
+	if oldErr != nil {
+		return nil, oldErr
+	}
+	newFields, newErr := taskFields(after)
+	if newErr != nil {
+		return nil, newErr
+	}
+
+	names := slices.Collect(maps.Keys(oldFields))
+	for name := range newFields {
+		if _, ok := oldFields[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+
+	events := []Event{}
+	for _, name := range names {
+		if slices.Contains(untrackedFields, name) || string(oldFields[name]) == string(newFields[name]) {
+			continue
+		}
+		events = append(events, Event{TaskID: after.ID, Action: ActionUpdate, Field: name, Old: oldFields[name], New: newFields[name]})
+	}
+	return events, nil
+}
+
+// History returns the events of a task, oldest first.
+func (s *Store) History(id int) ([]Event, error) {
+	events, err := s.events()
+	if err != nil {
+		return nil, err
+	}
+	return slices.DeleteFunc(events, func(event Event) bool {
+		return event.TaskID != id
+	}), nil
+}
+
+// Activity returns the events of every task since a time, oldest first.
+func (s *Store) Activity(since time.Time) ([]Event, error) {
+	events, err := s.events()
+	if err != nil {
+		return nil, err
+	}
+	return slices.DeleteFunc(events, func(event Event) bool {
+		return event.Time.Before(since)
+	}), nil
+}
+
+func (s *Store) events() ([]Event, error) {
+	if s.history == nil {
+		return nil, ErrNoHistory
+	}
+	return s.history.Events()
+}
@@ -0,0 +1,115 @@
+package tasks
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// forEachHistory runs test with every backend recording into a history file,
+// and with a memory history.
+func forEachHistory(t *testing.T, test func(t *testing.T, store *Store)) {
+	forEachBackend(t, func(t *testing.T, store *Store) {
+		history := NewFileHistory(filepath.Join(t.TempDir(), "history.jsonl"))
+		test(t, NewStore(store.repo, WithHistory(history, "ana")))
+	})
+	t.Run("memory history", func(t *testing.T) {
+		test(t, NewStore(NewMemoryRepository(), WithHistory(NewMemoryHistory(), "ana")))
+	})
+}
+
+func TestHistory(t *testing.T) {
+	forEachHistory(t, func(t *testing.T, store *Store) {
+		task, _ := store.AddTask("write docs")
+		store.UpdateTask(task.ID, "write the docs")
+		store.UpdateTaskStatus(task.ID, Done)
+		store.AddTask("other")
+		store.DeleteTask(task.ID)
+
+		events, err := store.History(task.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var lines []string
+		for _, event := range events {
+			if event.User != "ana" || event.Time.IsZero() {
+				t.Errorf("events should be signed and dated, received %+v", event)
+			}
+			_, line, _ := strings.Cut(event.String(), "ana ")
+			lines = append(lines, line)
+		}
+		expected := []string{
+			`task 1 created: "write docs"`,
+			`task 1 Description: "write docs" -> "write the docs"`,
+			`task 1 Status: TO DO -> DONE`,
+			`task 1 deleted: "write the docs"`,
+		}
+		if strings.Join(lines, "\n") != strings.Join(expected, "\n") {
+			t.Errorf("expected\n%s\nreceived\n%s", strings.Join(expected, "\n"), strings.Join(lines, "\n"))
+		}
+	})
+}
+
+func TestHistoryOfTrash(t *testing.T) {
+	forEachHistory(t, func(t *testing.T, store *Store) {
+		task, _ := store.AddTask("draft")
+		store.DeleteTask(task.ID)
+		store.RestoreTask(task.ID)
+		store.DeleteTask(task.ID)
+		store.Purge(time.Now().Add(time.Second))
+
+		events, err := store.History(task.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var actions []string
+		for _, event := range events {
+			actions = append(actions, event.Action)
+		}
+		expected := []string{ActionCreate, ActionDelete, ActionRestore, ActionDelete, ActionDelete}
+		if strings.Join(actions, " ") != strings.Join(expected, " ") {
+			t.Errorf("expected %v, received %v", expected, actions)
+		}
+	})
+}
+
+func TestHistoryKeepsFailedChangesOut(t *testing.T) {
+	forEachHistory(t, func(t *testing.T, store *Store) {
+		store.AddTask("parent")
+		store.Add(Task{Description: "child", Parent: 1})
+		if err := store.DeleteTask(1); err == nil {
+			t.Fatal("deleting a parent should fail")
+		}
+		store.UpdateTaskStatus(1, InProgress)
+
+		events, _ := store.Activity(time.Time{})
+		if len(events) != 3 {
+			t.Errorf("expected 2 creates and 1 status change, received %v", events)
+		}
+		if recent, _ := store.Activity(time.Now().Add(time.Minute)); len(recent) != 0 {
+			t.Errorf("expected no events in the future, received %v", recent)
+		}
+	})
+}
+
+func TestRecurringAddIsOneEvent(t *testing.T) {
+	forEachHistory(t, func(t *testing.T, store *Store) {
+		daily, _ := ParseRecurrence("daily")
+		task, err := store.Add(Task{Description: "standup", Recur: &daily})
+		if err != nil || task.Series != task.ID {
+			t.Fatalf("expected a task starting its series, received %+v (%v)", task, err)
+		}
+		if events, _ := store.History(task.ID); len(events) != 1 {
+			t.Errorf("expected only the create, received %v", events)
+		}
+	})
+}
+
+func TestStoreWithoutHistory(t *testing.T) {
+	store := NewStore(NewMemoryRepository())
+	if _, err := store.History(1); !errors.Is(err, ErrNoHistory) {
+		t.Errorf("expected ErrNoHistory, received %v", err)
+	}
+}
@@ -0,0 +1,143 @@
+package tasks
+
+import (
+	"slices"
+	"sync"
+)
+
+// MemoryRepository keeps tasks in memory. It is meant for tests and for
+// sessions that write their changes elsewhere when they end.
+type MemoryRepository struct {
+	mu    sync.Mutex
+	state memoryState
+}
+
+type memoryState struct {
+	tasks  []Task
+	nextID int
+}
+
+// NewMemoryRepository returns a repository holding a copy of tasks.
+func NewMemoryRepository(tasks ...Task) *MemoryRepository {
+	return &MemoryRepository{state: memoryState{tasks: slices.Clone(tasks)}}
+}
+
+func (r *MemoryRepository) Atomic(fn func(repo Repository) error) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	saved := memoryState{tasks: slices.Clone(r.state.tasks), nextID: r.state.nextID}
+	if err := fn(&r.state); err != nil {
+		r.state = saved
+		return err
+	}
+	return nil
+}
+
+func (r *MemoryRepository) Get(id int) (Task, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.state.Get(id)
+}
+
+func (r *MemoryRepository) List() ([]Task, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.state.List()
+}
+
+func (r *MemoryRepository) Insert(task Task) (Task, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.state.Insert(task)
+}
+
+func (r *MemoryRepository) Update(task Task) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.state.Update(task)
+}
+
+func (r *MemoryRepository) Restore(task Task) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.state.Restore(task)
+}
+
+func (r *MemoryRepository) Delete(id int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.state.Delete(id)
+}
+
+func (r *MemoryRepository) NextID() (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.state.NextID()
+}
+
+func (s *memoryState) index(id int) int {
+	return slices.IndexFunc(s.tasks, func(task Task) bool {
+		return task.ID == id
+	})
+}
+
+func (s *memoryState) Atomic(fn func(repo Repository) error) error {
+	return fn(s)
+}
+
+func (s *memoryState) Get(id int) (Task, error) {
+	index := s.index(id)
+	if index == -1 {
+		return ZeroTask, &ErrTaskNotFound{id}
+	}
+	return s.tasks[index], nil
+}
+
+func (s *memoryState) List() ([]Task, error) {
+	return slices.Clone(s.tasks), nil
+}
+
+func (s *memoryState) Insert(task Task) (Task, error) {
+	task.ID, _ = s.NextID()
+	s.nextID = task.ID + 1
+	s.tasks = append(s.tasks, task)
+	return task, nil
+}
+
+func (s *memoryState) Update(task Task) error {
+	index := s.index(task.ID)
+	if index == -1 {
+		return &ErrTaskNotFound{task.ID}
+	}
+	s.tasks[index] = task
+	return nil
+}
+
+func (s *memoryState) Restore(task Task) error {
+	index := s.index(task.ID)
+	if index == -1 {
+		s.tasks = append(s.tasks, task)
+		return nil
+	}
+	s.tasks[index] = task
+	return nil
+}
+
+func (s *memoryState) Delete(id int) error {
+	index := s.index(id)
+	if index == -1 {
+		return &ErrTaskNotFound{id}
+	}
+	s.nextID, _ = s.NextID()
+	s.tasks = slices.Delete(s.tasks, index, index+1)
+	return nil
+}
+
+func (s *memoryState) NextID() (int, error) {
+	lastID := max(s.nextID-1, 0)
+	for _, task := range s.tasks {
+		lastID = max(lastID, task.ID)
+	}
+	return lastID + 1, nil
+}
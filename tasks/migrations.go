@@ -0,0 +1,97 @@
+package tasks
+
+import (
+	"backend/jsondatabase"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// migrations upgrade stored tasks to the current Task layout. Versions carry
+// on from the jsondatabase envelope, so the first one is version 2.
+var migrations = []jsondatabase.Migration{
+	{
+		Version:     2,
+		Description: "store CreatedAt and UpdatedAt as RFC 3339 timestamps",
+		Up:          migrateTimestamps,
+	},
+}
+
+var ErrMigrationUnsupported = errors.New("this storage backend has no schema to migrate")
+
+// Migrator is implemented by repositories whose files carry a schema version.
+type Migrator interface {
+	Migrate(dryRun bool) (*jsondatabase.MigrationReport, error)
+}
+
+func (r *fileRepository) Migrate(dryRun bool) (*jsondatabase.MigrationReport, error) {
+	return r.db.Migrate(dryRun)
+}
+
+// Migrate upgrades the store's file to the current schema. With dryRun it only
+// reports what would change.
+func (s *Store) Migrate(dryRun bool) (*jsondatabase.MigrationReport, error) {
+	return migrateRepository(s.repo, dryRun)
+}
+
+func migrateRepository(repo Repository, dryRun bool) (*jsondatabase.MigrationReport, error) {
+	migrator, ok := repo.(Migrator)
+	if !ok {
+		return nil, ErrMigrationUnsupported
+	}
+	return migrator.Migrate(dryRun)
+}
+
+// editFields applies edit to every item decoded as a field map.
+func editFields(items []json.RawMessage, edit func(fields map[string]json.RawMessage) error) ([]json.RawMessage, error) {
+	for i, item := range items {
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(item, &fields); err != nil {
+			return nil, err
+		}
+		if err := edit(fields); err != nil {
+			return nil, fmt.Errorf("task %s: %w", fields["ID"], err)
+		}
+		edited, err := json.Marshal(fields)
+		if err != nil {
+			return nil, err
+		}
+		items[i] = edited
+	}
+	return items, nil
+}
+
+// legacyTimeLayout is the format of time.Time.String, which older versions
+// stored, minus the monotonic clock reading.
+const legacyTimeLayout = "2006-01-02 15:04:05.999999999 -0700 MST"
+
+func migrateTimestamps(items []json.RawMessage) ([]json.RawMessage, error) {
+	return editFields(items, func(fields map[string]json.RawMessage) error {
+		for _, name := range []string{"CreatedAt", "UpdatedAt"} {
+			var value string
+			if raw, ok := fields[name]; !ok || json.Unmarshal(raw, &value) != nil {
+				continue
+			}
+			if value == "" {
+				delete(fields, name)
+				continue
+			}
+			parsed, err := parseLegacyTime(value)
+			if err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+			fields[name], _ = json.Marshal(parsed)
+		}
+		return nil
+	})
+}
+
+func parseLegacyTime(value string) (time.Time, error) {
+	if parsed, err := time.Parse(time.RFC3339Nano, value); err == nil {
+		return parsed, nil
+	}
+	value, _, _ = strings.Cut(value, " m=")
+	return time.Parse(legacyTimeLayout, value)
+}
@@ -0,0 +1,78 @@
+package tasks
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestMigrateLegacyTimestamps(t *testing.T) {
+	for _, backend := range []string{BackendJSON, BackendJSONLines} {
+		t.Run(backend, func(t *testing.T) {
+			created := time.Date(2025, 3, 14, 9, 26, 53, 589793000, time.FixedZone("CET", 3600))
+			legacyValue := created.String() + " m=+0.000150541"
+			var legacy string
+			if backend == BackendJSON {
+				legacy = `[{"ID":1,"Description":"old","Status":0,"CreatedAt":"` + legacyValue + `","UpdatedAt":""}]`
+			} else {
+				legacy = `{"op":"put","id":1,"item":{"ID":1,"Description":"old","Status":0,"CreatedAt":"` + legacyValue + `","UpdatedAt":""}}` + "\n"
+			}
+			path := filepath.Join(t.TempDir(), "legacy."+backend)
+			if err := os.WriteFile(path, []byte(legacy), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			repo, _ := NewRepository(backend, path)
+			store := NewStore(repo)
+			report, err := store.Migrate(true)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if report.ToVersion != 2 || report.Steps[len(report.Steps)-1].Changed != 1 {
+				t.Errorf("unexpected dry run report: %+v", report)
+			}
+
+			task, err := store.GetTask(1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !task.CreatedAt.Equal(created) {
+				t.Errorf("expected CreatedAt %s, received %s", created, task.CreatedAt)
+			}
+			if !task.UpdatedAt.IsZero() {
+				t.Errorf("empty UpdatedAt should become the zero time, received %s", task.UpdatedAt)
+			}
+
+			if _, err := store.UpdateTask(1, "migrated"); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := os.Stat(path + ".v" + map[string]string{BackendJSON: "0", BackendJSONLines: "1"}[backend] + ".bak"); err != nil {
+				t.Errorf("expected a backup of the legacy file: %s", err)
+			}
+		})
+	}
+}
+
+func TestParseLegacyTime(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    time.Time
+		wantErr bool
+	}{
+		{"2024-01-02 03:04:05.123456789 +0000 UTC m=+0.001", time.Date(2024, 1, 2, 3, 4, 5, 123456789, time.UTC), false},
+		{"2024-01-02 03:04:05 -0300 -03", time.Date(2024, 1, 2, 6, 4, 5, 0, time.UTC), false},
+		{"2024-01-02T03:04:05Z", time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), false},
+		{"yesterday", time.Time{}, true},
+	}
+	for _, test := range tests {
+		got, err := parseLegacyTime(test.value)
+		if (err != nil) != test.wantErr {
+			t.Errorf("%q: unexpected error %v", test.value, err)
+			continue
+		}
+		if !test.wantErr && !got.Equal(test.want) {
+			t.Errorf("%q: expected %s, received %s", test.value, test.want, got)
+		}
+	}
+}
@@ -0,0 +1,180 @@
+package tasks
+
+import (
+	"backend/jsondatabase"
+	"errors"
+)
+
+// TaskChange is a task before and after a change. Before is nil for a task
+// that was created and After for one that was deleted.
+type TaskChange struct {
+	Before *Task `json:"before,omitempty"`
+	After  *Task `json:"after,omitempty"`
+	// Archived is set on the deletion of a task moved to the archive.
+	Archived bool `json:"archived,omitempty"`
+}
+
+// observer is told about the changes of each saved transaction.
+type observer func(changes []TaskChange) error
+
+// StoreOption configures a Store.
+type StoreOption func(*Store)
+
+// observedRepository collects the changes made in each transaction and hands
+// them to the observers once the transaction is saved.
+type observedRepository struct {
+	Repository
+	notify observer
+}
+
+func (r *observedRepository) Atomic(fn func(repo Repository) error) error {
+	var changes []TaskChange
+	err := r.Repository.Atomic(func(repo Repository) error {
+		changes = nil
+		return fn(&recordingRepository{repo, &changes})
+	})
+	if err != nil || len(changes) == 0 {
+		return err
+	}
+	return r.notify(changes)
+}
+
+func (r *observedRepository) Insert(task Task) (Task, error) {
+	var inserted Task
+	err := r.Atomic(func(repo Repository) error {
+		var insertErr error
+		inserted, insertErr = repo.Insert(task)
+		return insertErr
+	})
+	if err != nil {
+		return ZeroTask, err
+	}
+	return inserted, nil
+}
+
+func (r *observedRepository) Update(task Task) error {
+	return r.Atomic(func(repo Repository) error {
+		return repo.Update(task)
+	})
+}
+
+func (r *observedRepository) Restore(task Task) error {
+	return r.Atomic(func(repo Repository) error {
+		return repo.Restore(task)
+	})
+}
+
+func (r *observedRepository) Delete(id int) error {
+	return r.Atomic(func(repo Repository) error {
+		return repo.Delete(id)
+	})
+}
+
+func (r *observedRepository) Migrate(dryRun bool) (*jsondatabase.MigrationReport, error) {
+	return migrateRepository(r.Repository, dryRun)
+}
+
+// recordingRepository collects the changes made inside one Atomic call.
+type recordingRepository struct {
+	Repository
+	changes *[]TaskChange
+}
+
+func (r *recordingRepository) Atomic(fn func(repo Repository) error) error {
+	return fn(r)
+}
+
+func (r *recordingRepository) Insert(task Task) (Task, error) {
+	inserted, err := r.Repository.Insert(task)
+	if err != nil {
+		return inserted, err
+	}
+	*r.changes = append(*r.changes, TaskChange{After: &inserted})
+	return inserted, nil
+}
+
+func (r *recordingRepository) Update(task Task) error {
+	before, getErr := r.Repository.Get(task.ID)
+	if getErr != nil {
+		return getErr
+	}
+	if err := r.Repository.Update(task); err != nil {
+		return err
+	}
+	*r.changes = append(*r.changes, TaskChange{Before: &before, After: &task})
+	return nil
+}
+
+func (r *recordingRepository) Restore(task Task) error {
+	change := TaskChange{After: &task}
+	before, getErr := unfiltered(r.Repository).Get(task.ID)
+	var notFound *ErrTaskNotFound
+	switch {
+	case getErr == nil:
+		change.Before = &before
+	case !errors.As(getErr, &notFound):
+		return getErr
+	}
+	if err := r.Repository.Restore(task); err != nil {
+		return err
+	}
+	*r.changes = append(*r.changes, change)
+	return nil
+}
+
+func (r *recordingRepository) Delete(id int) error {
+	before, getErr := r.Repository.Get(id)
+	if getErr != nil {
+		return getErr
+	}
+	if err := r.Repository.Delete(id); err != nil {
+		return err
+	}
+	*r.changes = append(*r.changes, TaskChange{Before: &before})
+	return nil
+}
+
+// observed reports whether the changes of the store are recorded by undo or
+// handed to observers.
+func (s *Store) observed() bool {
+	return s.undo != nil || len(s.observers) > 0
+}
+
+// notify records changes for undo and hands them to every observer, stopping
+// at the first error. On a session the undo stack is the session's own and
+// the observers are told only once the changes are saved, so history never
+// records what a failed Save left out of the repository.
+func (s *Store) notify(changes []TaskChange) error {
+	if s.undo != nil {
+		if err := s.undo.record(changes); err != nil {
+			return err
+		}
+	}
+	if s.session == nil {
+		return s.notifyObservers(changes)
+	}
+	s.session.held = append(s.session.held, func() error {
+		return s.notifyObservers(changes)
+	})
+	return nil
+}
+
+// beforeSave runs write, a write outside the repository that must land before
+// the changes that follow it. On a session it is held until Save, which runs
+// it before writing the changes of the session.
+func (s *Store) beforeSave(write func() error) error {
+	if s.session == nil {
+		return write()
+	}
+	s.session.heldWrites = append(s.session.heldWrites, write)
+	return nil
+}
+
+func (s *Store) notifyObservers(changes []TaskChange) error {
+	for _, observe := range s.observers {
+		if err := observe(changes); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package tasks
+
+import (
+	"fmt"
+	"strings"
+)
+
+type Priority int
+
+const (
+	PriorityNone Priority = iota
+	PriorityLow
+	PriorityMedium
+	PriorityHigh
+	PriorityCritical
+)
+
+var priorityNames = []string{"none", "low", "medium", "high", "critical"}
+
+// priorityMarkers are shown next to the description in Task.String().
+var priorityMarkers = []string{"", "↓", "!", "!!", "!!!"}
+
+func (p Priority) String() string {
+	if validatePriority(p) != nil {
+		return fmt.Sprintf("Priority(%d)", int(p))
+	}
+	return priorityNames[p]
+}
+
+// Marker is a short symbol for the priority, empty for PriorityNone.
+func (p Priority) Marker() string {
+	if validatePriority(p) != nil {
+		return ""
+	}
+	return priorityMarkers[p]
+}
+
+type ErrInvalidPriority struct {
+	Name string
+}
+
+func (e *ErrInvalidPriority) Error() string {
+	return fmt.Sprintf("invalid priority %q. Expected one of %s", e.Name, strings.Join(priorityNames, ", "))
+}
+
+// ParsePriority returns the priority called name, ignoring case.
+func ParsePriority(name string) (Priority, error) {
+	for value, priorityName := range priorityNames {
+		if strings.EqualFold(name, priorityName) {
+			return Priority(value), nil
+		}
+	}
+	return PriorityNone, &ErrInvalidPriority{name}
+}
+
+func validatePriority(priority Priority) error {
+	if priority < PriorityNone || priority > PriorityCritical {
+		return &ErrInvalidPriority{fmt.Sprint(int(priority))}
+	}
+	return nil
+}
+
+func (s *Store) UpdateTaskPriority(id int, priority Priority) (Task, error) {
+	if validateErr := validatePriority(priority); validateErr != nil {
+		return ZeroTask, validateErr
+	}
+	return s.modifyTask(id, func(task *Task) {
+		task.Priority = priority
+		setUpdatedDate(task)
+	})
+}
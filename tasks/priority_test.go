@@ -0,0 +1,72 @@
+package tasks
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParsePriority(t *testing.T) {
+	for name, want := range map[string]Priority{"none": PriorityNone, "low": PriorityLow, "HIGH": PriorityHigh, "critical": PriorityCritical} {
+		got, err := ParsePriority(name)
+		if err != nil || got != want {
+			t.Errorf("ParsePriority(%q): expected %s, received %s (%v)", name, want, got, err)
+		}
+	}
+
+	expectedErrType := &ErrInvalidPriority{}
+	if _, err := ParsePriority("urgent"); !errors.As(err, &expectedErrType) {
+		t.Errorf("expected ErrInvalidPriority, received %v", err)
+	}
+}
+
+func TestUpdateTaskPriority(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, store *Store) {
+		task, _ := store.AddTask("ship it")
+		if task.Priority != PriorityNone {
+			t.Errorf("new tasks should have no priority, received %s", task.Priority)
+		}
+
+		updated, err := store.UpdateTaskPriority(task.ID, PriorityHigh)
+		if err != nil {
+			t.Fatal(err)
+		}
+		stored, _ := store.GetTask(task.ID)
+		if stored.Priority != PriorityHigh || updated.UpdatedAt.IsZero() {
+			t.Errorf("expected a high priority update, received %+v", stored)
+		}
+		if !strings.Contains(stored.String(), "!! ship it") {
+			t.Errorf("expected a priority marker in %s", stored)
+		}
+
+		expectedErrType := &ErrInvalidPriority{}
+		if _, err := store.UpdateTaskPriority(task.ID, 9); !errors.As(err, &expectedErrType) {
+			t.Errorf("expected ErrInvalidPriority, received %v", err)
+		}
+	})
+}
+
+func TestQueryByPriority(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, store *Store) {
+		store.Add(Task{Description: "someday", Priority: PriorityLow})
+		store.Add(Task{Description: "now", Priority: PriorityCritical})
+		store.AddTask("unsorted")
+		store.Add(Task{Description: "soon", Priority: PriorityHigh})
+
+		sorted, err := store.Query(Query{Status: AllTasks, SortBy: SortByPriority})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ids := taskIDs(sorted); ids != "2 4 1 3" {
+			t.Errorf("expected 2 4 1 3, received %s", ids)
+		}
+
+		urgent, err := store.Query(Query{Status: AllTasks, Priorities: []Priority{PriorityHigh, PriorityCritical}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ids := taskIDs(urgent); ids != "2 4" {
+			t.Errorf("expected 2 4, received %s", ids)
+		}
+	})
+}
@@ -0,0 +1,162 @@
+package tasks
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+type ErrInvalidProject struct {
+	Name string
+}
+
+func (e *ErrInvalidProject) Error() string {
+	return fmt.Sprintf("invalid project %q. Expected dot separated names without spaces, like web.frontend", e.Name)
+}
+
+type ErrProjectNotFound struct {
+	Name string
+}
+
+func (e *ErrProjectNotFound) Error() string {
+	return fmt.Sprintf("no task belongs to project %s", e.Name)
+}
+
+// NormalizeProject lowercases a dotted project name and checks that none of
+// its parts is empty. An empty name means no project.
+func NormalizeProject(name string) (string, error) {
+	normalized := strings.ToLower(strings.TrimSpace(name))
+	if normalized == "" {
+		return "", nil
+	}
+	for part := range strings.SplitSeq(normalized, ".") {
+		if part == "" || strings.IndexFunc(part, unicode.IsSpace) != -1 {
+			return "", &ErrInvalidProject{name}
+		}
+	}
+	return normalized, nil
+}
+
+// InProject reports whether project is parent or one of its subprojects.
+func InProject(project, parent string) bool {
+	return project == parent || strings.HasPrefix(project, parent+".")
+}
+
+// projectAncestors returns project and every project above it, like web and
+// web.frontend for web.frontend.
+func projectAncestors(project string) []string {
+	ancestors := []string{}
+	for i, r := range project {
+		if r == '.' {
+			ancestors = append(ancestors, project[:i])
+		}
+	}
+	return append(ancestors, project)
+}
+
+// ProjectCount holds the number of tasks in each status of a project,
+// subprojects included.
+type ProjectCount struct {
+	Project    string
+	Todo       int
+	InProgress int
+	Done       int
+	// Other counts the tasks in statuses added by the workflow.
+	Other int
+}
+
+func (s *Store) UpdateTaskProject(id int, project string) (Task, error) {
+	normalized, err := NormalizeProject(project)
+	if err != nil {
+		return ZeroTask, err
+	}
+	return s.modifyTask(id, func(task *Task) {
+		task.Project = normalized
+		setUpdatedDate(task)
+	})
+}
+
+// Projects returns every project in use and the projects above them, sorted
+// by name.
+func (s *Store) Projects() ([]ProjectCount, error) {
+	tasks, err := s.repo.List()
+	if err != nil {
+		return nil, err
+	}
+	counts := map[string]*ProjectCount{}
+	for _, task := range tasks {
+		if task.Project == "" {
+			continue
+		}
+		for _, project := range projectAncestors(task.Project) {
+			count, ok := counts[project]
+			if !ok {
+				count = &ProjectCount{Project: project}
+				counts[project] = count
+			}
+			switch task.Status {
+			case Todo:
+				count.Todo++
+			case InProgress:
+				count.InProgress++
+			case Done:
+				count.Done++
+			default:
+				count.Other++
+			}
+		}
+	}
+
+	projects := make([]ProjectCount, 0, len(counts))
+	for _, count := range counts {
+		projects = append(projects, *count)
+	}
+	slices.SortFunc(projects, func(a, b ProjectCount) int {
+		return cmp.Compare(a.Project, b.Project)
+	})
+	return projects, nil
+}
+
+// RenameProject moves every task of project and its subprojects to newName in
+// a single transaction, returning the number of tasks moved. Renaming web to
+// site turns web.frontend into site.frontend.
+func (s *Store) RenameProject(project, newName string) (int, error) {
+	from, fromErr := NormalizeProject(project)
+	if fromErr != nil {
+		return 0, fromErr
+	}
+	to, toErr := NormalizeProject(newName)
+	if toErr != nil {
+		return 0, toErr
+	}
+	if from == "" || to == "" {
+		return 0, &ErrInvalidProject{""}
+	}
+
+	renamed := 0
+	err := s.repo.Atomic(func(repo Repository) error {
+		renamed = 0
+		tasks, listErr := repo.List()
+		if listErr != nil {
+			return listErr
+		}
+		for _, task := range tasks {
+			if task.Project == "" || !InProject(task.Project, from) {
+				continue
+			}
+			task.Project = to + strings.TrimPrefix(task.Project, from)
+			setUpdatedDate(&task)
+			if updateErr := repo.Update(task); updateErr != nil {
+				return updateErr
+			}
+			renamed++
+		}
+		if renamed == 0 {
+			return &ErrProjectNotFound{from}
+		}
+		return nil
+	})
+	return renamed, err
+}
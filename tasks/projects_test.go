@@ -0,0 +1,84 @@
+package tasks
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestNormalizeProject(t *testing.T) {
+	tests := map[string]string{"Web.Frontend": "web.frontend", " api ": "api", "": ""}
+	for name, want := range tests {
+		if got, err := NormalizeProject(name); err != nil || got != want {
+			t.Errorf("NormalizeProject(%q): expected %q, received %q (%v)", name, want, got, err)
+		}
+	}
+
+	expectedErrType := &ErrInvalidProject{}
+	for _, name := range []string{"web.", ".web", "web..frontend", "web front"} {
+		if _, err := NormalizeProject(name); !errors.As(err, &expectedErrType) {
+			t.Errorf("NormalizeProject(%q): expected ErrInvalidProject, received %v", name, err)
+		}
+	}
+}
+
+func TestProjects(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, store *Store) {
+		store.Add(Task{Description: "landing page", Project: "web.frontend"})
+		store.Add(Task{Description: "auth", Project: "Web.Backend"})
+		store.Add(Task{Description: "webhooks", Project: "webhooks"})
+		store.AddTask("no project")
+		store.UpdateTaskStatus(2, Done)
+
+		web, err := store.Query(Query{Status: AllTasks, Project: "web"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ids := taskIDs(web); ids != "1 2" {
+			t.Errorf("project web should include its subprojects only, received %s", ids)
+		}
+
+		counts, err := store.Projects()
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := []ProjectCount{
+			{Project: "web", Todo: 1, Done: 1},
+			{Project: "web.backend", Done: 1},
+			{Project: "web.frontend", Todo: 1},
+			{Project: "webhooks", Todo: 1},
+		}
+		if !slices.Equal(counts, expected) {
+			t.Errorf("expected %v, received %v", expected, counts)
+		}
+	})
+}
+
+func TestRenameProject(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, store *Store) {
+		store.Add(Task{Description: "landing page", Project: "web.frontend"})
+		store.Add(Task{Description: "root", Project: "web"})
+		store.Add(Task{Description: "webhooks", Project: "webhooks"})
+
+		renamed, err := store.RenameProject("web", "site")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if renamed != 2 {
+			t.Errorf("expected 2 renamed tasks, received %d", renamed)
+		}
+		tasks, _ := store.Query(Query{Status: AllTasks})
+		projects := []string{}
+		for _, task := range tasks {
+			projects = append(projects, task.Project)
+		}
+		if !slices.Equal(projects, []string{"site.frontend", "site", "webhooks"}) {
+			t.Errorf("unexpected projects after rename: %v", projects)
+		}
+
+		expectedErrType := &ErrProjectNotFound{}
+		if _, err := store.RenameProject("web", "site"); !errors.As(err, &expectedErrType) {
+			t.Errorf("expected ErrProjectNotFound, received %v", err)
+		}
+	})
+}
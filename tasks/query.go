@@ -0,0 +1,205 @@
+package tasks
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"time"
+)
+
+type SortKey string
+
+const (
+	SortByID       SortKey = "id"
+	SortByCreated  SortKey = "created"
+	SortByUpdated  SortKey = "updated"
+	SortByPriority SortKey = "priority"
+	SortByDue      SortKey = "due"
+)
+
+var SortKeys = []SortKey{SortByID, SortByCreated, SortByUpdated, SortByPriority, SortByDue}
+
+type ErrInvalidSortKey struct {
+	Key string
+}
+
+func (e *ErrInvalidSortKey) Error() string {
+	return fmt.Sprintf("invalid sort key %q. Expected one of %v", e.Key, SortKeys)
+}
+
+// Query selects and orders tasks. The zero value of each field matches every
+// task, except Status, which must be AllTasks to do so.
+type Query struct {
+	Status       int
+	CreatedAfter time.Time
+	UpdatedAfter time.Time
+	// Priorities lists the priorities to include. Empty means all of them.
+	Priorities []Priority
+	// DueFrom and DueBefore bound the due date, DueFrom inclusive. Tasks
+	// without a due date never match a bound.
+	DueFrom   time.Time
+	DueBefore time.Time
+	// Tags must all be on a task and ExcludeTags none of them. Both hold
+	// normalized tags.
+	Tags        []string
+	ExcludeTags []string
+	// Project selects the tasks of a project and its subprojects.
+	Project string
+	// Pending leaves out tasks in a closed status, like done.
+	Pending bool
+	// Ready keeps only todo tasks whose blockers are all closed.
+	Ready  bool
+	SortBy SortKey
+}
+
+// Views are named queries that list accepts in place of a status.
+const (
+	ViewReady       = "ready"
+	ViewOverdue     = "overdue"
+	ViewDueToday    = "due-today"
+	ViewDueThisWeek = "due-this-week"
+)
+
+var Views = []string{ViewReady, ViewOverdue, ViewDueToday, ViewDueThisWeek}
+
+// ViewQuery returns the query behind a view, or false if name is not one.
+// The due views select unfinished tasks by when they are due.
+func ViewQuery(name string, now time.Time) (Query, bool) {
+	today := startOfDay(now)
+	query := Query{Status: AllTasks, Pending: true, SortBy: SortByDue}
+	switch name {
+	case ViewReady:
+		query = Query{Status: AllTasks, Ready: true}
+	case ViewOverdue:
+		query.DueBefore = today
+	case ViewDueToday:
+		query.DueFrom = today
+		query.DueBefore = today.AddDate(0, 0, 1)
+	case ViewDueThisWeek:
+		query.DueFrom = today
+		query.DueBefore = startOfWeek(today).AddDate(0, 0, 7)
+	default:
+		return query, false
+	}
+	return query, true
+}
+
+// matches reports whether task is selected by q. open holds the IDs of the
+// tasks that are not done, which Ready needs.
+func (q Query) matches(task Task, open map[int]bool) bool {
+	if q.Status != AllTasks && task.Status != TaskStatus(q.Status) {
+		return false
+	}
+	if !q.CreatedAfter.IsZero() && task.CreatedAt.Before(q.CreatedAfter) {
+		return false
+	}
+	if !q.UpdatedAfter.IsZero() && task.LastChange().Before(q.UpdatedAfter) {
+		return false
+	}
+	if q.Pending && task.Status.Closed() {
+		return false
+	}
+	hasDueBound := !q.DueFrom.IsZero() || !q.DueBefore.IsZero()
+	if hasDueBound && task.Due.IsZero() {
+		return false
+	}
+	if !q.DueFrom.IsZero() && task.Due.Before(q.DueFrom) {
+		return false
+	}
+	if !q.DueBefore.IsZero() && !task.Due.Before(q.DueBefore) {
+		return false
+	}
+	if q.Project != "" && !InProject(task.Project, q.Project) {
+		return false
+	}
+	for _, tag := range q.Tags {
+		if !task.HasTag(tag) {
+			return false
+		}
+	}
+	if slices.ContainsFunc(q.ExcludeTags, task.HasTag) {
+		return false
+	}
+	if len(q.Priorities) > 0 && !slices.Contains(q.Priorities, task.Priority) {
+		return false
+	}
+	if q.Ready {
+		blocked := slices.ContainsFunc(task.BlockedBy, func(id int) bool { return open[id] })
+		return task.Status == Todo && !blocked
+	}
+	return true
+}
+
+// LastChange is when the task was last updated, or created if it never was.
+func (task Task) LastChange() time.Time {
+	if task.UpdatedAt.IsZero() {
+		return task.CreatedAt
+	}
+	return task.UpdatedAt
+}
+
+// SortTasks orders tasks in place by key. Time keys put the newest first,
+// priority puts the most urgent first, due puts the soonest first with undated
+// tasks last and ties fall back to ID order.
+func SortTasks(tasks []Task, key SortKey) error {
+	var compare func(a, b Task) int
+	switch key {
+	case SortByID, "":
+		compare = func(a, b Task) int { return 0 }
+	case SortByCreated:
+		compare = func(a, b Task) int { return b.CreatedAt.Compare(a.CreatedAt) }
+	case SortByUpdated:
+		compare = func(a, b Task) int { return b.LastChange().Compare(a.LastChange()) }
+	case SortByPriority:
+		compare = func(a, b Task) int { return cmp.Compare(b.Priority, a.Priority) }
+	case SortByDue:
+		compare = func(a, b Task) int {
+			return cmp.Or(compareBool(a.Due.IsZero(), b.Due.IsZero()), a.Due.Compare(b.Due))
+		}
+	default:
+		return &ErrInvalidSortKey{string(key)}
+	}
+	slices.SortStableFunc(tasks, func(a, b Task) int {
+		return cmp.Or(compare(a, b), cmp.Compare(a.ID, b.ID))
+	})
+	return nil
+}
+
+// compareBool orders false before true.
+func compareBool(a, b bool) int {
+	switch {
+	case a == b:
+		return 0
+	case a:
+		return 1
+	}
+	return -1
+}
+
+// Query returns the tasks matching q in the order it asks for.
+func (s *Store) Query(q Query) ([]Task, error) {
+	return queryRepository(s.repo, q)
+}
+
+func queryRepository(repo Repository, q Query) ([]Task, error) {
+	if validateErr := validateStatus(TaskStatus(q.Status)); q.Status != AllTasks && validateErr != nil {
+		return nil, validateErr
+	}
+	project, projectErr := NormalizeProject(q.Project)
+	if projectErr != nil {
+		return nil, projectErr
+	}
+	q.Project = project
+	tasks, err := repo.List()
+	if err != nil {
+		return nil, err
+	}
+	open := openTasks(tasks)
+	tasks = slices.DeleteFunc(tasks, func(task Task) bool {
+		return !q.matches(task, open)
+	})
+	if sortErr := SortTasks(tasks, q.SortBy); sortErr != nil {
+		return nil, sortErr
+	}
+	return tasks, nil
+}
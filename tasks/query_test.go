@@ -0,0 +1,58 @@
+package tasks
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSortTasks(t *testing.T) {
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	tasks := []Task{
+		{ID: 1, CreatedAt: base, UpdatedAt: base.Add(5 * time.Hour)},
+		{ID: 2, CreatedAt: base.Add(2 * time.Hour)},
+		{ID: 3, CreatedAt: base.Add(time.Hour)},
+	}
+
+	SortTasks(tasks, SortByCreated)
+	if ids := taskIDs(tasks); ids != "2 3 1" {
+		t.Errorf("sort by created: expected 2 3 1, received %s", ids)
+	}
+	SortTasks(tasks, SortByUpdated)
+	if ids := taskIDs(tasks); ids != "1 2 3" {
+		t.Errorf("sort by updated: expected 1 2 3, received %s", ids)
+	}
+	SortTasks(tasks, SortByID)
+	if ids := taskIDs(tasks); ids != "1 2 3" {
+		t.Errorf("sort by id: expected 1 2 3, received %s", ids)
+	}
+
+	expectedErrType := &ErrInvalidSortKey{}
+	if err := SortTasks(tasks, "colour"); !errors.As(err, &expectedErrType) {
+		t.Errorf("expected ErrInvalidSortKey, received %v", err)
+	}
+}
+
+func TestQuery(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, store *Store) {
+		first, _ := store.AddTask("first")
+		second, _ := store.AddTask("second")
+		store.UpdateTaskStatus(second.ID, Done)
+
+		done, err := store.Query(Query{Status: int(Done)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(done) != 1 || done[0].ID != second.ID {
+			t.Errorf("expected only task %d, received %v", second.ID, done)
+		}
+
+		recent, err := store.Query(Query{Status: AllTasks, UpdatedAfter: first.CreatedAt.Add(time.Nanosecond)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(recent) != 1 || recent[0].ID != second.ID {
+			t.Errorf("expected only the updated task, received %v", recent)
+		}
+	})
+}
@@ -0,0 +1,268 @@
+package tasks
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type RecurrenceKind string
+
+const (
+	RecurDaily   RecurrenceKind = "daily"
+	RecurWeekly  RecurrenceKind = "weekly"
+	RecurMonthly RecurrenceKind = "monthly"
+	// RecurAfter repeats a number of days after the last instance was done.
+	RecurAfter RecurrenceKind = "after"
+)
+
+// Recurrence is the rule a recurring task repeats by.
+type Recurrence struct {
+	Kind RecurrenceKind `json:"kind"`
+	// Weekdays are the days a weekly task falls on. Empty means the weekday
+	// of its due date.
+	Weekdays []time.Weekday `json:"weekdays,omitempty"`
+	// Day is the day of the month of a monthly task. Months too short for it
+	// use their last day.
+	Day int `json:"day,omitempty"`
+	// Days is the gap of RecurAfter.
+	Days int `json:"days,omitempty"`
+}
+
+type ErrInvalidRecurrence struct {
+	Rule string
+}
+
+func (e *ErrInvalidRecurrence) Error() string {
+	return fmt.Sprintf("invalid recurrence %q. Expected daily, weekly, weekly mon,thu, monthly, monthly 15 or every 3 days", e.Rule)
+}
+
+// ParseRecurrence reads rules like "daily", "weekly mon,thu", "monthly 15"
+// and "every 3 days", the last one counting from when a task is done.
+func ParseRecurrence(rule string) (Recurrence, error) {
+	fields := strings.Fields(strings.ToLower(rule))
+	invalid := &ErrInvalidRecurrence{rule}
+	if len(fields) == 0 {
+		return Recurrence{}, invalid
+	}
+
+	switch {
+	case fields[0] == "daily" && len(fields) == 1:
+		return Recurrence{Kind: RecurDaily}, nil
+	case fields[0] == "weekly" && len(fields) <= 2:
+		recurrence := Recurrence{Kind: RecurWeekly}
+		if len(fields) == 1 {
+			return recurrence, nil
+		}
+		for name := range strings.SplitSeq(fields[1], ",") {
+			weekday, ok := parseWeekday(name)
+			if !ok {
+				return Recurrence{}, invalid
+			}
+			recurrence.Weekdays = append(recurrence.Weekdays, weekday)
+		}
+		slices.Sort(recurrence.Weekdays)
+		recurrence.Weekdays = slices.Compact(recurrence.Weekdays)
+		return recurrence, nil
+	case fields[0] == "monthly" && len(fields) <= 2:
+		recurrence := Recurrence{Kind: RecurMonthly}
+		if len(fields) == 1 {
+			return recurrence, nil
+		}
+		day, err := strconv.Atoi(fields[1])
+		if err != nil || day < 1 || day > 31 {
+			return Recurrence{}, invalid
+		}
+		recurrence.Day = day
+		return recurrence, nil
+	case fields[0] == "every" && len(fields) >= 2:
+		_, _, days, ok := parseOffset(strings.Join(fields[1:], " "))
+		if !ok || days < 1 {
+			return Recurrence{}, invalid
+		}
+		return Recurrence{Kind: RecurAfter, Days: days}, nil
+	}
+	return Recurrence{}, invalid
+}
+
+func (r Recurrence) String() string {
+	switch r.Kind {
+	case RecurWeekly:
+		if len(r.Weekdays) == 0 {
+			return "weekly"
+		}
+		names := make([]string, len(r.Weekdays))
+		for i, weekday := range r.Weekdays {
+			names[i] = strings.ToLower(weekday.String()[:3])
+		}
+		return "weekly on " + strings.Join(names, ",")
+	case RecurMonthly:
+		if r.Day == 0 {
+			return "monthly"
+		}
+		return fmt.Sprintf("monthly on day %d", r.Day)
+	case RecurAfter:
+		return fmt.Sprintf("every %d days after done", r.Days)
+	}
+	return string(r.Kind)
+}
+
+// clampDay clamps day to the length of the month date is in.
+func clampDay(date time.Time, day int) time.Time {
+	firstOfNext := time.Date(date.Year(), date.Month()+1, 1, 0, 0, 0, 0, date.Location())
+	day = min(day, firstOfNext.AddDate(0, 0, -1).Day())
+	return time.Date(date.Year(), date.Month(), day, 0, 0, 0, 0, date.Location())
+}
+
+// Next returns the due date of the instance after one due on due and done on
+// done. Missed occurrences are skipped: the next date is after both.
+func (r Recurrence) Next(due, done time.Time) time.Time {
+	after := startOfDay(done)
+	if !due.IsZero() && due.After(after) {
+		after = startOfDay(due)
+	}
+	anchor := due
+	if anchor.IsZero() {
+		anchor = done
+	}
+
+	switch r.Kind {
+	case RecurAfter:
+		return startOfDay(done).AddDate(0, 0, r.Days)
+	case RecurWeekly:
+		weekdays := r.Weekdays
+		if len(weekdays) == 0 {
+			weekdays = []time.Weekday{anchor.Weekday()}
+		}
+		next := after.AddDate(0, 0, 1)
+		for !slices.Contains(weekdays, next.Weekday()) {
+			next = next.AddDate(0, 0, 1)
+		}
+		return next
+	case RecurMonthly:
+		day := r.Day
+		if day == 0 {
+			day = anchor.Day()
+		}
+		next := clampDay(after, day)
+		for i := 1; !next.After(after); i++ {
+			next = clampDay(time.Date(after.Year(), after.Month()+time.Month(i), 1, 0, 0, 0, 0, after.Location()), day)
+		}
+		return next
+	}
+	return after.AddDate(0, 0, 1)
+}
+
+// anchored returns the rule with the weekday or the day of the month it
+// leaves empty taken from date, so later instances keep to the day the series
+// started on instead of drifting with a date clamped to a short month.
+func (r Recurrence) anchored(date time.Time) Recurrence {
+	switch {
+	case r.Kind == RecurWeekly && len(r.Weekdays) == 0:
+		r.Weekdays = []time.Weekday{date.Weekday()}
+	case r.Kind == RecurMonthly && r.Day == 0:
+		r.Day = date.Day()
+	}
+	return r
+}
+
+// first returns the first due date of a new series started now: today for a
+// daily rule, otherwise the next date of the rule after today. Rules
+// repeating after done have none.
+func (r Recurrence) first(now time.Time) time.Time {
+	today := startOfDay(now)
+	switch r.Kind {
+	case RecurAfter:
+		return time.Time{}
+	case RecurDaily:
+		return today
+	}
+	return r.anchored(today).Next(today, today)
+}
+
+// nextInstance returns the task that follows a recurring task that was just
+// marked done.
+func nextInstance(task Task, now time.Time) Task {
+	next := task
+	next.ID = 0
+	next.Status = Todo
+	next.Due = task.Recur.Next(task.Due, now)
+	next.BlockedBy = nil
+	next.CreatedAt = now
+	next.UpdatedAt = time.Time{}
+	return next
+}
+
+// SetRecurrence makes a task repeat by rule, starting a series with it. A task
+// without a due date gets the first one of the rule.
+func (s *Store) SetRecurrence(id int, recurrence Recurrence) (Task, error) {
+	return s.modifyTask(id, func(task *Task) {
+		task.Series = cmp.Or(task.Series, task.ID)
+		if task.Due.IsZero() {
+			task.Due = recurrence.first(time.Now())
+		}
+		recurrence = recurrence.anchored(task.Due)
+		task.Recur = &recurrence
+		setUpdatedDate(task)
+	})
+}
+
+// StopRecurrence ends the series the task belongs to, so marking its open
+// instance done no longer creates another. It returns the tasks changed.
+func (s *Store) StopRecurrence(id int) ([]Task, error) {
+	stopped := []Task{}
+	err := s.repo.Atomic(func(repo Repository) error {
+		task, getErr := repo.Get(id)
+		if getErr != nil {
+			return getErr
+		}
+		series, seriesErr := seriesOf(repo, task)
+		if seriesErr != nil {
+			return seriesErr
+		}
+		for _, instance := range series {
+			if instance.Recur == nil {
+				continue
+			}
+			instance.Recur = nil
+			setUpdatedDate(&instance)
+			if updateErr := repo.Update(instance); updateErr != nil {
+				return updateErr
+			}
+			stopped = append(stopped, instance)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return stopped, nil
+}
+
+// seriesOf returns every instance of the series task belongs to, or just
+// task if it is not recurring.
+func seriesOf(repo Repository, task Task) ([]Task, error) {
+	if task.Series == 0 {
+		return []Task{task}, nil
+	}
+	tasks, err := repo.List()
+	if err != nil {
+		return nil, err
+	}
+	return slices.DeleteFunc(tasks, func(instance Task) bool {
+		return instance.Series != task.Series
+	}), nil
+}
+
+// Series returns every instance of the series a task belongs to, oldest
+// first.
+func (s *Store) Series(id int) ([]Task, error) {
+	task, err := s.repo.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	return seriesOf(s.repo, task)
+}
@@ -0,0 +1,151 @@
+package tasks
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestParseRecurrence(t *testing.T) {
+	tests := map[string]Recurrence{
+		"daily":          {Kind: RecurDaily},
+		"Weekly":         {Kind: RecurWeekly},
+		"weekly thu,mon": {Kind: RecurWeekly, Weekdays: []time.Weekday{time.Monday, time.Thursday}},
+		"monthly":        {Kind: RecurMonthly},
+		"monthly 31":     {Kind: RecurMonthly, Day: 31},
+		"every 3 days":   {Kind: RecurAfter, Days: 3},
+		"every 2w":       {Kind: RecurAfter, Days: 14},
+	}
+	for rule, want := range tests {
+		got, err := ParseRecurrence(rule)
+		if err != nil || got.Kind != want.Kind || got.Day != want.Day || got.Days != want.Days || !slices.Equal(got.Weekdays, want.Weekdays) {
+			t.Errorf("ParseRecurrence(%q): expected %+v, received %+v (%v)", rule, want, got, err)
+		}
+	}
+
+	expectedErrType := &ErrInvalidRecurrence{}
+	for _, rule := range []string{"", "hourly", "weekly funday", "monthly 32", "every 0 days", "daily please"} {
+		if _, err := ParseRecurrence(rule); !errors.As(err, &expectedErrType) {
+			t.Errorf("ParseRecurrence(%q): expected ErrInvalidRecurrence, received %v", rule, err)
+		}
+	}
+}
+
+func TestRecurrenceNext(t *testing.T) {
+	// dueNow is Wednesday 2026-05-20.
+	tests := []struct {
+		rule string
+		due  time.Time
+		want time.Time
+	}{
+		{"daily", dueDate(2026, 5, 20), dueDate(2026, 5, 21)},
+		{"daily", dueDate(2026, 5, 10), dueDate(2026, 5, 21)},
+		{"weekly", dueDate(2026, 5, 18), dueDate(2026, 5, 25)},
+		{"weekly mon,thu", dueDate(2026, 5, 18), dueDate(2026, 5, 21)},
+		{"weekly mon,thu", dueDate(2026, 5, 21), dueDate(2026, 5, 25)},
+		{"monthly", dueDate(2026, 5, 15), dueDate(2026, 6, 15)},
+		{"monthly 31", dueDate(2026, 5, 31), dueDate(2026, 6, 30)},
+		{"monthly 31", dueDate(2026, 1, 31), dueDate(2026, 5, 31)},
+		{"every 3 days", dueDate(2026, 5, 1), dueDate(2026, 5, 23)},
+		{"every 3 days", time.Time{}, dueDate(2026, 5, 23)},
+	}
+	for _, test := range tests {
+		recurrence, _ := ParseRecurrence(test.rule)
+		if got := recurrence.Next(test.due, dueNow); !got.Equal(test.want) {
+			t.Errorf("%s due %s: expected %s, received %s", test.rule, test.due.Format(time.DateOnly), test.want.Format(time.DateOnly), got.Format(time.DateOnly))
+		}
+	}
+}
+
+func TestRecurrenceFirst(t *testing.T) {
+	// now is Sunday 2026-10-18.
+	now := time.Date(2026, 10, 18, 9, 0, 0, 0, dueNow.Location())
+	tests := map[string]time.Time{
+		"daily":          dueDate(2026, 10, 18),
+		"weekly":         dueDate(2026, 10, 25),
+		"weekly mon,thu": dueDate(2026, 10, 19),
+		"monthly":        dueDate(2026, 11, 18),
+		"monthly 31":     dueDate(2026, 10, 31),
+		"every 3 days":   {},
+	}
+	for rule, want := range tests {
+		recurrence, _ := ParseRecurrence(rule)
+		if got := recurrence.first(now); !got.Equal(want) {
+			t.Errorf("%s: expected %s, received %s", rule, want.Format(time.DateOnly), got.Format(time.DateOnly))
+		}
+	}
+}
+
+func TestRecurrenceKeepsDay(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, store *Store) {
+		monthly, _ := ParseRecurrence("monthly")
+		task, err := store.Add(Task{Description: "pay rent", Due: dueDate(2026, 1, 31), Recur: &monthly})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if task.Recur.Day != 31 {
+			t.Errorf("expected the day of the first due date saved, received %+v", task.Recur)
+		}
+		if next := task.Recur.Next(dueDate(2026, 2, 28), dueDate(2026, 2, 28)); !next.Equal(dueDate(2026, 3, 31)) {
+			t.Errorf("expected the series back on the 31st after February, received %s", next.Format(time.DateOnly))
+		}
+	})
+}
+
+func TestRecurringTask(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, store *Store) {
+		weekly, _ := ParseRecurrence("weekly")
+		first, err := store.Add(Task{Description: "rotate on-call +ops", Due: time.Now(), Recur: &weekly})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if first.Series != first.ID {
+			t.Errorf("a recurring task should start a series, received %d", first.Series)
+		}
+
+		done, err := store.UpdateTaskStatus(first.ID, Done)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if done.Recur != nil {
+			t.Errorf("the rule should move to the next instance")
+		}
+		series, _ := store.Series(first.ID)
+		if len(series) != 2 {
+			t.Fatalf("expected 2 instances, received %v", series)
+		}
+		next := series[1]
+		expectedDue := startOfDay(first.Due).AddDate(0, 0, 7)
+		if next.Status != Todo || next.Recur == nil || next.Series != first.ID || !next.Due.Equal(expectedDue) {
+			t.Errorf("unexpected next instance %+v", next)
+		}
+		if !slices.Equal(next.Tags, []string{"ops"}) {
+			t.Errorf("the next instance should keep the tags, received %v", next.Tags)
+		}
+
+		stopped, err := store.StopRecurrence(first.ID)
+		if err != nil || len(stopped) != 1 {
+			t.Fatalf("expected the open instance to stop, received %v (%v)", stopped, err)
+		}
+		store.UpdateTaskStatus(next.ID, Done)
+		if series, _ := store.Series(first.ID); len(series) != 2 {
+			t.Errorf("a stopped series should not grow, received %v", series)
+		}
+	})
+}
+
+func TestSetRecurrence(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, store *Store) {
+		task, _ := store.AddTask("update dependencies")
+		daily, _ := ParseRecurrence("daily")
+
+		recurring, err := store.SetRecurrence(task.ID, daily)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if recurring.Series != task.ID || !recurring.Due.Equal(startOfDay(time.Now())) {
+			t.Errorf("expected a series due today, received %+v", recurring)
+		}
+	})
+}
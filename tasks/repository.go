@@ -0,0 +1,205 @@
+package tasks
+
+import (
+	"backend/jsondatabase"
+	"errors"
+	"fmt"
+)
+
+// Repository is a storage backend for tasks. Every call is atomic on its own;
+// Atomic groups several calls into one transaction.
+type Repository interface {
+	Get(id int) (Task, error)
+	List() ([]Task, error)
+	// Insert stores task under a new ID and returns it with the ID set.
+	Insert(task Task) (Task, error)
+	// Update replaces the stored task that has the same ID.
+	Update(task Task) error
+	// Restore stores task under its own ID, whether or not a task with that
+	// ID exists. It brings back deleted tasks.
+	Restore(task Task) error
+	Delete(id int) error
+	// NextID returns the ID the next Insert will assign.
+	NextID() (int, error)
+	// Atomic runs fn against a repository whose changes are kept only if fn
+	// returns nil.
+	Atomic(fn func(repo Repository) error) error
+}
+
+const (
+	BackendJSON      = "json"
+	BackendJSONLines = "jsonl"
+	BackendMemory    = "memory"
+)
+
+var Backends = []string{BackendJSON, BackendJSONLines, BackendMemory}
+
+type ErrUnknownBackend struct {
+	Name string
+}
+
+func (e *ErrUnknownBackend) Error() string {
+	return fmt.Sprintf("unknown storage backend %q. Expected json, jsonl or memory", e.Name)
+}
+
+// NewRepository opens the named backend at path. The memory backend ignores
+// path.
+func NewRepository(backend string, path string, opts ...jsondatabase.Option) (Repository, error) {
+	opts = append([]jsondatabase.Option{jsondatabase.WithMigrations(migrations...)}, opts...)
+	switch backend {
+	case BackendJSON, "":
+		return NewJSONRepository(jsondatabase.New[Task](path, opts...)), nil
+	case BackendJSONLines:
+		return NewJSONLinesRepository(jsondatabase.NewLog[Task](path, opts...)), nil
+	case BackendMemory:
+		return NewMemoryRepository(), nil
+	}
+	return nil, &ErrUnknownBackend{backend}
+}
+
+type transactor interface {
+	Update(fn func(tx *jsondatabase.Tx[Task]) error) error
+	View(fn func(tx *jsondatabase.Tx[Task]) error) error
+	Migrate(dryRun bool) (*jsondatabase.MigrationReport, error)
+}
+
+// fileRepository runs every call in a jsondatabase transaction.
+type fileRepository struct {
+	db transactor
+}
+
+// NewJSONRepository keeps tasks in a single JSON document.
+func NewJSONRepository(db *jsondatabase.Database[Task]) Repository {
+	return &fileRepository{db}
+}
+
+// NewJSONLinesRepository keeps tasks in an append-only JSON Lines log.
+func NewJSONLinesRepository(log *jsondatabase.Log[Task]) Repository {
+	return &fileRepository{log}
+}
+
+func (r *fileRepository) view(fn func(repo Repository) error) error {
+	return r.db.View(func(tx *jsondatabase.Tx[Task]) error {
+		return fn(txRepository{tx})
+	})
+}
+
+func (r *fileRepository) Atomic(fn func(repo Repository) error) error {
+	return r.db.Update(func(tx *jsondatabase.Tx[Task]) error {
+		return fn(txRepository{tx})
+	})
+}
+
+func (r *fileRepository) Get(id int) (Task, error) {
+	task := ZeroTask
+	err := r.view(func(repo Repository) error {
+		var getErr error
+		task, getErr = repo.Get(id)
+		return getErr
+	})
+	return task, err
+}
+
+func (r *fileRepository) List() ([]Task, error) {
+	var tasks []Task
+	err := r.view(func(repo Repository) error {
+		var listErr error
+		tasks, listErr = repo.List()
+		return listErr
+	})
+	return tasks, err
+}
+
+func (r *fileRepository) Insert(task Task) (Task, error) {
+	inserted := ZeroTask
+	err := r.Atomic(func(repo Repository) error {
+		var insertErr error
+		inserted, insertErr = repo.Insert(task)
+		return insertErr
+	})
+	if err != nil {
+		return ZeroTask, err
+	}
+	return inserted, nil
+}
+
+func (r *fileRepository) Update(task Task) error {
+	return r.Atomic(func(repo Repository) error {
+		return repo.Update(task)
+	})
+}
+
+func (r *fileRepository) Restore(task Task) error {
+	return r.Atomic(func(repo Repository) error {
+		return repo.Restore(task)
+	})
+}
+
+func (r *fileRepository) Delete(id int) error {
+	return r.Atomic(func(repo Repository) error {
+		return repo.Delete(id)
+	})
+}
+
+func (r *fileRepository) NextID() (int, error) {
+	var id int
+	err := r.view(func(repo Repository) error {
+		var idErr error
+		id, idErr = repo.NextID()
+		return idErr
+	})
+	return id, err
+}
+
+// taskError turns database lookup failures into ErrTaskNotFound.
+func taskError(err error) error {
+	var notFound *jsondatabase.NotFoundError
+	if errors.As(err, &notFound) {
+		return &ErrTaskNotFound{notFound.ID}
+	}
+	return err
+}
+
+// txRepository is the Repository handed to Atomic callbacks of file backends.
+type txRepository struct {
+	tx *jsondatabase.Tx[Task]
+}
+
+func (r txRepository) Atomic(fn func(repo Repository) error) error {
+	return fn(r)
+}
+
+func (r txRepository) Get(id int) (Task, error) {
+	task, err := r.tx.Get(id)
+	if err != nil {
+		return ZeroTask, taskError(err)
+	}
+	return task, nil
+}
+
+func (r txRepository) List() ([]Task, error) {
+	return r.tx.All(), nil
+}
+
+func (r txRepository) Insert(task Task) (Task, error) {
+	return r.tx.Insert(task)
+}
+
+func (r txRepository) Update(task Task) error {
+	if _, err := r.Get(task.ID); err != nil {
+		return err
+	}
+	return r.tx.Put(task)
+}
+
+func (r txRepository) Restore(task Task) error {
+	return r.tx.Put(task)
+}
+
+func (r txRepository) Delete(id int) error {
+	return taskError(r.tx.Delete(id))
+}
+
+func (r txRepository) NextID() (int, error) {
+	return r.tx.NextID(), nil
+}
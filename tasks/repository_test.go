@@ -0,0 +1,98 @@
+package tasks
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestAtomicRollsBack(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, store *Store) {
+		task, _ := store.AddTask("keep me")
+		errAbort := errors.New("abort")
+
+		err := store.repo.Atomic(func(repo Repository) error {
+			task.Description = "changed"
+			if err := repo.Update(task); err != nil {
+				return err
+			}
+			if _, err := repo.Insert(Task{Description: "extra"}); err != nil {
+				return err
+			}
+			return errAbort
+		})
+		if !errors.Is(err, errAbort) {
+			t.Fatalf("expected callback error, received %v", err)
+		}
+
+		tasks, err := store.repo.List()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(tasks) != 1 || tasks[0].Description != "keep me" {
+			t.Errorf("changes should be rolled back, received %v", tasks)
+		}
+	})
+}
+
+func TestRepositoryNextID(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, store *Store) {
+		next, err := store.repo.NextID()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if next != 1 {
+			t.Errorf("expected an empty repository to start at id 1, received %d", next)
+		}
+		task, _ := store.AddTask("first")
+		if task.ID != next {
+			t.Errorf("NextID returned %d but Insert assigned %d", next, task.ID)
+		}
+	})
+}
+
+func TestRepositoryUpdateMissingTask(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, store *Store) {
+		expectedErrType := &ErrTaskNotFound{}
+		err := store.repo.Update(Task{ID: 42, Description: "ghost"})
+		if err == nil || !errors.As(err, &expectedErrType) {
+			t.Errorf("expected ErrTaskNotFound, received %s", err)
+		}
+	})
+}
+
+func TestDeletedIDsAreNotReused(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, store *Store) {
+		store.AddTask("first")
+		newest, _ := store.AddTask("second")
+		if err := store.DeleteTask(newest.ID); err != nil {
+			t.Fatal(err)
+		}
+
+		task, err := store.AddTask("third")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if task.ID == newest.ID {
+			t.Errorf("id %d of a deleted task was handed out again", task.ID)
+		}
+	})
+}
+
+func TestStoreMigrate(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, store *Store) {
+		store.AddTask("migrated")
+		report, err := store.Migrate(true)
+		if _, isMemory := store.trash.Repository.(*MemoryRepository); isMemory {
+			if !errors.Is(err, ErrMigrationUnsupported) {
+				t.Errorf("expected ErrMigrationUnsupported, received %v", err)
+			}
+			return
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !report.UpToDate() {
+			t.Errorf("a new store should be at the latest schema: %+v", report)
+		}
+	})
+}
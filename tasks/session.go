@@ -0,0 +1,234 @@
+package tasks
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// ErrSessionConflict is returned by Session.Save when a task the session
+// changed was also changed in the repository since the session read it.
+type ErrSessionConflict struct {
+	ID int
+}
+
+func (e *ErrSessionConflict) Error() string {
+	return fmt.Sprintf("task %d was changed by someone else during the session, nothing was saved", e.ID)
+}
+
+// Session is a copy of a repository kept in memory, for running many commands
+// with a single read and write. Changes reach the repository only on Save.
+type Session struct {
+	*MemoryRepository
+	source Repository
+	// saved is the source as of the last read or Save.
+	saved map[int]Task
+	// held are the notifications of the stores on the session, run in order
+	// by the next successful Save.
+	held []func() error
+	// heldWrites are writes outside the repository that must land before the
+	// changes, like copies into the archive, run by the next Save first.
+	heldWrites []func() error
+	// savers write what the stores keep along the session, like its undo
+	// stack, at the end of every successful Save.
+	savers []func() error
+}
+
+// OpenSession reads every task of source, including trashed ones, into a new
+// session. Tasks added in the session get the IDs source would give them.
+func OpenSession(source Repository) (*Session, error) {
+	var list []Task
+	var nextID int
+	err := source.Atomic(func(repo Repository) error {
+		var listErr error
+		list, listErr = repo.List()
+		if listErr != nil {
+			return listErr
+		}
+		nextID, listErr = repo.NextID()
+		return listErr
+	})
+	if err != nil {
+		return nil, err
+	}
+	memory := NewMemoryRepository(list...)
+	memory.state.nextID = nextID
+	return &Session{MemoryRepository: memory, source: source, saved: tasksByID(list)}, nil
+}
+
+func tasksByID(list []Task) map[int]Task {
+	byID := make(map[int]Task, len(list))
+	for _, task := range list {
+		byID[task.ID] = task
+	}
+	return byID
+}
+
+// Changes returns what the session changed since the last Save, ordered by
+// task ID.
+func (s *Session) Changes() ([]TaskChange, error) {
+	current, err := s.MemoryRepository.List()
+	if err != nil {
+		return nil, err
+	}
+	var changes []TaskChange
+	for _, task := range current {
+		before, ok := s.saved[task.ID]
+		switch {
+		case !ok:
+			changes = append(changes, TaskChange{After: &task})
+		case !sameTask(before, task):
+			changes = append(changes, TaskChange{Before: &before, After: &task})
+		}
+	}
+	currentByID := tasksByID(current)
+	for id, task := range s.saved {
+		if _, ok := currentByID[id]; !ok {
+			changes = append(changes, TaskChange{Before: &task})
+		}
+	}
+	slices.SortFunc(changes, func(a, b TaskChange) int {
+		return cmp.Compare(changeTaskID(a), changeTaskID(b))
+	})
+	return changes, nil
+}
+
+// Save writes the changes of the session to the source in one transaction
+// and returns how many tasks it wrote. If another process changed one of
+// those tasks since the session read it, Save writes nothing and returns
+// ErrSessionConflict.
+//
+// What the stores on the session write elsewhere waits for Save too: the
+// archive is written before the changes, and the history and the undo stack
+// after them. Changes the observers make, like archiving, are written in a
+// second round. A session that is never saved leaves all of them untouched.
+func (s *Session) Save() (int, error) {
+	saved := 0
+	for {
+		changes, err := s.Changes()
+		if err != nil {
+			return saved, err
+		}
+		if len(changes) == 0 && len(s.held) == 0 && len(s.heldWrites) == 0 {
+			break
+		}
+		if err := runHeld(&s.heldWrites); err != nil {
+			return saved, err
+		}
+		if err := s.write(changes); err != nil {
+			return saved, err
+		}
+		saved += len(changes)
+		if err := runHeld(&s.held); err != nil {
+			return saved, err
+		}
+	}
+	for _, save := range s.savers {
+		if err := save(); err != nil {
+			return saved, err
+		}
+	}
+	return saved, nil
+}
+
+// runHeld runs and drops the functions in held, stopping at the first error.
+func runHeld(held *[]func() error) error {
+	fns := *held
+	*held = nil
+	for _, fn := range fns {
+		if err := fn(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// write writes changes to the source in one transaction, or nothing if one
+// of the tasks changed in the source since the session read it.
+func (s *Session) write(changes []TaskChange) error {
+	if len(changes) == 0 {
+		return nil
+	}
+	err := s.source.Atomic(func(repo Repository) error {
+		for _, change := range changes {
+			id := changeTaskID(change)
+			same, storedErr := storedAs(repo, id, change.Before)
+			if storedErr != nil {
+				return storedErr
+			}
+			if !same {
+				return &ErrSessionConflict{id}
+			}
+			if change.After == nil {
+				storedErr = repo.Delete(id)
+			} else {
+				storedErr = repo.Restore(*change.After)
+			}
+			if storedErr != nil {
+				return storedErr
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, change := range changes {
+		if change.After == nil {
+			delete(s.saved, change.Before.ID)
+		} else {
+			s.saved[change.After.ID] = *change.After
+		}
+	}
+	return nil
+}
+
+// errKeepStack rolls back the update sessionUndoLog reads the stack with.
+var errKeepStack = errors.New("undo stack read, not changed")
+
+// sessionUndoLog keeps the undo stack of a session in memory, so undo in the
+// session sees its unsaved changes and the log sees only saved ones. It reads
+// log on first use and writes the stack back on each Save.
+type sessionUndoLog struct {
+	log     UndoLog
+	stack   *UndoStack
+	changed bool
+}
+
+func (l *sessionUndoLog) Update(fn func(stack *UndoStack) error) error {
+	if l.stack == nil {
+		var stored UndoStack
+		err := l.log.Update(func(stack *UndoStack) error {
+			stored = *stack
+			return errKeepStack
+		})
+		if err != nil && !errors.Is(err, errKeepStack) {
+			return err
+		}
+		l.stack = &stored
+	}
+	stack := UndoStack{slices.Clone(l.stack.Undo), slices.Clone(l.stack.Redo)}
+	if err := fn(&stack); err != nil {
+		return err
+	}
+	*l.stack = stack
+	l.changed = true
+	return nil
+}
+
+// save replaces the stack of log with the one of the session.
+func (l *sessionUndoLog) save() error {
+	if !l.changed {
+		return nil
+	}
+	err := l.log.Update(func(stack *UndoStack) error {
+		*stack = *l.stack
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("the changes were saved but they cannot be undone: %w", err)
+	}
+	l.changed = false
+	return nil
+}
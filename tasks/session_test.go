@@ -0,0 +1,173 @@
+package tasks
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSessionSave(t *testing.T) {
+	for _, backend := range []string{BackendJSON, BackendJSONLines} {
+		t.Run(backend, func(t *testing.T) {
+			source, err := NewRepository(backend, filepath.Join(t.TempDir(), "tasks."+backend))
+			if err != nil {
+				t.Fatal(err)
+			}
+			main := NewStore(source)
+			main.AddTask("first")
+			main.AddTask("second")
+			main.AddTask("third")
+			main.DeleteTask(3)
+
+			session, err := OpenSession(source)
+			if err != nil {
+				t.Fatal(err)
+			}
+			store := NewStore(session)
+			store.UpdateTask(1, "first changed")
+			store.Purge(time.Now().Add(time.Second))
+			store.DeleteTask(2)
+			added, _ := store.AddTask("fourth")
+
+			if added.ID != 4 {
+				t.Errorf("expected the session to keep the ID sequence, received %d", added.ID)
+			}
+			if task, _ := main.GetTask(1); task.Description != "first" {
+				t.Errorf("nothing should be written before Save, received %v", task)
+			}
+			saved, err := session.Save()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if saved != 4 {
+				t.Errorf("expected 4 tasks written, received %d", saved)
+			}
+			if tasks, _ := main.Query(Query{Status: AllTasks}); taskIDs(tasks) != "1 4" {
+				t.Errorf("expected tasks 1 and 4 left, received %v", tasks)
+			}
+			if trash, _ := main.Trash(); len(trash) != 1 || trash[0].ID != 2 {
+				t.Errorf("expected task 2 trashed and 3 purged, received %v", trash)
+			}
+			if saved, _ := session.Save(); saved != 0 {
+				t.Errorf("a second Save should have nothing to write, received %d", saved)
+			}
+		})
+	}
+}
+
+func TestSessionConflict(t *testing.T) {
+	source := NewMemoryRepository()
+	main := NewStore(source)
+	main.AddTask("first")
+	main.AddTask("second")
+
+	session, err := OpenSession(source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	NewStore(session).UpdateTask(1, "from the session")
+	NewStore(session).UpdateTask(2, "from the session")
+	main.UpdateTask(2, "from elsewhere")
+
+	var conflict *ErrSessionConflict
+	if _, err := session.Save(); !errors.As(err, &conflict) || conflict.ID != 2 {
+		t.Fatalf("expected a conflict on task 2, received %v", err)
+	}
+	if task, _ := main.GetTask(1); task.Description != "first" {
+		t.Errorf("a conflict should save nothing, received %v", task)
+	}
+}
+
+func TestSessionHoldsObservers(t *testing.T) {
+	source := NewMemoryRepository()
+	main := NewStore(source)
+	main.AddTask("first")
+
+	session, err := OpenSession(source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	history, undo := NewMemoryHistory(), NewMemoryUndoLog()
+	store := NewStore(session, WithHistory(history, "ana"), WithUndo(undo, 0))
+	store.UpdateTask(1, "from the session")
+	store.AddTask("second")
+	if events, _ := history.Events(); len(events) != 0 {
+		t.Errorf("nothing should be recorded before Save, received %v", events)
+	}
+
+	main.UpdateTask(1, "from elsewhere")
+	var conflict *ErrSessionConflict
+	if _, err := session.Save(); !errors.As(err, &conflict) {
+		t.Fatalf("expected a conflict, received %v", err)
+	}
+	if events, _ := history.Events(); len(events) != 0 {
+		t.Errorf("a conflict should record no history, received %v", events)
+	}
+	if _, err := NewStore(source, WithUndo(undo, 0)).Undo(1); !errors.Is(err, ErrNothingToUndo) {
+		t.Errorf("a conflict should record nothing to undo, received %v", err)
+	}
+
+	session, _ = OpenSession(source)
+	store = NewStore(session, WithHistory(history, "ana"), WithUndo(undo, 0))
+	store.UpdateTask(1, "from the session")
+	store.AddTask("second")
+	if _, err := session.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if events, _ := history.Events(); len(events) != 2 {
+		t.Errorf("expected the update and the create recorded on Save, received %v", events)
+	}
+	if undone, err := store.Undo(1); err != nil || len(undone) != 1 || undone[0].Changes[0].Before != nil {
+		t.Errorf("expected the create undone first, received %v (%v)", undone, err)
+	}
+}
+
+func TestDiscardedSessionLeavesUndoAndArchive(t *testing.T) {
+	source, undo, archive := NewMemoryRepository(), NewMemoryUndoLog(), NewMemoryRepository()
+	main := NewStore(source, WithUndo(undo, 0), WithArchive(archive, 0))
+	main.AddTask("keep")
+	main.AddTask("throw away")
+	main.AddTask("finish")
+	main.DeleteTask(2)
+	main.UpdateTaskStatus(3, Done)
+	stackSize := func() (int, int) {
+		var undone, redone int
+		undo.Update(func(stack *UndoStack) error {
+			undone, redone = len(stack.Undo), len(stack.Redo)
+			return nil
+		})
+		return undone, redone
+	}
+
+	session, _ := OpenSession(source)
+	store := NewStore(session, WithUndo(undo, 0), WithArchive(archive, 0))
+	if _, err := store.Undo(1); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Redo(1); err != nil {
+		t.Fatalf("expected the session to redo its own undo, received %v", err)
+	}
+	if _, err := store.Purge(time.Now().Add(time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Archive(time.Now().Add(time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	if undone, redone := stackSize(); undone != 5 || redone != 0 {
+		t.Errorf("a session that is not saved should leave the undo log, received %d and %d operations", undone, redone)
+	}
+	if archived, _ := main.QueryArchive(Query{Status: AllTasks}); len(archived) != 0 {
+		t.Errorf("a session that is not saved should leave the archive, received %v", archived)
+	}
+
+	if _, err := session.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if undone, _ := stackSize(); undone != 1 {
+		t.Errorf("expected only the add of task 1 left after the purge and archive, received %d operations", undone)
+	}
+	if archived, _ := main.QueryArchive(Query{Status: AllTasks}); taskIDs(archived) != "3" {
+		t.Errorf("expected task 3 archived on Save, received %v", archived)
+	}
+}
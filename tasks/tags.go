@@ -0,0 +1,118 @@
+package tasks
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+type ErrInvalidTag struct {
+	Tag string
+}
+
+func (e *ErrInvalidTag) Error() string {
+	return fmt.Sprintf("invalid tag %q. Tags must be a single word and may not start with + or -", e.Tag)
+}
+
+// NormalizeTag lowercases tag and drops a leading + so "+Backend" and
+// "backend" are the same tag.
+func NormalizeTag(tag string) (string, error) {
+	normalized := strings.ToLower(strings.TrimPrefix(strings.TrimSpace(tag), "+"))
+	invalid := normalized == "" ||
+		strings.HasPrefix(normalized, "+") ||
+		strings.HasPrefix(normalized, "-") ||
+		strings.IndexFunc(normalized, unicode.IsSpace) != -1
+	if invalid {
+		return "", &ErrInvalidTag{tag}
+	}
+	return normalized, nil
+}
+
+// NormalizeTags normalizes every tag and returns them sorted without
+// duplicates.
+func NormalizeTags(tags []string) ([]string, error) {
+	normalized := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag, err := NormalizeTag(tag)
+		if err != nil {
+			return nil, err
+		}
+		normalized = append(normalized, tag)
+	}
+	slices.Sort(normalized)
+	return slices.Compact(normalized), nil
+}
+
+// ExtractTags splits the +tag words out of a description, as in
+// "fix login +backend".
+func ExtractTags(description string) (string, []string) {
+	words := []string{}
+	tags := []string{}
+	for _, word := range strings.Fields(description) {
+		if len(word) > 1 && strings.HasPrefix(word, "+") {
+			tags = append(tags, word)
+		} else {
+			words = append(words, word)
+		}
+	}
+	if len(tags) == 0 {
+		return description, nil
+	}
+	return strings.Join(words, " "), tags
+}
+
+func (task Task) HasTag(tag string) bool {
+	return slices.Contains(task.Tags, tag)
+}
+
+// TagCount is the number of tasks carrying a tag.
+type TagCount struct {
+	Tag   string
+	Count int
+}
+
+// TagTask adds and removes tags on a task in one change.
+func (s *Store) TagTask(id int, add []string, remove []string) (Task, error) {
+	added, addErr := NormalizeTags(add)
+	if addErr != nil {
+		return ZeroTask, addErr
+	}
+	removed, removeErr := NormalizeTags(remove)
+	if removeErr != nil {
+		return ZeroTask, removeErr
+	}
+	return s.modifyTask(id, func(task *Task) {
+		tags := slices.Concat(task.Tags, added)
+		tags = slices.DeleteFunc(tags, func(tag string) bool {
+			return slices.Contains(removed, tag)
+		})
+		slices.Sort(tags)
+		task.Tags = slices.Compact(tags)
+		setUpdatedDate(task)
+	})
+}
+
+// Tags returns every tag in use with the number of tasks carrying it, sorted
+// by tag.
+func (s *Store) Tags() ([]TagCount, error) {
+	tasks, err := s.repo.List()
+	if err != nil {
+		return nil, err
+	}
+	counts := map[string]int{}
+	for _, task := range tasks {
+		for _, tag := range task.Tags {
+			counts[tag]++
+		}
+	}
+	tags := make([]TagCount, 0, len(counts))
+	for tag, count := range counts {
+		tags = append(tags, TagCount{tag, count})
+	}
+	slices.SortFunc(tags, func(a, b TagCount) int {
+		return cmp.Compare(a.Tag, b.Tag)
+	})
+	return tags, nil
+}
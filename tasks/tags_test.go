@@ -0,0 +1,76 @@
+package tasks
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestNormalizeTags(t *testing.T) {
+	tags, err := NormalizeTags([]string{"+Backend", "urgent", "backend", "URGENT", "ui"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(tags, []string{"backend", "ui", "urgent"}) {
+		t.Errorf("expected sorted unique lowercase tags, received %v", tags)
+	}
+
+	expectedErrType := &ErrInvalidTag{}
+	for _, tag := range []string{"", "+", "two words", "-minus", "++double"} {
+		if _, err := NormalizeTag(tag); !errors.As(err, &expectedErrType) {
+			t.Errorf("NormalizeTag(%q): expected ErrInvalidTag, received %v", tag, err)
+		}
+	}
+}
+
+func TestExtractTags(t *testing.T) {
+	description, tags := ExtractTags("fix login +backend +Urgent now")
+	if description != "fix login now" || !slices.Equal(tags, []string{"+backend", "+Urgent"}) {
+		t.Errorf("unexpected split: %q %v", description, tags)
+	}
+	if description, tags := ExtractTags("1 + 1"); description != "1 + 1" || tags != nil {
+		t.Errorf("a lone + is not a tag: %q %v", description, tags)
+	}
+}
+
+func TestTags(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, store *Store) {
+		login, err := store.AddTask("fix login +backend +Urgent")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if login.Description != "fix login" || !slices.Equal(login.Tags, []string{"backend", "urgent"}) {
+			t.Errorf("inline tags should be extracted, received %+v", login)
+		}
+		if _, err := store.AddTask("+backend +ui"); !errors.Is(err, ErrNoDescriptionProvided) {
+			t.Errorf("a task with only tags has no description, received %v", err)
+		}
+		store.AddTask("style button +ui")
+
+		tagged, err := store.TagTask(login.ID, []string{"+db", "backend"}, []string{"Urgent"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(tagged.Tags, []string{"backend", "db"}) {
+			t.Errorf("expected backend and db, received %v", tagged.Tags)
+		}
+
+		counts, err := store.Tags()
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := []TagCount{{"backend", 1}, {"db", 1}, {"ui", 1}}
+		if !slices.Equal(counts, expected) {
+			t.Errorf("expected %v, received %v", expected, counts)
+		}
+
+		filtered, _ := store.Query(Query{Status: AllTasks, Tags: []string{"backend"}})
+		if ids := taskIDs(filtered); ids != "1" {
+			t.Errorf("expected task 1, received %s", ids)
+		}
+		filtered, _ = store.Query(Query{Status: AllTasks, ExcludeTags: []string{"backend"}})
+		if ids := taskIDs(filtered); ids != "2" {
+			t.Errorf("expected task 2, received %s", ids)
+		}
+	})
+}
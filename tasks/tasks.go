@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"slices"
+	"strings"
 	"time"
 )
 
@@ -18,24 +19,31 @@ const (
 
 const AllTasks = -1
 
+// StatusNameToValue returns the status called name in the current workflow,
+// or -1 if there is none.
 func StatusNameToValue(name string) TaskStatus {
-	switch name {
-	case "todo":
-		return Todo
-	case "done":
-		return Done
-	case "in-progress":
-		return InProgress
+	status, err := ParseStatus(name)
+	if err != nil {
+		return -1
 	}
-	return -1
+	return status
 }
 
 type Task struct {
 	ID          int
 	Description string
 	Status      TaskStatus
-	CreatedAt   string
-	UpdatedAt   string
+	Priority    Priority    `json:",omitzero"`
+	Due         time.Time   `json:",omitzero"`
+	Tags        []string    `json:",omitempty"`
+	Project     string      `json:",omitempty"`
+	Parent      int         `json:",omitzero"`
+	BlockedBy   []int       `json:",omitempty"`
+	Recur       *Recurrence `json:",omitempty"`
+	Series      int         `json:",omitzero"`
+	CreatedAt   time.Time
+	UpdatedAt   time.Time `json:",omitzero"`
+	DeletedAt   time.Time `json:",omitzero"`
 }
 
 var ZeroTask = Task{ID: -1, Description: ""}
@@ -49,17 +57,55 @@ func (task Task) SetID(id int) jsondatabase.Storable {
 	return task
 }
 
+// Name is the label a status is shown with. Values the workflow does not
+// define, such as those of a status removed from the config file, show as a
+// number.
+func (status TaskStatus) Name() string {
+	definition, ok := CurrentWorkflow().Definition(status)
+	if !ok {
+		return fmt.Sprintf("STATUS %d", status)
+	}
+	return definition.Label
+}
+
+// Closed reports whether the status counts as finished, like done.
+func (status TaskStatus) Closed() bool {
+	definition, _ := CurrentWorkflow().Definition(status)
+	return definition.Closed
+}
+
 func (task Task) String() string {
-	var statusName string
-	switch task.Status {
-	case Todo:
-		statusName = "TO DO"
-	case InProgress:
-		statusName = "IN PROGRESS"
-	case Done:
-		statusName = "DONE"
+	statusName := task.Status.Name()
+	changes := "created: " + RelativeTime(task.CreatedAt, time.Now())
+	if !task.UpdatedAt.IsZero() {
+		changes += " | updated: " + RelativeTime(task.UpdatedAt, time.Now())
+	}
+	if task.Trashed() {
+		changes += " | deleted: " + RelativeTime(task.DeletedAt, time.Now())
+	}
+	if !task.Due.IsZero() {
+		changes += " | due: " + DueLabel(task.Due, time.Now())
+	}
+	if task.Parent != 0 {
+		changes += fmt.Sprintf(" | parent: %d", task.Parent)
+	}
+	if task.Recur != nil {
+		changes += " | repeats: " + task.Recur.String()
+	}
+	if len(task.BlockedBy) > 0 {
+		changes += " | blocked by: " + joinIDs(task.BlockedBy)
+	}
+	if task.Project != "" {
+		changes += " | project: " + task.Project
 	}
-	return fmt.Sprintf("{ID: %d | description: %s | status: %s}", task.ID, task.Description, statusName)
+	if len(task.Tags) > 0 {
+		changes += " | tags: +" + strings.Join(task.Tags, " +")
+	}
+	description := task.Description
+	if marker := task.Priority.Marker(); marker != "" {
+		description = marker + " " + description
+	}
+	return fmt.Sprintf("{ID: %d | description: %s | status: %s | %s}", task.ID, description, statusName, changes)
 }
 
 var ErrNoDescriptionProvided = errors.New("no description provided")
@@ -69,7 +115,7 @@ type ErrTaskNotFound struct {
 }
 
 func (e *ErrTaskNotFound) Error() string {
-	return fmt.Sprintf("Task with id %d not found",e.ID)
+	return fmt.Sprintf("Task with id %d not found", e.ID)
 }
 
 type ErrInvalidStatus struct {
@@ -77,209 +123,280 @@ type ErrInvalidStatus struct {
 }
 
 func (e *ErrInvalidStatus) Error() string {
-	return fmt.Sprintf("invalid status. Value must be between %d and %d but received %d instead",Todo,Done,e.receivedStatus)
+	values := []string{}
+	for _, status := range CurrentWorkflow().Statuses {
+		values = append(values, fmt.Sprintf("%d (%s)", status.Value, status.Name))
+	}
+	return fmt.Sprintf("invalid status. Value must be one of %s but received %d instead", strings.Join(values, ", "), e.receivedStatus)
 }
 
-func validateStatus(status TaskStatus) error{
-	if (status > Done) || (status < Todo) {
+func validateStatus(status TaskStatus) error {
+	if _, ok := CurrentWorkflow().Definition(status); !ok {
 		return &ErrInvalidStatus{status}
 	}
 	return nil
 }
 
-func AddTask(description string) (Task, error) {
-	if description == "" {
-		return ZeroTask,ErrNoDescriptionProvided
+// Store implements the task operations on top of a Repository.
+type Store struct {
+	repo      Repository
+	trash     *trashRepository
+	archive   Repository
+	history   History
+	undo      *undoState
+	observers []observer
+	// session is set when the store works on a Session, which holds what
+	// the store writes elsewhere until it is saved.
+	session *Session
+}
+
+// NewStore returns a Store backed by repo. Deleted tasks go to the trash.
+func NewStore(repo Repository, opts ...StoreOption) *Store {
+	trash, ok := repo.(*trashRepository)
+	if !ok {
+		trash = &trashRepository{repo}
+	}
+	s := &Store{repo: trash, trash: trash}
+	s.session, _ = repo.(*Session)
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.session != nil && s.undo != nil {
+		log := &sessionUndoLog{log: s.undo.log}
+		s.undo.log = log
+		s.session.savers = append(s.session.savers, log.save)
 	}
+	if s.observed() {
+		s.repo = &observedRepository{trash, s.notify}
+	}
+	return s
+}
 
-	var newTask Task
-	var db jsondatabase.Database[Task]
-	db.Open()
-	defer db.Close()
+// defaultStore reads the files the CLI writes, so it migrates them like
+// NewRepository does.
+var defaultStore = NewStore(NewJSONRepository(jsondatabase.New[Task]("", jsondatabase.WithMigrations(migrations...))))
 
-	newTask.Description = description
-	newTask.CreatedAt = time.Now().String()
+func AddTask(description string) (Task, error) {
+	return defaultStore.AddTask(description)
+}
 
-	return db.Append(newTask)
+func UpdateTask(id int, description string) (Task, error) {
+	return defaultStore.UpdateTask(id, description)
 }
 
-func findTaskIndex(id int, tasks []Task) int {
-	index := -1
+func UpdateTaskStatus(id int, status TaskStatus) (Task, error) {
+	return defaultStore.UpdateTaskStatus(id, status)
+}
 
-	for i, t := range tasks {
-		if t.GetID() == id {
-			index = i
-			break
-		}
-	}
+func UpdateTaskPriority(id int, priority Priority) (Task, error) {
+	return defaultStore.UpdateTaskPriority(id, priority)
+}
 
-	return index
+func UpdateTaskDue(id int, due time.Time) (Task, error) {
+	return defaultStore.UpdateTaskDue(id, due)
 }
 
-func UpdateTask(id int, description string) (Task,error) {
-	if description == "" {
-		return ZeroTask,ErrNoDescriptionProvided
-	}
-	var db jsondatabase.Database[Task]
-	db.Open()
-	defer db.Close()
+func DeleteTask(id int) error {
+	return defaultStore.DeleteTask(id)
+}
 
-	tasks, getErr := db.GetAll()
-	if getErr != nil {
-		return ZeroTask,getErr
-	}
+func ChangeTaskStatus(id int, newStatus TaskStatus) error {
+	return defaultStore.ChangeTaskStatus(id, newStatus)
+}
 
-	taskToUpdate := findTaskIndex(id, tasks)
+func ListTasks(status int) ([]Task, error) {
+	return defaultStore.ListTasks(status)
+}
 
-	if taskToUpdate != -1 {
-		tasks[taskToUpdate].Description = description
-		setUpdatedDate(&tasks[taskToUpdate])
-		writeErr := db.WriteAll(tasks)
-		if writeErr != nil {
-			return ZeroTask,writeErr
-		}
-	} else {
-		return ZeroTask,fmt.Errorf("no task with id %d available", id)
-	}
-	return tasks[taskToUpdate],nil
+func GetTask(id int) (Task, error) {
+	return defaultStore.GetTask(id)
 }
 
-func UpdateTaskStatus(id int, status TaskStatus) (Task,error) {
-	var db jsondatabase.Database[Task]
-	if openErr := db.Open(); openErr != nil {
-		return ZeroTask,openErr
-	}
+func (s *Store) AddTask(description string) (Task, error) {
+	return s.Add(Task{Description: description})
+}
 
-	if validateErr := validateStatus(status); validateErr != nil {
-		db.Close()
-		return ZeroTask,validateErr		
+// Add stores a new task built by the caller. The ID, status and timestamps
+// are filled in by the store, and +tag words in the description are moved to
+// Tags.
+func (s *Store) Add(task Task) (Task, error) {
+	description, inlineTags := ExtractTags(task.Description)
+	if description == "" {
+		return ZeroTask, ErrNoDescriptionProvided
 	}
-
-	tasks, getErr := db.GetAll()
-	if getErr != nil {
-		db.Close()
-		return ZeroTask,getErr
+	if validateErr := validatePriority(task.Priority); validateErr != nil {
+		return ZeroTask, validateErr
 	}
-
-	taskToUpdate := findTaskIndex(id, tasks)
-
-	if taskToUpdate != -1 {
-		tasks[taskToUpdate].Status = status
-		setUpdatedDate(&tasks[taskToUpdate])
-		writeErr := db.WriteAll(tasks)
-		if writeErr != nil {
-			return ZeroTask,writeErr
+	tags, tagsErr := NormalizeTags(slices.Concat(task.Tags, inlineTags))
+	if tagsErr != nil {
+		return ZeroTask, tagsErr
+	}
+	project, projectErr := NormalizeProject(task.Project)
+	if projectErr != nil {
+		return ZeroTask, projectErr
+	}
+	task.Description = description
+	task.Tags = tags
+	task.Project = project
+
+	task.Status = Todo
+	task.CreatedAt = time.Now()
+	task.UpdatedAt = time.Time{}
+	task.Series = 0
+	if task.Recur != nil {
+		if task.Due.IsZero() {
+			task.Due = task.Recur.first(task.CreatedAt)
 		}
-	} else {
-		return ZeroTask,fmt.Errorf("no task with id %d available", id)
+		recurrence := task.Recur.anchored(task.Due)
+		task.Recur = &recurrence
 	}
-
-	return tasks[taskToUpdate],db.Close()
+	var inserted Task
+	err := s.repo.Atomic(func(repo Repository) error {
+		if task.Parent != 0 {
+			if _, parentErr := repo.Get(task.Parent); parentErr != nil {
+				return parentErr
+			}
+		}
+		if task.Recur != nil {
+			// A recurring task starts its own series.
+			id, idErr := repo.NextID()
+			if idErr != nil {
+				return idErr
+			}
+			task.Series = id
+		}
+		var insertErr error
+		inserted, insertErr = repo.Insert(task)
+		return insertErr
+	})
+	if err != nil {
+		return ZeroTask, err
+	}
+	return inserted, nil
 }
 
-func setUpdatedDate(task *Task) {
-	task.UpdatedAt = time.Now().String()
+// modifyTask applies modify to the task with the given id and saves it in a
+// single transaction.
+func (s *Store) modifyTask(id int, modify func(task *Task)) (Task, error) {
+	var modified Task
+	err := s.repo.Atomic(func(repo Repository) error {
+		task, getErr := repo.Get(id)
+		if getErr != nil {
+			return getErr
+		}
+		modify(&task)
+		modified = task
+		return repo.Update(task)
+	})
+	if err != nil {
+		return ZeroTask, err
+	}
+	return modified, nil
 }
 
-func DeleteTask(id int) error {
-	var db jsondatabase.Database[Task]
-	if openErr := db.Open(); openErr != nil {
-		return openErr
+func (s *Store) UpdateTask(id int, description string) (Task, error) {
+	if description == "" {
+		return ZeroTask, ErrNoDescriptionProvided
 	}
+	return s.modifyTask(id, func(task *Task) {
+		task.Description = description
+		setUpdatedDate(task)
+	})
+}
 
-	tasks, getErr := db.GetAll()
-	if getErr != nil {
-		return getErr
-	}
+// UpdateTaskStatus changes the status of a task. The move must be allowed by
+// the workflow, a task cannot be started or finished while a task it is
+// blocked by is open, nor marked done while it has open subtasks. Finishing
+// a recurring task adds its next instance.
+func (s *Store) UpdateTaskStatus(id int, status TaskStatus) (Task, error) {
+	return s.setStatus(id, status, false)
+}
 
-	taskToDelete := findTaskIndex(id, tasks)
+// ForceTaskStatus works like UpdateTaskStatus without any of the checks.
+func (s *Store) ForceTaskStatus(id int, status TaskStatus) (Task, error) {
+	return s.setStatus(id, status, true)
+}
 
-	if taskToDelete != -1 {
-		tasks = slices.Delete(tasks, taskToDelete, taskToDelete+1)
-		if len(tasks) == 0 {
-			clearErr := db.Clear()
-			if clearErr != nil {
-				return clearErr
+func (s *Store) setStatus(id int, status TaskStatus, force bool) (Task, error) {
+	if validateErr := validateStatus(status); validateErr != nil {
+		return ZeroTask, validateErr
+	}
+	var modified Task
+	err := s.repo.Atomic(func(repo Repository) error {
+		task, getErr := repo.Get(id)
+		if getErr != nil {
+			return getErr
+		}
+		if !force {
+			if checkErr := checkStatusChange(repo, task, status); checkErr != nil {
+				return checkErr
 			}
-		} else {
-			writeErr := db.WriteAll(tasks)
-			if writeErr != nil {
-				return writeErr
+		}
+		finishesSeries := status == Done && task.Status != Done && task.Recur != nil
+		task.Status = status
+		setUpdatedDate(&task)
+		if finishesSeries {
+			if _, insertErr := repo.Insert(nextInstance(task, time.Now())); insertErr != nil {
+				return insertErr
 			}
+			task.Recur = nil
 		}
-	} else {
-		return fmt.Errorf("no task with id %d available", id)
+		modified = task
+		return repo.Update(task)
+	})
+	if err != nil {
+		return ZeroTask, err
 	}
-	return db.Close()
+	return modified, nil
 }
 
-func ChangeTaskStatus(id int, newStatus TaskStatus) error {
-	var db jsondatabase.Database[Task]
-	if openErr := db.Open(); openErr != nil {
-		return openErr
-	}
-
-	tasks, getErr := db.GetAll()
-	if getErr != nil {
-		return getErr
+func checkStatusChange(repo Repository, task Task, status TaskStatus) error {
+	if transitionErr := checkTransition(task, status); transitionErr != nil {
+		return transitionErr
 	}
-
-	taskToUpdate := findTaskIndex(id, tasks)
-
-	if taskToUpdate != -1 {
-		tasks[taskToUpdate].Status = newStatus
-		if writErr := db.WriteAll(tasks); writErr != nil {
-			return writErr
+	if status == Done {
+		if finishErr := checkCanFinish(repo, task); finishErr != nil {
+			return finishErr
 		}
-	} else {
-		return fmt.Errorf("no task with id %d available", id)
 	}
+	if status == InProgress || status == Done {
+		return checkCanStart(repo, task)
+	}
+	return nil
+}
 
-	return db.Close()
+func setUpdatedDate(task *Task) {
+	task.UpdatedAt = time.Now()
 }
 
-func ListTasks(status int) error {
-	var db jsondatabase.Database[Task]
-	db.Open()
+// DeleteTask deletes a task without subtasks. See DeleteTaskMode for the
+// others.
+func (s *Store) DeleteTask(id int) error {
+	_, err := s.DeleteTaskMode(id, DeleteRefuse)
+	return err
+}
 
-	if validateErr := validateStatus(TaskStatus(status)); status != AllTasks && validateErr != nil {
-		db.Close()
-		return validateErr		
-	}
+func (s *Store) ChangeTaskStatus(id int, newStatus TaskStatus) error {
+	_, err := s.modifyTask(id, func(task *Task) {
+		task.Status = newStatus
+	})
+	return err
+}
 
-	tasks, getErr := db.GetAll()
-	if getErr != nil {
-		db.Close()
-		return getErr
-	}
+// ListTasks returns the tasks in a status, or every task with AllTasks.
+func (s *Store) ListTasks(status int) ([]Task, error) {
+	return s.Query(Query{Status: status})
+}
 
-	for _, task := range tasks {
-		if status == -1 {
-			fmt.Println(task)
-		} else {
-			if task.Status == TaskStatus(status) {
-				fmt.Println(task)
-			}
+// GetTask returns a task, looking in the archive for tasks that are no
+// longer in the main repository.
+func (s *Store) GetTask(id int) (Task, error) {
+	task, err := s.repo.Get(id)
+	var notFound *ErrTaskNotFound
+	if s.archive != nil && errors.As(err, &notFound) {
+		if archived, archiveErr := s.archive.Get(id); archiveErr == nil {
+			return archived, nil
 		}
 	}
-	return db.Close()
-} 
-
-func GetTask(id int) (Task, error) {
-	var db jsondatabase.Database[Task]
-	db.Open()
-	defer db.Close()
-
-	tasks,err := db.GetAll()	
-	if err != nil {
-		return ZeroTask,err
-	}
-
-	for _, task := range tasks {
-		if task.ID == id {
-			return task, nil
-		}	
-	}
-
-	return ZeroTask, &ErrTaskNotFound{id}
+	return task, err
 }
@@ -1,8 +1,13 @@
 package tasks
 
 import (
+	"backend/jsondatabase"
 	"errors"
 	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
 	"testing"
 )
 
@@ -13,133 +18,268 @@ func TestMain(m *testing.M) {
 	if err != nil {
 		panic(err)
 	}
+	os.Remove("./db.json.lock")
 
 	os.Exit(exitVal)
 }
 
-func TestAddTask(t *testing.T) {
-	expectedDesc := "task 1"
-	result, err := AddTask(expectedDesc)
-	if err != nil {
-		t.Error(err.Error())
-	}
-	if result.Description != expectedDesc {
-		t.Errorf("description should be %s, but received %s", expectedDesc, result.Description)
+// forEachBackend runs test against an empty store for every backend.
+func forEachBackend(t *testing.T, test func(t *testing.T, store *Store)) {
+	for _, backend := range Backends {
+		t.Run(backend, func(t *testing.T) {
+			repo, err := NewRepository(backend, filepath.Join(t.TempDir(), "tasks."+backend))
+			if err != nil {
+				t.Fatal(err)
+			}
+			test(t, NewStore(repo))
+		})
 	}
+}
 
-	if result.CreatedAt == "" {
-		t.Errorf("Adding a task should populate CreatedAt field")
-	}
+func TestAddTask(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, store *Store) {
+		expectedDesc := "task 1"
+		result, err := store.AddTask(expectedDesc)
+		if err != nil {
+			t.Error(err.Error())
+		}
+		if result.Description != expectedDesc {
+			t.Errorf("description should be %s, but received %s", expectedDesc, result.Description)
+		}
+
+		if result.CreatedAt.IsZero() {
+			t.Errorf("Adding a task should populate CreatedAt field")
+		}
+	})
 }
 
 func TestAddTaskEmpty(t *testing.T) {
-	task, err := AddTask("")
-	if err == nil || !errors.Is(err, ErrNoDescriptionProvided) {
-		t.Errorf("expected ErrNoDescriptionProvided error")
-	}
-	if task != ZeroTask {
-		t.Errorf("expected ZeroTask, received %s", task)
-	}
+	forEachBackend(t, func(t *testing.T, store *Store) {
+		task, err := store.AddTask("")
+		if err == nil || !errors.Is(err, ErrNoDescriptionProvided) {
+			t.Errorf("expected ErrNoDescriptionProvided error")
+		}
+		if !reflect.DeepEqual(task, ZeroTask) {
+			t.Errorf("expected ZeroTask, received %s", task)
+		}
+	})
 }
 
 func TestUpdateTask(t *testing.T) {
-	updatedText := "Updated task 1"
-	task, _ := AddTask("Test 1")
-	updatedTask, err := UpdateTask(task.ID, updatedText)
-	if err != nil {
-		t.Error(err.Error())
-	}
+	forEachBackend(t, func(t *testing.T, store *Store) {
+		updatedText := "Updated task 1"
+		task, _ := store.AddTask("Test 1")
+		updatedTask, err := store.UpdateTask(task.ID, updatedText)
+		if err != nil {
+			t.Error(err.Error())
+		}
 
-	if updatedTask.Description != updatedText {
-		t.Errorf("description doesnt match. expected %s, received %s", updatedText, updatedTask.Description)
-	}
+		if updatedTask.Description != updatedText {
+			t.Errorf("description doesnt match. expected %s, received %s", updatedText, updatedTask.Description)
+		}
 
-	taskToCompare, err := GetTask(task.ID)
-	if err != nil {
-		t.Error(err.Error())
-	}
-	if taskToCompare.Description != updatedText {
-		t.Errorf("description doesnt match. expected %s, received %s", updatedText, taskToCompare.Description)
-	}
+		taskToCompare, err := store.GetTask(task.ID)
+		if err != nil {
+			t.Error(err.Error())
+		}
+		if taskToCompare.Description != updatedText {
+			t.Errorf("description doesnt match. expected %s, received %s", updatedText, taskToCompare.Description)
+		}
+	})
 }
 
 func TestUpdateTaskEmpty(t *testing.T) {
-	AddTask("Test task")
+	forEachBackend(t, func(t *testing.T, store *Store) {
+		store.AddTask("Test task")
 
-	task, err := UpdateTask(1, "")
-	if err == nil || !errors.Is(err, ErrNoDescriptionProvided) {
-		t.Errorf("expected ErrNoDescriptionProvided error")
-	}
-	if task != ZeroTask {
-		t.Errorf("expected ZeroTask, received %s", task)
-	}
+		task, err := store.UpdateTask(1, "")
+		if err == nil || !errors.Is(err, ErrNoDescriptionProvided) {
+			t.Errorf("expected ErrNoDescriptionProvided error")
+		}
+		if !reflect.DeepEqual(task, ZeroTask) {
+			t.Errorf("expected ZeroTask, received %s", task)
+		}
+	})
 }
 
 func TestUpdateNonExistingTask(t *testing.T) {
-	_, err := UpdateTask(10, "")
-	if err == nil {
-		t.Errorf("expected error when updating non existing task")
-	}
+	forEachBackend(t, func(t *testing.T, store *Store) {
+		_, err := store.UpdateTask(10, "")
+		if err == nil {
+			t.Errorf("expected error when updating non existing task")
+		}
+	})
 }
 
 func TestUpdateTaskStatus(t *testing.T) {
-	updatedStatus := InProgress
-	task, _ := AddTask("Test 1")
-	updatedTask, err := UpdateTaskStatus(task.ID, updatedStatus)
+	forEachBackend(t, func(t *testing.T, store *Store) {
+		updatedStatus := InProgress
+		task, _ := store.AddTask("Test 1")
+		updatedTask, err := store.UpdateTaskStatus(task.ID, updatedStatus)
+		if err != nil {
+			t.Error(err.Error())
+		}
+
+		if updatedTask.Status != updatedStatus {
+			t.Errorf("status doesnt match. expected %d, received %d", updatedStatus, updatedTask.Status)
+		}
+
+		taskToCompare, err := store.GetTask(task.ID)
+		if err != nil {
+			t.Error(err.Error())
+		}
+		if taskToCompare.Status != updatedStatus {
+			t.Errorf("status doesnt match. expected %d, received %d", updatedStatus, taskToCompare.Status)
+		}
+	})
+}
+
+func TestUpdateTaskStatusWrongStatus(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, store *Store) {
+		expectedErrType := &ErrInvalidStatus{}
+		task, _ := store.AddTask("test task")
+		wrongStatus := TaskStatus(10)
+		_, err := store.UpdateTaskStatus(task.ID, wrongStatus)
+
+		if err == nil || !errors.As(err, &expectedErrType) {
+			t.Errorf("expected ErrInvalidStatus, received %s", err)
+		}
+	})
+}
+
+func TestListTasks(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, store *Store) {
+		_, err := store.ListTasks(AllTasks)
+		if err != nil {
+			t.Error(err)
+		}
+
+		_, err = store.ListTasks(int(Todo))
+		if err != nil {
+			t.Error(err)
+		}
+
+		_, err = store.ListTasks(int(InProgress))
+		if err != nil {
+			t.Error(err)
+		}
+
+		_, err = store.ListTasks(int(Done))
+		if err != nil {
+			t.Error(err)
+		}
+	})
+}
+
+func TestListTaskInvalidStatus(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, store *Store) {
+		expectedErrType := &ErrInvalidStatus{}
+		_, err := store.ListTasks(10)
+		if err == nil || !errors.As(err, &expectedErrType) {
+			t.Errorf("expected ErrInvalidStatus, received %s", err)
+		}
+	})
+}
+
+func TestStoreUsesCustomPath(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "tasks.json")
+	store := NewStore(NewJSONRepository(jsondatabase.New[Task](path)))
+
+	task, err := store.AddTask("stored elsewhere")
 	if err != nil {
-		t.Error(err.Error())
+		t.Fatal(err)
 	}
-
-	if updatedTask.Status != updatedStatus {
-		t.Errorf("status doesnt match. expected %d, received %d", updatedStatus, updatedTask.Status)
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("expected database file at %s: %s", path, err)
 	}
 
-	taskToCompare, err := GetTask(task.ID)
+	got, err := store.GetTask(task.ID)
 	if err != nil {
-		t.Error(err.Error())
+		t.Fatal(err)
 	}
-	if taskToCompare.Status != updatedStatus {
-		t.Errorf("status doesnt match. expected %d, received %d", updatedStatus, taskToCompare.Status)
+	if got.Description != "stored elsewhere" {
+		t.Errorf("expected description %q, received %q", "stored elsewhere", got.Description)
 	}
 }
 
-func TestUpdateTaskStatusWrongStatus(t *testing.T) {
-	expectedErrType := &ErrInvalidStatus{}
-	task, _ := AddTask("test task")
-	wrongStatus := TaskStatus(10)
-	_,err := UpdateTaskStatus(task.ID,wrongStatus)
-	
-	if err == nil || !errors.As(err, &expectedErrType) {
-		t.Errorf("expected ErrInvalidStatus, received %s",err)
-	}
+func TestDeleteNonExistingTask(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, store *Store) {
+		expectedErrType := &ErrTaskNotFound{}
+		err := store.DeleteTask(1000)
+		if err == nil || !errors.As(err, &expectedErrType) {
+			t.Errorf("expected ErrTaskNotFound, received %s", err)
+		}
+		if expectedErrType.ID != 1000 {
+			t.Errorf("expected error for id 1000, received %d", expectedErrType.ID)
+		}
+	})
 }
 
-func TestListTasks(t *testing.T) {
-	err := ListTasks(AllTasks)
+func TestDeleteTask(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, store *Store) {
+		task, _ := store.AddTask("to delete")
+		if err := store.DeleteTask(task.ID); err != nil {
+			t.Fatal(err)
+		}
+		expectedErrType := &ErrTaskNotFound{}
+		if _, err := store.GetTask(task.ID); !errors.As(err, &expectedErrType) {
+			t.Errorf("expected ErrTaskNotFound after delete, received %s", err)
+		}
+	})
+}
+
+func TestPackageFunctionsUseDefaultStore(t *testing.T) {
+	task, err := AddTask("default store")
 	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat("./db.json"); err != nil {
+		t.Errorf("expected default database at ./db.json: %s", err)
+	}
+	if _, err := UpdateTaskStatus(task.ID, Done); err != nil {
 		t.Error(err)
 	}
-	
-	err = ListTasks(int(Todo))
-	if err != nil {
+	if done, err := ListTasks(int(Done)); err != nil || len(done) == 0 {
+		t.Errorf("expected the done task listed, received %v %v", done, err)
+	}
+	if err := DeleteTask(task.ID); err != nil {
 		t.Error(err)
 	}
+}
 
-	err = ListTasks(int(InProgress))
-	if err != nil {
-		t.Error(err)
+func TestDefaultStoreReadsMigratedFiles(t *testing.T) {
+	t.Cleanup(func() { os.Remove("./db.json.v0.bak") })
+
+	legacy := `[{"ID":1,"Description":"legacy","Status":0,"CreatedAt":"2025-03-14 09:26:53.589793 +0100 CET m=+0.000150541","UpdatedAt":""}]`
+	if err := os.WriteFile("./db.json", []byte(legacy), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if task, err := GetTask(1); err != nil || task.Description != "legacy" {
+		t.Errorf("expected the legacy task read, received %v %v", task, err)
 	}
 
-	err = ListTasks(int(Done))
-	if err != nil {
-		t.Error(err)
+	os.Remove("./db.json")
+	repo, _ := NewRepository(BackendJSON, "./db.json")
+	if _, err := NewStore(repo).AddTask("current"); err != nil {
+		t.Fatal(err)
+	}
+	if task, err := GetTask(1); err != nil || task.Description != "current" {
+		t.Errorf("expected the task of a current file read, received %v %v", task, err)
 	}
 }
 
-func TestListTaskInvalidStatus(t *testing.T) {
-	expectedErrType := &ErrInvalidStatus{}
-	err := ListTasks(10)
+func TestUnknownBackend(t *testing.T) {
+	expectedErrType := &ErrUnknownBackend{}
+	_, err := NewRepository("sqlite", "tasks.db")
 	if err == nil || !errors.As(err, &expectedErrType) {
-		t.Errorf("expected ErrInvalidStatus, received %s",err)
+		t.Errorf("expected ErrUnknownBackend, received %s", err)
 	}
-}
\ No newline at end of file
+}
+
+func taskIDs(tasks []Task) string {
+	ids := make([]string, len(tasks))
+	for i, task := range tasks {
+		ids[i] = strconv.Itoa(task.ID)
+	}
+	return strings.Join(ids, " ")
+}
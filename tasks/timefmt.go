@@ -0,0 +1,73 @@
+package tasks
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RelativeTime describes t relative to now, such as "3h ago" or "in 2d". Times
+// more than a week away are shown as a date in the local time zone.
+func RelativeTime(t, now time.Time) string {
+	if t.IsZero() {
+		return "never"
+	}
+	diff := now.Sub(t)
+	format := "%s ago"
+	if diff < 0 {
+		diff = -diff
+		format = "in %s"
+	}
+
+	var amount string
+	switch {
+	case diff < time.Minute:
+		return "just now"
+	case diff < time.Hour:
+		amount = fmt.Sprintf("%dm", int(diff/time.Minute))
+	case diff < 24*time.Hour:
+		amount = fmt.Sprintf("%dh", int(diff/time.Hour))
+	case diff < 7*24*time.Hour:
+		amount = fmt.Sprintf("%dd", int(diff/(24*time.Hour)))
+	default:
+		return t.Local().Format("2006-01-02")
+	}
+	return fmt.Sprintf(format, amount)
+}
+
+// LocalTime formats t in the local time zone.
+func LocalTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Local().Format("2006-01-02 15:04")
+}
+
+type ErrInvalidAge struct {
+	Value string
+}
+
+func (e *ErrInvalidAge) Error() string {
+	return fmt.Sprintf("invalid age %q. Expected a number followed by m, h, d or w, like 30m or 2d", e.Value)
+}
+
+// ParseAge reads durations like "90m", "12h", "2d" or "1w". Go duration
+// strings such as "1h30m" are accepted too.
+func ParseAge(value string) (time.Duration, error) {
+	units := map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour}
+	for suffix, unit := range units {
+		if number, ok := strings.CutSuffix(value, suffix); ok {
+			count, err := strconv.Atoi(number)
+			if err != nil || count < 0 {
+				return 0, &ErrInvalidAge{value}
+			}
+			return time.Duration(count) * unit, nil
+		}
+	}
+	age, err := time.ParseDuration(value)
+	if err != nil || age < 0 {
+		return 0, &ErrInvalidAge{value}
+	}
+	return age, nil
+}
@@ -0,0 +1,54 @@
+package tasks
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRelativeTime(t *testing.T) {
+	now := time.Date(2026, 5, 20, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		t    time.Time
+		want string
+	}{
+		{now.Add(-20 * time.Second), "just now"},
+		{now.Add(-5 * time.Minute), "5m ago"},
+		{now.Add(-3*time.Hour - 10*time.Minute), "3h ago"},
+		{now.Add(-50 * time.Hour), "2d ago"},
+		{now.Add(26 * time.Hour), "in 1d"},
+		{time.Time{}, "never"},
+	}
+	for _, test := range tests {
+		if got := RelativeTime(test.t, now); got != test.want {
+			t.Errorf("RelativeTime(%s): expected %q, received %q", test.t, test.want, got)
+		}
+	}
+
+	old := now.Add(-30 * 24 * time.Hour)
+	if got := RelativeTime(old, now); got != old.Local().Format("2006-01-02") {
+		t.Errorf("old times should show a date, received %q", got)
+	}
+}
+
+func TestParseAge(t *testing.T) {
+	tests := map[string]time.Duration{
+		"2d":    48 * time.Hour,
+		"1w":    7 * 24 * time.Hour,
+		"90m":   90 * time.Minute,
+		"1h30m": 90 * time.Minute,
+	}
+	for value, want := range tests {
+		got, err := ParseAge(value)
+		if err != nil || got != want {
+			t.Errorf("ParseAge(%q): expected %s, received %s (%v)", value, want, got, err)
+		}
+	}
+
+	expectedErrType := &ErrInvalidAge{}
+	for _, value := range []string{"", "d", "-2d", "soon"} {
+		if _, err := ParseAge(value); !errors.As(err, &expectedErrType) {
+			t.Errorf("ParseAge(%q): expected ErrInvalidAge, received %v", value, err)
+		}
+	}
+}
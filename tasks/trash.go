@@ -0,0 +1,168 @@
+package tasks
+
+import (
+	"backend/jsondatabase"
+	"slices"
+	"time"
+)
+
+// trashRepository moves deleted tasks to the trash instead of removing them.
+// Trashed tasks keep their ID and are stored with DeletedAt set; Get and List
+// leave them out, so the rest of the store never sees them.
+type trashRepository struct {
+	Repository
+}
+
+func (r *trashRepository) Atomic(fn func(repo Repository) error) error {
+	return r.Repository.Atomic(func(repo Repository) error {
+		return fn(&trashRepository{repo})
+	})
+}
+
+func (r *trashRepository) Get(id int) (Task, error) {
+	task, err := r.Repository.Get(id)
+	if err != nil {
+		return ZeroTask, err
+	}
+	if !task.DeletedAt.IsZero() {
+		return ZeroTask, &ErrTaskNotFound{id}
+	}
+	return task, nil
+}
+
+func (r *trashRepository) List() ([]Task, error) {
+	tasks, err := r.Repository.List()
+	return slices.DeleteFunc(tasks, Task.Trashed), err
+}
+
+// inTransaction runs fn with the unfiltered repository of a transaction
+// and a view of it that hides the trash.
+func (r *trashRepository) inTransaction(fn func(repo, visible Repository) error) error {
+	return r.Repository.Atomic(func(repo Repository) error {
+		return fn(repo, &trashRepository{repo})
+	})
+}
+
+func (r *trashRepository) Update(task Task) error {
+	return r.inTransaction(func(repo, visible Repository) error {
+		if _, getErr := visible.Get(task.ID); getErr != nil {
+			return getErr
+		}
+		return repo.Update(task)
+	})
+}
+
+func (r *trashRepository) Delete(id int) error {
+	return r.inTransaction(func(repo, visible Repository) error {
+		task, getErr := visible.Get(id)
+		if getErr != nil {
+			return getErr
+		}
+		task.DeletedAt = time.Now()
+		return repo.Update(task)
+	})
+}
+
+// unfiltered returns the repository repo reads from, under the trash and any
+// recording, where trashed tasks are found too.
+func unfiltered(repo Repository) Repository {
+	switch r := repo.(type) {
+	case *recordingRepository:
+		return unfiltered(r.Repository)
+	case *trashRepository:
+		return r.Repository
+	}
+	return repo
+}
+
+func (r *trashRepository) Migrate(dryRun bool) (*jsondatabase.MigrationReport, error) {
+	return migrateRepository(r.Repository, dryRun)
+}
+
+// Trashed reports whether the task is in the trash.
+func (task Task) Trashed() bool {
+	return !task.DeletedAt.IsZero()
+}
+
+// Trash returns the tasks in the trash, most recently deleted first.
+func (s *Store) Trash() ([]Task, error) {
+	all, err := s.trash.Repository.List()
+	if err != nil {
+		return nil, err
+	}
+	trashed := slices.DeleteFunc(all, func(task Task) bool {
+		return !task.Trashed()
+	})
+	slices.SortStableFunc(trashed, func(a, b Task) int {
+		return b.DeletedAt.Compare(a.DeletedAt)
+	})
+	return trashed, nil
+}
+
+// RestoreTask takes a task out of the trash with its original ID. A parent or
+// blockers that are no longer around are dropped.
+func (s *Store) RestoreTask(id int) (Task, error) {
+	task, err := s.trash.Repository.Get(id)
+	if err != nil {
+		return ZeroTask, err
+	}
+	if !task.Trashed() {
+		return ZeroTask, &ErrTaskNotFound{id}
+	}
+	task.DeletedAt = time.Time{}
+	setUpdatedDate(&task)
+
+	err = s.repo.Atomic(func(repo Repository) error {
+		if task.Parent != 0 {
+			if _, parentErr := repo.Get(task.Parent); parentErr != nil {
+				task.Parent = 0
+			}
+		}
+		task.BlockedBy = slices.DeleteFunc(slices.Clone(task.BlockedBy), func(blocker int) bool {
+			_, blockerErr := repo.Get(blocker)
+			return blockerErr != nil
+		})
+		return repo.Restore(task)
+	})
+	if err != nil {
+		return ZeroTask, err
+	}
+	return task, nil
+}
+
+// Purge removes for good the tasks that were put in the trash before a time,
+// and returns their IDs. Operations on them can no longer be undone.
+func (s *Store) Purge(before time.Time) ([]int, error) {
+	var purged []int
+	// The deletes go under the trash, but through the observers so they are
+	// recorded like any other.
+	var repo Repository = s.trash.Repository
+	if s.observed() {
+		repo = &observedRepository{repo, s.notify}
+	}
+	err := repo.Atomic(func(repo Repository) error {
+		all, listErr := repo.List()
+		if listErr != nil {
+			return listErr
+		}
+		for _, task := range all {
+			if !task.Trashed() || !task.DeletedAt.Before(before) {
+				continue
+			}
+			if deleteErr := repo.Delete(task.ID); deleteErr != nil {
+				return deleteErr
+			}
+			purged = append(purged, task.ID)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if s.undo != nil && len(purged) > 0 {
+		if forgetErr := s.undo.forget(purged); forgetErr != nil {
+			return purged, forgetErr
+		}
+	}
+	return purged, nil
+}
@@ -0,0 +1,106 @@
+package tasks
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestDeleteMovesToTrash(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, store *Store) {
+		store.AddTask("keep")
+		store.AddTask("throw away")
+		if err := store.DeleteTask(2); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := store.GetTask(2); err == nil {
+			t.Error("a trashed task should not be found")
+		}
+		if tasks, _ := store.Query(Query{Status: AllTasks}); taskIDs(tasks) != "1" {
+			t.Errorf("expected only task 1 listed, received %v", tasks)
+		}
+		trash, err := store.Trash()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(trash) != 1 || trash[0].ID != 2 || !trash[0].Trashed() {
+			t.Errorf("expected task 2 in the trash, received %v", trash)
+		}
+		if err := store.DeleteTask(2); err == nil {
+			t.Error("deleting a trashed task again should fail")
+		}
+		if _, err := store.UpdateTask(2, "changed"); err == nil {
+			t.Error("updating a trashed task should fail")
+		}
+	})
+}
+
+func TestRestoreTask(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, store *Store) {
+		addTree(t, store)
+		store.DeleteTaskMode(1, DeleteCascade)
+
+		restored, err := store.RestoreTask(3)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if restored.ID != 3 || restored.Trashed() || restored.Parent != 0 {
+			t.Errorf("expected task 3 back at the top level, received %v", restored)
+		}
+		if task, err := store.GetTask(3); err != nil || task.Description != "build" {
+			t.Errorf("expected task 3 found again, received %v %v", task, err)
+		}
+		if _, err := store.RestoreTask(3); err == nil {
+			t.Error("restoring a task that is not in the trash should fail")
+		}
+		if task, _ := store.AddTask("new"); task.ID != 6 {
+			t.Errorf("trashed IDs should not be handed out again, received %d", task.ID)
+		}
+	})
+}
+
+func TestRestoreKeepsRecordedChanges(t *testing.T) {
+	forEachHistory(t, func(t *testing.T, store *Store) {
+		store.AddTask("build")
+		store.AddTask("test")
+		store.Block(2, 1)
+		store.DeleteTask(2)
+		store.DeleteTask(1)
+		if _, err := store.RestoreTask(2); err != nil {
+			t.Fatal(err)
+		}
+
+		events, _ := store.History(2)
+		last := events[len(events)-1]
+		if last.Field != "BlockedBy" || string(last.Old) != "[1]" {
+			t.Errorf("expected the dropped blocker recorded, received %s", last)
+		}
+	})
+}
+
+func TestPurge(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, store *Store) {
+		store.AddTask("first")
+		store.AddTask("second")
+		store.DeleteTask(1)
+		store.DeleteTask(2)
+
+		if purged, _ := store.Purge(time.Now().Add(-time.Hour)); len(purged) != 0 {
+			t.Errorf("nothing was deleted an hour ago, received %v", purged)
+		}
+		purged, err := store.Purge(time.Now().Add(time.Second))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(purged, []int{1, 2}) {
+			t.Errorf("expected tasks 1 and 2 purged, received %v", purged)
+		}
+		if trash, _ := store.Trash(); len(trash) != 0 {
+			t.Errorf("expected an empty trash, received %v", trash)
+		}
+		if _, err := store.RestoreTask(1); err == nil {
+			t.Error("a purged task cannot be restored")
+		}
+	})
+}
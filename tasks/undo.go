@@ -0,0 +1,347 @@
+package tasks
+
+import (
+	"backend/jsondatabase"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	StackUndo = "undo"
+	StackRedo = "redo"
+)
+
+const DefaultUndoDepth = 20
+
+// Operation is one saved change of the store, such as adding a task or
+// deleting a task with its subtasks, kept so it can be undone.
+type Operation struct {
+	ID      int          `json:"id"`
+	Stack   string       `json:"stack"`
+	Time    time.Time    `json:"time"`
+	Changes []TaskChange `json:"changes"`
+}
+
+func (o Operation) GetID() int {
+	return o.ID
+}
+
+func (o Operation) SetID(id int) jsondatabase.Storable {
+	o.ID = id
+	return o
+}
+
+// Summary describes the changes of the operation, as in "created task 3,
+// updated task 1 (Status)".
+func (o Operation) Summary() string {
+	parts := []string{}
+	for _, change := range o.Changes {
+		switch {
+		case change.Before == nil:
+			parts = append(parts, fmt.Sprintf("created task %d", change.After.ID))
+		case change.After == nil || (change.After.Trashed() && !change.Before.Trashed()):
+			parts = append(parts, fmt.Sprintf("deleted task %d", change.Before.ID))
+		case change.Before.Trashed() && !change.After.Trashed():
+			parts = append(parts, fmt.Sprintf("restored task %d", change.After.ID))
+		default:
+			fields := []string{}
+			events, _ := diffTasks(*change.Before, *change.After)
+			for _, event := range events {
+				fields = append(fields, event.Field)
+			}
+			part := fmt.Sprintf("updated task %d", change.After.ID)
+			if len(fields) > 0 {
+				part += " (" + strings.Join(fields, ", ") + ")"
+			}
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// UndoStack holds the operations that can be undone and redone, oldest
+// first.
+type UndoStack struct {
+	Undo []Operation
+	Redo []Operation
+}
+
+// UndoLog stores the undo stack of a Store between invocations.
+type UndoLog interface {
+	// Update runs fn on the stored stack and saves it if fn returns nil.
+	Update(fn func(stack *UndoStack) error) error
+}
+
+var (
+	ErrNoUndo        = errors.New("this store does not record undo")
+	ErrNothingToUndo = errors.New("nothing to undo")
+	ErrNothingToRedo = errors.New("nothing to redo")
+)
+
+// ErrUndoConflict is returned when a task was changed outside the store after
+// the operation being undone or redone, so reverting it would lose that
+// change.
+type ErrUndoConflict struct {
+	ID int
+}
+
+func (e *ErrUndoConflict) Error() string {
+	return fmt.Sprintf("task %d was changed since, it cannot be reverted", e.ID)
+}
+
+// FileUndoLog keeps the undo stack in a JSON file.
+type FileUndoLog struct {
+	db *jsondatabase.Database[Operation]
+}
+
+func NewFileUndoLog(path string) *FileUndoLog {
+	return &FileUndoLog{jsondatabase.New[Operation](path)}
+}
+
+func (l *FileUndoLog) Update(fn func(stack *UndoStack) error) error {
+	return l.db.Update(func(tx *jsondatabase.Tx[Operation]) error {
+		var stack UndoStack
+		for _, operation := range tx.All() {
+			if operation.Stack == StackRedo {
+				stack.Redo = append(stack.Redo, operation)
+			} else {
+				stack.Undo = append(stack.Undo, operation)
+			}
+		}
+		if err := fn(&stack); err != nil {
+			return err
+		}
+
+		for _, operation := range tx.All() {
+			if err := tx.Delete(operation.ID); err != nil {
+				return err
+			}
+		}
+		if err := insertOperations(tx, StackUndo, stack.Undo); err != nil {
+			return err
+		}
+		return insertOperations(tx, StackRedo, stack.Redo)
+	})
+}
+
+func insertOperations(tx *jsondatabase.Tx[Operation], stackName string, operations []Operation) error {
+	for _, operation := range operations {
+		operation.Stack = stackName
+		if _, err := tx.Insert(operation); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MemoryUndoLog keeps the undo stack in memory, for the memory backend and
+// tests.
+type MemoryUndoLog struct {
+	mu    sync.Mutex
+	stack UndoStack
+}
+
+func NewMemoryUndoLog() *MemoryUndoLog {
+	return &MemoryUndoLog{}
+}
+
+func (l *MemoryUndoLog) Update(fn func(stack *UndoStack) error) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	stack := UndoStack{slices.Clone(l.stack.Undo), slices.Clone(l.stack.Redo)}
+	if err := fn(&stack); err != nil {
+		return err
+	}
+	l.stack = stack
+	return nil
+}
+
+type undoState struct {
+	log   UndoLog
+	depth int
+	// replaying is set while Undo and Redo apply an operation, so that it is
+	// not recorded as a new one.
+	replaying bool
+}
+
+// WithUndo records every change the store makes in log, keeping the last
+// depth operations. A depth of zero or less keeps DefaultUndoDepth.
+func WithUndo(log UndoLog, depth int) StoreOption {
+	if depth <= 0 {
+		depth = DefaultUndoDepth
+	}
+	return func(s *Store) {
+		s.undo = &undoState{log: log, depth: depth}
+	}
+}
+
+// record pushes changes as a new operation. A new operation makes the undone
+// ones impossible to redo. Archiving cannot be undone, the archive being out
+// of reach of the store's repository.
+func (u *undoState) record(changes []TaskChange) error {
+	changes = slices.DeleteFunc(slices.Clone(changes), func(change TaskChange) bool {
+		return change.Archived
+	})
+	if u.replaying || len(changes) == 0 {
+		return nil
+	}
+	err := u.log.Update(func(stack *UndoStack) error {
+		stack.Undo = append(stack.Undo, Operation{Stack: StackUndo, Time: time.Now(), Changes: changes})
+		stack.Undo = stack.Undo[max(0, len(stack.Undo)-u.depth):]
+		stack.Redo = nil
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("the change was saved but it cannot be undone: %w", err)
+	}
+	return nil
+}
+
+// forget drops the operations that change any of ids, so that tasks purged
+// for good cannot come back through undo.
+func (u *undoState) forget(ids []int) error {
+	return u.log.Update(func(stack *UndoStack) error {
+		touches := func(operation Operation) bool {
+			return slices.ContainsFunc(operation.Changes, func(change TaskChange) bool {
+				return slices.Contains(ids, changeTaskID(change))
+			})
+		}
+		stack.Undo = slices.DeleteFunc(stack.Undo, touches)
+		stack.Redo = slices.DeleteFunc(stack.Redo, touches)
+		return nil
+	})
+}
+
+// Undo reverts the last n operations, most recent first, and returns them.
+// It stops at the first operation that cannot be reverted, returning the ones
+// reverted before it with the error.
+func (s *Store) Undo(n int) ([]Operation, error) {
+	return s.replay(n, false)
+}
+
+// Redo applies again the last n undone operations and returns them.
+func (s *Store) Redo(n int) ([]Operation, error) {
+	return s.replay(n, true)
+}
+
+func (s *Store) replay(n int, redo bool) ([]Operation, error) {
+	if s.undo == nil {
+		return nil, ErrNoUndo
+	}
+	s.undo.replaying = true
+	defer func() { s.undo.replaying = false }()
+
+	var replayed []Operation
+	var stopErr error
+	err := s.undo.log.Update(func(stack *UndoStack) error {
+		from, to, empty := &stack.Undo, &stack.Redo, ErrNothingToUndo
+		if redo {
+			from, to, empty = &stack.Redo, &stack.Undo, ErrNothingToRedo
+		}
+		if len(*from) == 0 {
+			return empty
+		}
+		for range n {
+			if len(*from) == 0 {
+				break
+			}
+			operation := (*from)[len(*from)-1]
+			if err := s.repo.Atomic(func(repo Repository) error {
+				return applyOperation(repo, operation, redo)
+			}); err != nil {
+				if len(replayed) == 0 {
+					return err
+				}
+				stopErr = err
+				break
+			}
+			*from = (*from)[:len(*from)-1]
+			*to = append(*to, operation)
+			replayed = append(replayed, operation)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return replayed, stopErr
+}
+
+// applyOperation reverts operation, or applies it again when forward is set.
+func applyOperation(repo Repository, operation Operation, forward bool) error {
+	changes := slices.Clone(operation.Changes)
+	if !forward {
+		slices.Reverse(changes)
+	}
+	for _, change := range changes {
+		current, target := change.After, change.Before
+		if forward {
+			current, target = change.Before, change.After
+		}
+		id := changeTaskID(change)
+		if conflictErr := checkUnchanged(repo, id, current); conflictErr != nil {
+			return conflictErr
+		}
+		if target == nil {
+			if err := repo.Delete(id); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := repo.Restore(*target); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func changeTaskID(change TaskChange) int {
+	if change.After != nil {
+		return change.After.ID
+	}
+	return change.Before.ID
+}
+
+// checkUnchanged returns ErrUndoConflict unless the stored task with the
+// given id is expected, or is missing when expected is nil.
+func checkUnchanged(repo Repository, id int, expected *Task) error {
+	same, err := storedAs(repo, id, expected)
+	if err != nil {
+		return err
+	}
+	if !same {
+		return &ErrUndoConflict{id}
+	}
+	return nil
+}
+
+// storedAs reports whether the stored task with the given id is expected, or
+// is missing or in the trash when expected is nil. Trashed tasks are looked
+// up too, so a change restoring or purging one can be checked.
+func storedAs(repo Repository, id int, expected *Task) (bool, error) {
+	stored, getErr := unfiltered(repo).Get(id)
+	var notFound *ErrTaskNotFound
+	if errors.As(getErr, &notFound) {
+		return expected == nil, nil
+	}
+	if getErr != nil {
+		return false, getErr
+	}
+	if expected == nil {
+		return stored.Trashed(), nil
+	}
+	return sameTask(stored, *expected), nil
+}
+
+// sameTask compares tasks by their stored form, so times that only differ in
+// their monotonic clock reading are equal.
+func sameTask(a, b Task) bool {
+	aJSON, _ := json.Marshal(a)
+	bJSON, _ := json.Marshal(b)
+	return string(aJSON) == string(bJSON)
+}
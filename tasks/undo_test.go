@@ -0,0 +1,195 @@
+package tasks
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// forEachUndo runs test with every backend recording into an undo file that
+// keeps depth operations.
+func forEachUndo(t *testing.T, depth int, test func(t *testing.T, store *Store)) {
+	forEachBackend(t, func(t *testing.T, store *Store) {
+		log := NewFileUndoLog(filepath.Join(t.TempDir(), "undo.json"))
+		test(t, NewStore(store.repo, WithUndo(log, depth)))
+	})
+}
+
+func TestUndoRedo(t *testing.T) {
+	forEachUndo(t, 0, func(t *testing.T, store *Store) {
+		store.AddTask("write docs")
+		store.UpdateTask(1, "write the docs")
+		store.UpdateTaskStatus(1, InProgress)
+
+		undone, err := store.Undo(2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(undone) != 2 || undone[0].Summary() != "updated task 1 (Status)" || undone[1].Summary() != "updated task 1 (Description)" {
+			t.Errorf("expected the status and description changes undone, received %v", undone)
+		}
+		task, _ := store.GetTask(1)
+		if task.Description != "write docs" || task.Status != Todo {
+			t.Errorf("expected the task as it was added, received %v", task)
+		}
+
+		if _, err := store.Redo(1); err != nil {
+			t.Fatal(err)
+		}
+		if task, _ := store.GetTask(1); task.Description != "write the docs" || task.Status != Todo {
+			t.Errorf("expected the description change redone, received %v", task)
+		}
+	})
+}
+
+func TestUndoDelete(t *testing.T) {
+	forEachUndo(t, 0, func(t *testing.T, store *Store) {
+		addTree(t, store)
+		if _, err := store.DeleteTaskMode(1, DeleteCascade); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := store.Undo(1); err != nil {
+			t.Fatal(err)
+		}
+		tasks, _ := store.Query(Query{Status: AllTasks})
+		if len(tasks) != 5 {
+			t.Errorf("expected the whole tree back, received %v", tasks)
+		}
+
+		if _, err := store.Redo(1); err != nil {
+			t.Fatal(err)
+		}
+		if tasks, _ := store.Query(Query{Status: AllTasks}); len(tasks) != 1 {
+			t.Errorf("expected the tree deleted again, received %v", tasks)
+		}
+	})
+}
+
+func TestUndoAdd(t *testing.T) {
+	forEachUndo(t, 0, func(t *testing.T, store *Store) {
+		store.AddTask("first")
+		store.AddTask("second")
+		store.Undo(1)
+		if _, err := store.GetTask(2); err == nil {
+			t.Error("undoing an add should delete the task")
+		}
+		store.Redo(1)
+		if task, err := store.GetTask(2); err != nil || task.Description != "second" {
+			t.Errorf("redoing an add should bring the task back with its ID, received %v %v", task, err)
+		}
+	})
+}
+
+func TestUndoEmptyStack(t *testing.T) {
+	forEachUndo(t, 0, func(t *testing.T, store *Store) {
+		if _, err := store.Undo(1); !errors.Is(err, ErrNothingToUndo) {
+			t.Errorf("expected ErrNothingToUndo, received %v", err)
+		}
+		store.AddTask("first")
+		undone, err := store.Undo(5)
+		if err != nil || len(undone) != 1 {
+			t.Errorf("expected the only operation undone, received %v %v", undone, err)
+		}
+		if _, err := store.Redo(1); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := store.Redo(1); !errors.Is(err, ErrNothingToRedo) {
+			t.Errorf("expected ErrNothingToRedo, received %v", err)
+		}
+	})
+}
+
+func TestNewChangeClearsRedo(t *testing.T) {
+	forEachUndo(t, 0, func(t *testing.T, store *Store) {
+		store.AddTask("first")
+		store.Undo(1)
+		store.AddTask("second")
+		if _, err := store.Redo(1); !errors.Is(err, ErrNothingToRedo) {
+			t.Errorf("a new change should clear the redo stack, received %v", err)
+		}
+	})
+}
+
+func TestUndoDepth(t *testing.T) {
+	forEachUndo(t, 2, func(t *testing.T, store *Store) {
+		store.AddTask("first")
+		store.AddTask("second")
+		store.AddTask("third")
+		undone, _ := store.Undo(5)
+		if len(undone) != 2 {
+			t.Errorf("expected only the last 2 operations kept, received %v", undone)
+		}
+		if _, err := store.GetTask(1); err != nil {
+			t.Errorf("the oldest operation should be out of reach, received %v", err)
+		}
+	})
+}
+
+func TestUndoConflict(t *testing.T) {
+	forEachUndo(t, 0, func(t *testing.T, store *Store) {
+		store.AddTask("first")
+		store.UpdateTask(1, "changed")
+		// A change made without the undo log, as by an older version.
+		NewStore(store.repo.(*observedRepository).Repository).UpdateTask(1, "changed again")
+
+		var conflict *ErrUndoConflict
+		if _, err := store.Undo(1); !errors.As(err, &conflict) || conflict.ID != 1 {
+			t.Errorf("expected ErrUndoConflict for task 1, received %v", err)
+		}
+		if task, _ := store.GetTask(1); task.Description != "changed again" {
+			t.Errorf("a conflicting undo should change nothing, received %v", task)
+		}
+	})
+}
+
+func TestUndoIsRecordedInHistory(t *testing.T) {
+	history := NewMemoryHistory()
+	store := NewStore(NewMemoryRepository(), WithHistory(history, "ana"), WithUndo(NewMemoryUndoLog(), 0))
+	store.AddTask("first")
+	store.Undo(1)
+
+	events, _ := store.History(1)
+	if len(events) != 2 || events[1].Action != ActionDelete {
+		t.Errorf("expected the undo recorded as a delete, received %v", events)
+	}
+	if _, err := NewStore(NewMemoryRepository()).Undo(1); !errors.Is(err, ErrNoUndo) {
+		t.Errorf("expected ErrNoUndo, received %v", err)
+	}
+}
+
+func TestUndoRestore(t *testing.T) {
+	forEachUndo(t, 0, func(t *testing.T, store *Store) {
+		store.AddTask("first")
+		store.DeleteTask(1)
+		store.RestoreTask(1)
+
+		undone, err := store.Undo(1)
+		if err != nil || len(undone) != 1 || undone[0].Summary() != "restored task 1" {
+			t.Fatalf("expected the restore undone, received %v %v", undone, err)
+		}
+		if trash, _ := store.Trash(); len(trash) != 1 || trash[0].ID != 1 {
+			t.Errorf("expected task 1 back in the trash, received %v", trash)
+		}
+		if _, err := store.Redo(1); err != nil {
+			t.Errorf("expected the restore redone, received %v", err)
+		}
+		if _, err := store.GetTask(1); err != nil {
+			t.Errorf("expected task 1 out of the trash again, received %v", err)
+		}
+	})
+}
+
+func TestPurgeForgetsUndo(t *testing.T) {
+	forEachUndo(t, 0, func(t *testing.T, store *Store) {
+		store.AddTask("first")
+		store.AddTask("second")
+		store.DeleteTask(2)
+		store.Purge(time.Now().Add(time.Second))
+
+		undone, err := store.Undo(5)
+		if err != nil || len(undone) != 1 || undone[0].Summary() != "created task 1" {
+			t.Errorf("expected only the add of task 1 left to undo, received %v %v", undone, err)
+		}
+	})
+}
@@ -0,0 +1,194 @@
+package tasks
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+	"sync/atomic"
+)
+
+// StatusDefinition describes one status of a Workflow. Value is what tasks
+// store, so it must not change once tasks use it.
+type StatusDefinition struct {
+	Value TaskStatus `json:"value"`
+	Name  string     `json:"name"`
+	// Label is how the status is shown. It defaults to Name in upper case.
+	Label string `json:"label,omitempty"`
+	// Closed statuses count as finished: they do not block other tasks or
+	// keep a parent open, and the due views leave them out.
+	Closed bool `json:"closed,omitempty"`
+	// Transitions names the statuses a task can move to from this one. Empty
+	// allows every status.
+	Transitions []string `json:"transitions,omitempty"`
+}
+
+// Workflow is the set of statuses tasks can be in.
+type Workflow struct {
+	Statuses []StatusDefinition
+}
+
+var builtinStatuses = []StatusDefinition{
+	{Value: Todo, Name: "todo", Label: "TO DO"},
+	{Value: InProgress, Name: "in-progress", Label: "IN PROGRESS"},
+	{Value: Done, Name: "done", Label: "DONE", Closed: true},
+}
+
+var currentWorkflow atomic.Pointer[Workflow]
+
+func init() {
+	workflow := DefaultWorkflow()
+	currentWorkflow.Store(&workflow)
+}
+
+// DefaultWorkflow has the todo, in-progress and done statuses and allows
+// every transition between them.
+func DefaultWorkflow() Workflow {
+	statuses := make([]StatusDefinition, len(builtinStatuses))
+	for i, status := range builtinStatuses {
+		status.Transitions = slices.Clone(status.Transitions)
+		statuses[i] = status
+	}
+	return Workflow{statuses}
+}
+
+type ErrInvalidWorkflow struct {
+	Reason string
+}
+
+func (e *ErrInvalidWorkflow) Error() string {
+	return "invalid status workflow: " + e.Reason
+}
+
+// NewWorkflow adds definitions to the default workflow. Definitions named
+// like a built-in status change its label and transitions and keep its value;
+// done stays closed. Other statuses need a value above that of done.
+func NewWorkflow(definitions []StatusDefinition) (Workflow, error) {
+	workflow := DefaultWorkflow()
+	for _, definition := range definitions {
+		definition.Name = strings.ToLower(strings.TrimSpace(definition.Name))
+		if definition.Name == "" || strings.ContainsAny(definition.Name, " \t") {
+			return workflow, &ErrInvalidWorkflow{fmt.Sprintf("status name %q must be a single word", definition.Name)}
+		}
+		definition.Transitions = slices.Clone(definition.Transitions)
+		for i, transition := range definition.Transitions {
+			definition.Transitions[i] = strings.ToLower(strings.TrimSpace(transition))
+		}
+
+		index := slices.IndexFunc(workflow.Statuses, func(status StatusDefinition) bool {
+			return status.Name == definition.Name
+		})
+		switch {
+		case index != -1 && index < len(builtinStatuses):
+			builtin := workflow.Statuses[index]
+			if definition.Value != 0 && definition.Value != builtin.Value {
+				return workflow, &ErrInvalidWorkflow{fmt.Sprintf("%s is stored as %d and cannot change its value", builtin.Name, builtin.Value)}
+			}
+			definition.Value = builtin.Value
+			definition.Label = cmp.Or(definition.Label, builtin.Label)
+			definition.Closed = definition.Closed || builtin.Closed
+			workflow.Statuses[index] = definition
+			continue
+		case index != -1:
+			return workflow, &ErrInvalidWorkflow{fmt.Sprintf("status %s is defined twice", definition.Name)}
+		case definition.Value <= Done:
+			return workflow, &ErrInvalidWorkflow{fmt.Sprintf("status %s needs a value above %d", definition.Name, Done)}
+		}
+		if _, taken := workflow.Definition(definition.Value); taken {
+			return workflow, &ErrInvalidWorkflow{fmt.Sprintf("value %d of %s is already used", definition.Value, definition.Name)}
+		}
+		definition.Label = cmp.Or(definition.Label, strings.ToUpper(definition.Name))
+		workflow.Statuses = append(workflow.Statuses, definition)
+	}
+
+	for _, status := range workflow.Statuses {
+		for _, transition := range status.Transitions {
+			if _, ok := workflow.Lookup(transition); !ok {
+				return workflow, &ErrInvalidWorkflow{fmt.Sprintf("%s can move to unknown status %s", status.Name, transition)}
+			}
+		}
+	}
+	return workflow, nil
+}
+
+// SetWorkflow replaces the workflow statuses are checked and shown with.
+func SetWorkflow(workflow Workflow) {
+	currentWorkflow.Store(&workflow)
+}
+
+// CurrentWorkflow returns the workflow set with SetWorkflow, or the default
+// one.
+func CurrentWorkflow() Workflow {
+	return *currentWorkflow.Load()
+}
+
+// Lookup returns the status called name.
+func (w Workflow) Lookup(name string) (StatusDefinition, bool) {
+	index := slices.IndexFunc(w.Statuses, func(status StatusDefinition) bool {
+		return status.Name == strings.ToLower(name)
+	})
+	if index == -1 {
+		return StatusDefinition{}, false
+	}
+	return w.Statuses[index], true
+}
+
+// Definition returns the status stored as value.
+func (w Workflow) Definition(value TaskStatus) (StatusDefinition, bool) {
+	index := slices.IndexFunc(w.Statuses, func(status StatusDefinition) bool {
+		return status.Value == value
+	})
+	if index == -1 {
+		return StatusDefinition{}, false
+	}
+	return w.Statuses[index], true
+}
+
+// Names returns the names of every status.
+func (w Workflow) Names() []string {
+	names := make([]string, len(w.Statuses))
+	for i, status := range w.Statuses {
+		names[i] = status.Name
+	}
+	return names
+}
+
+type ErrUnknownStatus struct {
+	Name string
+}
+
+func (e *ErrUnknownStatus) Error() string {
+	return fmt.Sprintf("unknown status %q. Expected one of %s", e.Name, strings.Join(CurrentWorkflow().Names(), ", "))
+}
+
+type ErrInvalidTransition struct {
+	ID      int
+	From    string
+	To      string
+	Allowed []string
+}
+
+func (e *ErrInvalidTransition) Error() string {
+	return fmt.Sprintf("task %d cannot move from %s to %s. From %s it can move to %s", e.ID, e.From, e.To, e.From, strings.Join(e.Allowed, ", "))
+}
+
+// ParseStatus returns the status called name in the current workflow.
+func ParseStatus(name string) (TaskStatus, error) {
+	status, ok := CurrentWorkflow().Lookup(name)
+	if !ok {
+		return Todo, &ErrUnknownStatus{name}
+	}
+	return status.Value, nil
+}
+
+// checkTransition makes sure the workflow allows moving task to status.
+// Staying in the same status is always allowed.
+func checkTransition(task Task, status TaskStatus) error {
+	workflow := CurrentWorkflow()
+	from, _ := workflow.Definition(task.Status)
+	to, _ := workflow.Definition(status)
+	if task.Status == status || len(from.Transitions) == 0 || slices.Contains(from.Transitions, to.Name) {
+		return nil
+	}
+	return &ErrInvalidTransition{task.ID, from.Name, to.Name, from.Transitions}
+}
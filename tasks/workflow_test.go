@@ -0,0 +1,121 @@
+package tasks
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// reviewStatuses adds blocked, in-review and cancelled, and only lets done
+// tasks be reopened.
+var reviewStatuses = []StatusDefinition{
+	{Value: 3, Name: "blocked", Transitions: []string{"todo", "in-progress", "cancelled"}},
+	{Value: 4, Name: "in-review", Label: "IN REVIEW", Transitions: []string{"in-progress", "done"}},
+	{Value: 5, Name: "cancelled", Closed: true},
+	{Name: "done", Transitions: []string{"todo"}},
+}
+
+func useWorkflow(t *testing.T, definitions []StatusDefinition) {
+	t.Helper()
+	workflow, err := NewWorkflow(definitions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	SetWorkflow(workflow)
+	t.Cleanup(func() { SetWorkflow(DefaultWorkflow()) })
+}
+
+func TestNewWorkflow(t *testing.T) {
+	workflow, err := NewWorkflow(reviewStatuses)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names := strings.Join(workflow.Names(), " "); names != "todo in-progress done blocked in-review cancelled" {
+		t.Errorf("unexpected statuses %s", names)
+	}
+	done, _ := workflow.Lookup("DONE")
+	if done.Value != Done || done.Label != "DONE" || !done.Closed {
+		t.Errorf("overriding done should keep its value and label, received %+v", done)
+	}
+	blocked, _ := workflow.Lookup("blocked")
+	if blocked.Label != "BLOCKED" {
+		t.Errorf("labels should default to the upper case name, received %q", blocked.Label)
+	}
+
+	invalid := [][]StatusDefinition{
+		{{Value: 2, Name: "review"}},
+		{{Name: "done", Value: 7}},
+		{{Value: 3, Name: "a"}, {Value: 3, Name: "b"}},
+		{{Value: 3, Name: "a"}, {Value: 4, Name: "a"}},
+		{{Value: 3, Name: "a", Transitions: []string{"nowhere"}}},
+		{{Value: 3, Name: "two words"}},
+	}
+	expectedErrType := &ErrInvalidWorkflow{}
+	for _, definitions := range invalid {
+		if _, err := NewWorkflow(definitions); !errors.As(err, &expectedErrType) {
+			t.Errorf("NewWorkflow(%+v): expected ErrInvalidWorkflow, received %v", definitions, err)
+		}
+	}
+}
+
+func TestWorkflowTransitions(t *testing.T) {
+	useWorkflow(t, reviewStatuses)
+	forEachBackend(t, func(t *testing.T, store *Store) {
+		task, _ := store.AddTask("ship feature")
+		inReview := StatusNameToValue("in-review")
+		if inReview != 4 {
+			t.Fatalf("expected in-review to be 4, received %d", inReview)
+		}
+
+		for _, status := range []TaskStatus{InProgress, inReview, Done, Todo} {
+			if _, err := store.UpdateTaskStatus(task.ID, status); err != nil {
+				t.Fatalf("moving to %s: %s", status.Name(), err)
+			}
+		}
+
+		store.UpdateTaskStatus(task.ID, Done)
+		expectedErrType := &ErrInvalidTransition{}
+		_, err := store.UpdateTaskStatus(task.ID, inReview)
+		if !errors.As(err, &expectedErrType) {
+			t.Fatalf("expected ErrInvalidTransition, received %v", err)
+		}
+		if !strings.Contains(err.Error(), "From done it can move to todo") {
+			t.Errorf("the error should list the allowed moves: %s", err)
+		}
+		if _, err := store.ForceTaskStatus(task.ID, inReview); err != nil {
+			t.Errorf("forcing should skip the workflow: %s", err)
+		}
+
+		stored, _ := store.GetTask(task.ID)
+		if !strings.Contains(stored.String(), "status: IN REVIEW") {
+			t.Errorf("expected the label in %s", stored)
+		}
+	})
+}
+
+func TestClosedStatusesUnblock(t *testing.T) {
+	useWorkflow(t, reviewStatuses)
+	forEachBackend(t, func(t *testing.T, store *Store) {
+		store.AddTask("spike")
+		store.AddTask("build")
+		store.Block(2, 1)
+
+		store.UpdateTaskStatus(1, StatusNameToValue("cancelled"))
+		if _, err := store.UpdateTaskStatus(2, InProgress); err != nil {
+			t.Errorf("a cancelled blocker should not block: %s", err)
+		}
+	})
+}
+
+func TestParseStatus(t *testing.T) {
+	if status, err := ParseStatus("In-Progress"); err != nil || status != InProgress {
+		t.Errorf("expected in-progress, received %d (%v)", status, err)
+	}
+	expectedErrType := &ErrUnknownStatus{}
+	if _, err := ParseStatus("in-review"); !errors.As(err, &expectedErrType) {
+		t.Errorf("expected ErrUnknownStatus without a custom workflow, received %v", err)
+	}
+	if StatusNameToValue("in-review") != -1 {
+		t.Errorf("unknown statuses should map to -1")
+	}
+}
@@ -0,0 +1,299 @@
+// Package tui is an interactive board of tasks for the terminal. The Board
+// is driven by keys and renders into a Screen, so it runs the same with or
+// without a terminal; Run connects it to one.
+package tui
+
+import (
+	"backend/tasks"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// ErrQuit is returned by HandleKey when the user leaves the board.
+var ErrQuit = errors.New("quit")
+
+type mode int
+
+const (
+	modeBoard mode = iota
+	modeAdd
+	modeEdit
+	modeFilter
+	modeConfirmDelete
+)
+
+// column is the tasks in one status of the workflow.
+type column struct {
+	status tasks.StatusDefinition
+	tasks  []tasks.Task
+	// offset is the first task shown when the column does not fit.
+	offset int
+}
+
+// Board shows tasks in one column per status of the workflow. Every change
+// goes through the Store, like the commands of the CLI.
+type Board struct {
+	store   *tasks.Store
+	columns []column
+	// column and row are the selected task.
+	column, row int
+
+	mode mode
+	// input is the text being typed in the add, edit and filter boxes.
+	input   []rune
+	filter  string
+	message string
+}
+
+// New returns a board with the tasks of store loaded.
+func New(store *tasks.Store) (*Board, error) {
+	b := &Board{store: store}
+	return b, b.refresh()
+}
+
+// refresh reloads the tasks, keeping the selection in place as far as the
+// columns allow.
+func (b *Board) refresh() error {
+	list, err := b.store.Query(tasks.Query{Status: tasks.AllTasks})
+	if err != nil {
+		return err
+	}
+	statuses := tasks.CurrentWorkflow().Statuses
+	columns := make([]column, len(statuses))
+	for i, status := range statuses {
+		columns[i].status = status
+		if i < len(b.columns) {
+			columns[i].offset = b.columns[i].offset
+		}
+		for _, task := range list {
+			if task.Status == status.Value && b.matches(task) {
+				columns[i].tasks = append(columns[i].tasks, task)
+			}
+		}
+	}
+	b.columns = columns
+	b.column = min(b.column, len(columns)-1)
+	b.clampRow()
+	return nil
+}
+
+// matches reports whether a task passes the filter, matching its
+// description, project and tags regardless of case.
+func (b *Board) matches(task tasks.Task) bool {
+	if b.filter == "" {
+		return true
+	}
+	text := strings.Join(append([]string{task.Description, task.Project}, task.Tags...), " ")
+	return strings.Contains(strings.ToLower(text), strings.ToLower(b.filter))
+}
+
+func (b *Board) clampRow() {
+	b.row = max(min(b.row, len(b.columns[b.column].tasks)-1), 0)
+}
+
+// Selected returns the selected task, if any column has tasks.
+func (b *Board) Selected() (tasks.Task, bool) {
+	current := b.columns[b.column].tasks
+	if len(current) == 0 {
+		return tasks.ZeroTask, false
+	}
+	return current[b.row], true
+}
+
+// HandleKey applies a key press. Failed changes are shown on the board
+// rather than returned; HandleKey only fails with ErrQuit or when the tasks
+// cannot be read again.
+func (b *Board) HandleKey(key Key) error {
+	if key == KeyCtrlC {
+		return ErrQuit
+	}
+	switch b.mode {
+	case modeAdd, modeEdit, modeFilter:
+		return b.handleInput(key)
+	case modeConfirmDelete:
+		b.mode = modeBoard
+		b.message = ""
+		if key != 'y' && key != 'Y' {
+			return nil
+		}
+		task, _ := b.Selected()
+		if err := b.store.DeleteTask(task.ID); err != nil {
+			b.message = err.Error()
+		} else {
+			b.message = fmt.Sprintf("Task %d moved to the trash", task.ID)
+		}
+		return b.refresh()
+	}
+
+	b.message = ""
+	switch key {
+	case 'q':
+		return ErrQuit
+	case KeyLeft, 'h':
+		b.column = max(b.column-1, 0)
+		b.clampRow()
+	case KeyRight, 'l':
+		b.column = min(b.column+1, len(b.columns)-1)
+		b.clampRow()
+	case KeyUp, 'k':
+		b.row = max(b.row-1, 0)
+	case KeyDown, 'j':
+		b.row++
+		b.clampRow()
+	case 'a':
+		b.startInput(modeAdd, "")
+	case '/':
+		b.startInput(modeFilter, b.filter)
+	case KeyEsc:
+		b.filter = ""
+		return b.refresh()
+	case 'e':
+		if task, ok := b.Selected(); ok {
+			b.startInput(modeEdit, task.Description)
+		}
+	case 'i':
+		return b.setStatus(tasks.InProgress)
+	case 'd':
+		return b.setStatus(tasks.Done)
+	case 'x', KeyDelete:
+		if task, ok := b.Selected(); ok {
+			b.mode = modeConfirmDelete
+			b.message = fmt.Sprintf("Delete task %d? (y/n)", task.ID)
+		}
+	}
+	return nil
+}
+
+func (b *Board) startInput(mode mode, text string) {
+	b.mode = mode
+	b.input = []rune(text)
+}
+
+// handleInput types into the input box. The filter applies while it is
+// typed; add and edit save on Enter. Esc cancels.
+func (b *Board) handleInput(key Key) error {
+	switch {
+	case key == KeyEsc:
+		if b.mode == modeFilter {
+			b.filter = ""
+		}
+		b.mode = modeBoard
+		return b.refresh()
+	case key == KeyEnter:
+		return b.submit()
+	case key == KeyBackspace:
+		if len(b.input) > 0 {
+			b.input = b.input[:len(b.input)-1]
+		}
+	case key >= ' ':
+		b.input = append(b.input, rune(key))
+	default:
+		return nil
+	}
+	if b.mode == modeFilter {
+		b.filter = string(b.input)
+		return b.refresh()
+	}
+	return nil
+}
+
+// submit saves what was typed in the add or edit box.
+func (b *Board) submit() error {
+	mode := b.mode
+	b.mode = modeBoard
+	text := string(b.input)
+	var err error
+	switch mode {
+	case modeAdd:
+		var task tasks.Task
+		task, err = b.store.AddTask(text)
+		if err == nil {
+			b.message = fmt.Sprintf("Task added (ID: %d)", task.ID)
+		}
+	case modeEdit:
+		selected, _ := b.Selected()
+		_, err = b.store.UpdateTask(selected.ID, text)
+	}
+	if err != nil {
+		b.message = err.Error()
+	}
+	return b.refresh()
+}
+
+func (b *Board) setStatus(status tasks.TaskStatus) error {
+	task, ok := b.Selected()
+	if !ok {
+		return nil
+	}
+	if _, err := b.store.UpdateTaskStatus(task.ID, status); err != nil {
+		b.message = err.Error()
+	}
+	return b.refresh()
+}
+
+// boardHelp and inputHelp are the key hints on the last line.
+const (
+	boardHelp = "←↓↑→ move  a add  e edit  i in progress  d done  x delete  / filter  q quit"
+	inputHelp = "enter save  esc cancel"
+)
+
+var inputPrompts = map[mode]string{modeAdd: "Add: ", modeEdit: "Edit: ", modeFilter: "Filter: "}
+
+// Render draws the board on a screen of the given size: a title line, the
+// columns, a line for the input box or the last message, and the key hints.
+func (b *Board) Render(width, height int) *Screen {
+	screen := NewScreen(width, height)
+	title := "Tasks"
+	if b.filter != "" {
+		title += fmt.Sprintf(" (filter: %s)", b.filter)
+	}
+	screen.Put(0, 0, truncate(title, width))
+
+	columnWidth := width / len(b.columns)
+	visible := max(height-5, 0)
+	for i := range b.columns {
+		col := &b.columns[i]
+		x := i * columnWidth
+		header := fmt.Sprintf("%s (%d)", col.status.Label, len(col.tasks))
+		screen.Put(x, 1, truncate(header, columnWidth-1))
+		screen.Put(x, 2, strings.Repeat("─", max(columnWidth-1, 0)))
+
+		if i == b.column {
+			col.offset = min(col.offset, b.row)
+			col.offset = max(col.offset, b.row-visible+1)
+		}
+		col.offset = max(min(col.offset, len(col.tasks)-visible), 0)
+		for row, task := range col.tasks[col.offset:min(col.offset+visible, len(col.tasks))] {
+			marker := "  "
+			if i == b.column && col.offset+row == b.row {
+				marker = "> "
+			}
+			line := marker + taskLine(task)
+			screen.Put(x, 3+row, truncate(line, columnWidth-1))
+		}
+	}
+
+	if prompt, ok := inputPrompts[b.mode]; ok {
+		end := screen.Put(0, height-2, prompt+string(b.input))
+		screen.CursorX, screen.CursorY, screen.CursorVisible = min(end, width-1), height-2, true
+		screen.Put(0, height-1, inputHelp)
+	} else {
+		screen.Put(0, height-2, b.message)
+		screen.Put(0, height-1, boardHelp)
+	}
+	return screen
+}
+
+// taskLine is a task as shown in its column.
+func taskLine(task tasks.Task) string {
+	parts := []string{fmt.Sprint(task.ID), task.Description}
+	if task.Priority != tasks.PriorityNone {
+		parts = append(parts, "!"+task.Priority.String())
+	}
+	for _, tag := range slices.Sorted(slices.Values(task.Tags)) {
+		parts = append(parts, "+"+tag)
+	}
+	return strings.Join(parts, " ")
+}
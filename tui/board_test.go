@@ -0,0 +1,216 @@
+package tui
+
+import (
+	"backend/tasks"
+	"bufio"
+	"errors"
+	"strings"
+	"testing"
+)
+
+const (
+	testWidth  = 90
+	testHeight = 12
+)
+
+func newBoard(t *testing.T, descriptions ...string) (*Board, *tasks.Store) {
+	t.Helper()
+	store := tasks.NewStore(tasks.NewMemoryRepository())
+	for _, description := range descriptions {
+		if _, err := store.AddTask(description); err != nil {
+			t.Fatal(err)
+		}
+	}
+	board, err := New(store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return board, store
+}
+
+// press feeds keys to the board, failing on any error but ErrQuit.
+func press(t *testing.T, board *Board, keys ...Key) {
+	t.Helper()
+	for _, key := range keys {
+		if err := board.HandleKey(key); err != nil {
+			t.Fatalf("key %q: %v", key, err)
+		}
+	}
+}
+
+func render(board *Board) *Screen {
+	return board.Render(testWidth, testHeight)
+}
+
+func TestRenderColumns(t *testing.T) {
+	board, _ := newBoard(t, "buy milk +home", "write report")
+	screen := render(board)
+
+	header := screen.Line(1)
+	for _, label := range []string{"TO DO (2)", "IN PROGRESS (0)", "DONE (0)"} {
+		if !strings.Contains(header, label) {
+			t.Errorf("expected %q in the header, received %q", label, header)
+		}
+	}
+	if line := screen.Line(3); line != "> 1 buy milk +home" {
+		t.Errorf("expected the first task selected, received %q", line)
+	}
+	if line := screen.Line(4); line != "  2 write report" {
+		t.Errorf("expected the second task, received %q", line)
+	}
+	if line := screen.Line(testHeight - 1); line != boardHelp {
+		t.Errorf("expected the key hints, received %q", line)
+	}
+}
+
+func TestMoveTaskThroughColumns(t *testing.T) {
+	board, store := newBoard(t, "first", "second")
+	press(t, board, KeyDown, 'i')
+
+	if task, _ := store.GetTask(2); task.Status != tasks.InProgress {
+		t.Errorf("expected task 2 in progress, received %v", task)
+	}
+	press(t, board, KeyRight)
+	if task, _ := board.Selected(); task.ID != 2 {
+		t.Errorf("expected task 2 selected in its new column, received %v", task)
+	}
+	press(t, board, 'd')
+	screen := render(board)
+	if !strings.Contains(screen.Line(1), "DONE (1)") {
+		t.Errorf("expected task 2 done, received\n%s", screen)
+	}
+	if line := screen.Line(3); !strings.HasPrefix(line, "  1 first ") || !strings.HasSuffix(line, "  2 second") {
+		t.Errorf("expected task 1 left in todo and task 2 in done, received %q", line)
+	}
+}
+
+func TestAddAndEdit(t *testing.T) {
+	board, store := newBoard(t)
+	press(t, board, 'a')
+	press(t, board, Keys("buy bread")...)
+	if line := render(board).Line(testHeight - 2); line != "Add: buy bread" {
+		t.Errorf("expected the add box, received %q", line)
+	}
+	press(t, board, KeyEnter)
+	if line := render(board).Line(testHeight - 2); line != "Task added (ID: 1)" {
+		t.Errorf("expected a confirmation, received %q", line)
+	}
+
+	press(t, board, 'e', KeyBackspace, KeyBackspace, KeyBackspace, KeyBackspace, KeyBackspace)
+	press(t, board, Keys("milk")...)
+	press(t, board, KeyEnter)
+	if task, _ := store.GetTask(1); task.Description != "buy milk" {
+		t.Errorf("expected the edited description, received %q", task.Description)
+	}
+
+	press(t, board, 'a', KeyEnter)
+	if line := render(board).Line(testHeight - 2); line != tasks.ErrNoDescriptionProvided.Error() {
+		t.Errorf("expected the error on the board, received %q", line)
+	}
+	press(t, board, 'a', 'x', KeyEsc)
+	if list, _ := store.Query(tasks.Query{Status: tasks.AllTasks}); len(list) != 1 {
+		t.Errorf("esc should not add a task, received %v", list)
+	}
+}
+
+func TestDeleteAsksFirst(t *testing.T) {
+	board, store := newBoard(t, "keep", "remove")
+	press(t, board, KeyDown, 'x')
+	if line := render(board).Line(testHeight - 2); line != "Delete task 2? (y/n)" {
+		t.Errorf("expected a confirmation question, received %q", line)
+	}
+	press(t, board, 'n')
+	if _, err := store.GetTask(2); err != nil {
+		t.Errorf("answering n should keep the task, received %v", err)
+	}
+
+	press(t, board, 'x', 'y')
+	if _, err := store.GetTask(2); err == nil {
+		t.Error("expected task 2 deleted")
+	}
+	if trash, _ := store.Trash(); len(trash) != 1 {
+		t.Errorf("expected task 2 in the trash, received %v", trash)
+	}
+}
+
+func TestLiveFilter(t *testing.T) {
+	board, _ := newBoard(t, "buy milk", "call mom +family", "buy bread")
+	press(t, board, '/', 'b', 'u')
+	screen := render(board)
+	if !strings.Contains(screen.Line(1), "TO DO (2)") {
+		t.Errorf("expected the filter applied while typing, received\n%s", screen)
+	}
+	if !screen.CursorVisible || screen.CursorY != testHeight-2 {
+		t.Errorf("expected the cursor in the filter box, received %+v", screen)
+	}
+
+	press(t, board, KeyBackspace, KeyBackspace)
+	press(t, board, Keys("FAMILY")...)
+	press(t, board, KeyEnter)
+	screen = render(board)
+	if line := screen.Line(3); line != "> 2 call mom +family" || screen.Line(4) != "" {
+		t.Errorf("expected only the tagged task, received\n%s", screen)
+	}
+	if !strings.Contains(screen.Line(0), "filter: FAMILY") {
+		t.Errorf("expected the filter in the title, received %q", screen.Line(0))
+	}
+
+	press(t, board, KeyEsc)
+	if !strings.Contains(render(board).Line(1), "TO DO (3)") {
+		t.Error("esc should clear the filter")
+	}
+}
+
+func TestBlockedTaskShowsError(t *testing.T) {
+	board, store := newBoard(t, "first", "second")
+	if _, err := store.Block(1, 2); err != nil {
+		t.Fatal(err)
+	}
+	press(t, board, 'i')
+	if task, _ := store.GetTask(1); task.Status != tasks.Todo {
+		t.Errorf("a blocked task should not start, received %v", task)
+	}
+	if line := render(board).Line(testHeight - 2); !strings.Contains(line, "blocked") {
+		t.Errorf("expected the blocked error on the board, received %q", line)
+	}
+}
+
+func TestScrollKeepsSelectionVisible(t *testing.T) {
+	board, _ := newBoard(t, "1", "2", "3", "4", "5", "6", "7", "8", "9", "10")
+	for range 9 {
+		press(t, board, KeyDown)
+	}
+	screen := render(board)
+	if line := screen.Line(testHeight - 3); line != "> 10 10" {
+		t.Errorf("expected the last task selected at the bottom, received\n%s", screen)
+	}
+}
+
+func TestQuit(t *testing.T) {
+	board, _ := newBoard(t)
+	if err := board.HandleKey('q'); !errors.Is(err, ErrQuit) {
+		t.Errorf("expected q to quit, received %v", err)
+	}
+	press(t, board, 'a')
+	if err := board.HandleKey('q'); err != nil {
+		t.Errorf("q should be typed in the add box, received %v", err)
+	}
+	if err := board.HandleKey(KeyCtrlC); !errors.Is(err, ErrQuit) {
+		t.Errorf("expected ctrl-c to quit, received %v", err)
+	}
+}
+
+func TestReadKey(t *testing.T) {
+	input := "a\x1b[A\x1b[3~\r\x7f\x1b"
+	reader := bufio.NewReader(strings.NewReader(input))
+	expected := []Key{'a', KeyUp, KeyDelete, KeyEnter, KeyBackspace, KeyEsc}
+	for _, want := range expected {
+		key, err := ReadKey(reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if key != want {
+			t.Errorf("expected key %d, received %d", want, key)
+		}
+	}
+}
@@ -0,0 +1,11 @@
+module backend/tui
+
+go 1.25.3
+
+replace backend/jsondatabase => ../json-database
+
+replace backend/tasks => ../tasks
+
+require backend/tasks v0.0.0-00010101000000-000000000000
+
+require backend/jsondatabase v0.0.0-00010101000000-000000000000 // indirect
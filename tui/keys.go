@@ -0,0 +1,87 @@
+package tui
+
+import (
+	"bufio"
+)
+
+// Key is a key press: the rune typed, or one of the special keys below.
+type Key rune
+
+const (
+	KeyCtrlA     Key = 0x01
+	KeyCtrlC     Key = 0x03
+	KeyCtrlD     Key = 0x04
+	KeyCtrlE     Key = 0x05
+	KeyCtrlU     Key = 0x15
+	KeyTab       Key = '\t'
+	KeyBackspace Key = 0x7f
+	KeyEnter     Key = '\r'
+	KeyEsc       Key = 0x1b
+)
+
+// Keys without a rune are negative so they never collide with typed text.
+const (
+	KeyUp Key = -(iota + 1)
+	KeyDown
+	KeyLeft
+	KeyRight
+	KeyDelete
+	KeyHome
+	KeyEnd
+	// KeyUnknown is an escape sequence ReadKey does not know.
+	KeyUnknown
+)
+
+// Keys returns the keys that type text, for feeding a Board or a
+// LineEditor.
+func Keys(text string) []Key {
+	keys := make([]Key, 0, len(text))
+	for _, r := range text {
+		keys = append(keys, Key(r))
+	}
+	return keys
+}
+
+// escapeKeys maps the final byte of an ESC [ or ESC O sequence to its key.
+var escapeKeys = map[byte]Key{'A': KeyUp, 'B': KeyDown, 'C': KeyRight, 'D': KeyLeft, 'H': KeyHome, 'F': KeyEnd}
+
+// ReadKey reads one key press from a terminal in raw mode. An escape byte
+// with nothing buffered after it is the Esc key itself.
+func ReadKey(r *bufio.Reader) (Key, error) {
+	char, _, err := r.ReadRune()
+	if err != nil {
+		return 0, err
+	}
+	switch {
+	case char == '\n':
+		return KeyEnter, nil
+	case char == '\b':
+		return KeyBackspace, nil
+	case char != rune(KeyEsc) || r.Buffered() == 0:
+		return Key(char), nil
+	}
+
+	introducer, err := r.ReadByte()
+	if err != nil || (introducer != '[' && introducer != 'O') {
+		return KeyUnknown, err
+	}
+	// Parameters are digits and semicolons, like the 3 of ESC [ 3 ~.
+	params := []byte{}
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return KeyUnknown, err
+		}
+		if (b >= '0' && b <= '9') || b == ';' {
+			params = append(params, b)
+			continue
+		}
+		if b == '~' && string(params) == "3" {
+			return KeyDelete, nil
+		}
+		if key, ok := escapeKeys[b]; ok {
+			return key, nil
+		}
+		return KeyUnknown, nil
+	}
+}
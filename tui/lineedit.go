@@ -0,0 +1,218 @@
+package tui
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+)
+
+// ErrInterrupted is returned for a line dropped with Ctrl-C.
+var ErrInterrupted = errors.New("interrupted")
+
+// LineEditor reads lines the way a shell prompt does: with cursor movement,
+// a history browsed with the arrow keys and Tab completion. Like the Board,
+// it is driven by HandleKey, and ReadLine connects it to a terminal.
+type LineEditor struct {
+	Prompt string
+	// History holds the lines entered, oldest first. Enter adds to it.
+	History []string
+	// Complete returns the words that can go where the last word of before,
+	// the text left of the cursor, is. The editor keeps those that start
+	// with what was typed of that word.
+	Complete func(before string) []string
+
+	line   []rune
+	cursor int
+	// browsing is the History entry shown, len(History) for the line being
+	// typed, which is kept in draft meanwhile.
+	browsing int
+	draft    []rune
+	// listed holds the candidates of an ambiguous Tab until they are shown.
+	listed []string
+	reader *bufio.Reader
+}
+
+// Reset starts a new empty line.
+func (e *LineEditor) Reset() {
+	e.line, e.cursor, e.draft, e.listed = nil, 0, nil, nil
+	e.browsing = len(e.History)
+}
+
+// View returns the prompt with the line and the column of the cursor.
+func (e *LineEditor) View() (string, int) {
+	return e.Prompt + string(e.line), len([]rune(e.Prompt)) + e.cursor
+}
+
+// Listed returns the candidates of the last ambiguous Tab and forgets them.
+func (e *LineEditor) Listed() []string {
+	listed := e.listed
+	e.listed = nil
+	return listed
+}
+
+// HandleKey applies a key press. When Enter ends the line it returns the
+// line and true. Ctrl-C returns ErrInterrupted, and Ctrl-D on an empty line
+// io.EOF.
+func (e *LineEditor) HandleKey(key Key) (string, bool, error) {
+	switch key {
+	case KeyEnter:
+		line := string(e.line)
+		if strings.TrimSpace(line) != "" && (len(e.History) == 0 || e.History[len(e.History)-1] != line) {
+			e.History = append(e.History, line)
+		}
+		e.Reset()
+		return line, true, nil
+	case KeyCtrlC:
+		e.Reset()
+		return "", false, ErrInterrupted
+	case KeyCtrlD:
+		if len(e.line) == 0 {
+			return "", false, io.EOF
+		}
+		e.deleteAt(e.cursor)
+	case KeyLeft:
+		e.cursor = max(e.cursor-1, 0)
+	case KeyRight:
+		e.cursor = min(e.cursor+1, len(e.line))
+	case KeyHome, KeyCtrlA:
+		e.cursor = 0
+	case KeyEnd, KeyCtrlE:
+		e.cursor = len(e.line)
+	case KeyBackspace:
+		if e.cursor > 0 {
+			e.cursor--
+			e.deleteAt(e.cursor)
+		}
+	case KeyDelete:
+		e.deleteAt(e.cursor)
+	case KeyCtrlU:
+		e.line = slices.Delete(e.line, 0, e.cursor)
+		e.cursor = 0
+	case KeyUp:
+		e.browse(e.browsing - 1)
+	case KeyDown:
+		e.browse(e.browsing + 1)
+	case KeyTab:
+		e.complete()
+	default:
+		if key >= ' ' {
+			e.insert(string(rune(key)))
+		}
+	}
+	return "", false, nil
+}
+
+func (e *LineEditor) insert(text string) {
+	runes := []rune(text)
+	e.line = slices.Insert(e.line, e.cursor, runes...)
+	e.cursor += len(runes)
+}
+
+func (e *LineEditor) deleteAt(index int) {
+	if index < len(e.line) {
+		e.line = slices.Delete(e.line, index, index+1)
+	}
+}
+
+// browse shows History entry index, or the line being typed past the end.
+func (e *LineEditor) browse(index int) {
+	if index < 0 || index > len(e.History) || index == e.browsing {
+		return
+	}
+	if e.browsing == len(e.History) {
+		e.draft = e.line
+	}
+	e.browsing = index
+	if index == len(e.History) {
+		e.line = e.draft
+	} else {
+		e.line = []rune(e.History[index])
+	}
+	e.cursor = len(e.line)
+}
+
+// complete finishes the word left of the cursor. A single candidate is
+// inserted with a space after it; several are completed as far as they
+// agree, and listed when that adds nothing.
+func (e *LineEditor) complete() {
+	if e.Complete == nil {
+		return
+	}
+	before := string(e.line[:e.cursor])
+	word := before[strings.LastIndex(before, " ")+1:]
+	var matches []string
+	for _, candidate := range e.Complete(before) {
+		if strings.HasPrefix(candidate, word) && !slices.Contains(matches, candidate) {
+			matches = append(matches, candidate)
+		}
+	}
+	switch len(matches) {
+	case 0:
+	case 1:
+		e.insert(matches[0][len(word):] + " ")
+	default:
+		prefix := commonPrefix(matches)
+		if len(prefix) > len(word) {
+			e.insert(prefix[len(word):])
+		} else {
+			e.listed = matches
+		}
+	}
+}
+
+func commonPrefix(words []string) string {
+	prefix := words[0]
+	for _, word := range words[1:] {
+		for !strings.HasPrefix(word, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	return prefix
+}
+
+// ReadLine shows the prompt and reads a line from in. When in is not a
+// terminal it reads plain lines, without prompt or editing, so scripts can
+// be piped in.
+func (e *LineEditor) ReadLine(in *os.File, out io.Writer) (string, error) {
+	if e.reader == nil {
+		e.reader = bufio.NewReader(in)
+	}
+	restore, err := makeRaw(in.Fd())
+	if errors.Is(err, ErrNotTerminal) {
+		line, readErr := e.reader.ReadString('\n')
+		if readErr != nil && line == "" {
+			return "", readErr
+		}
+		return strings.TrimRight(line, "\r\n"), nil
+	}
+	if err != nil {
+		return "", err
+	}
+	defer restore()
+
+	e.Reset()
+	for {
+		text, cursor := e.View()
+		fmt.Fprintf(out, "\r%s\x1b[K\r", text)
+		if cursor > 0 {
+			fmt.Fprintf(out, "\x1b[%dC", cursor)
+		}
+
+		key, err := ReadKey(e.reader)
+		if err != nil {
+			return "", err
+		}
+		line, done, err := e.HandleKey(key)
+		if listed := e.Listed(); len(listed) > 0 {
+			fmt.Fprintf(out, "\r\n%s\r\n", strings.Join(listed, "  "))
+		}
+		if err != nil || done {
+			fmt.Fprint(out, "\r\n")
+			return line, err
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package tui
+
+import (
+	"errors"
+	"io"
+	"testing"
+)
+
+// typeLine feeds keys to the editor and returns the line Enter ended, or ""
+// if it was not ended.
+func typeLine(t *testing.T, editor *LineEditor, keys ...Key) string {
+	t.Helper()
+	for _, key := range keys {
+		line, done, err := editor.HandleKey(key)
+		if err != nil {
+			t.Fatalf("key %q: %v", key, err)
+		}
+		if done {
+			return line
+		}
+	}
+	return ""
+}
+
+func TestLineEditing(t *testing.T) {
+	editor := &LineEditor{Prompt: "> "}
+	editor.Reset()
+	typeLine(t, editor, Keys("add milk")...)
+	typeLine(t, editor, KeyLeft, KeyLeft, KeyLeft, KeyLeft, KeyBackspace)
+	typeLine(t, editor, Keys(" buy ")...)
+	if text, cursor := editor.View(); text != "> add buy milk" || cursor != 10 {
+		t.Errorf("expected the edited line with the cursor after buy, received %q at %d", text, cursor)
+	}
+	typeLine(t, editor, KeyHome, KeyDelete, KeyCtrlE)
+	typeLine(t, editor, Keys("s")...)
+	if line := typeLine(t, editor, KeyEnter); line != "dd buy milks" {
+		t.Errorf("expected the whole line on enter, received %q", line)
+	}
+
+	typeLine(t, editor, Keys("some text")...)
+	typeLine(t, editor, KeyCtrlU)
+	if text, _ := editor.View(); text != "> " {
+		t.Errorf("expected ctrl-u to clear the line, received %q", text)
+	}
+	if _, _, err := editor.HandleKey(KeyCtrlD); !errors.Is(err, io.EOF) {
+		t.Errorf("expected ctrl-d on an empty line to end the input, received %v", err)
+	}
+}
+
+func TestLineHistory(t *testing.T) {
+	editor := &LineEditor{History: []string{"list"}}
+	editor.Reset()
+	typeLine(t, editor, append(Keys("show 1"), KeyEnter)...)
+	typeLine(t, editor, Keys("draft")...)
+
+	typeLine(t, editor, KeyUp, KeyUp)
+	if text, _ := editor.View(); text != "list" {
+		t.Errorf("expected the oldest entry, received %q", text)
+	}
+	typeLine(t, editor, KeyUp, KeyDown)
+	if text, _ := editor.View(); text != "show 1" {
+		t.Errorf("expected the newest entry, received %q", text)
+	}
+	typeLine(t, editor, KeyDown)
+	if text, _ := editor.View(); text != "draft" {
+		t.Errorf("expected the draft back, received %q", text)
+	}
+	if len(editor.History) != 2 {
+		t.Errorf("expected the entered line added to history, received %v", editor.History)
+	}
+}
+
+func TestLineCompletion(t *testing.T) {
+	editor := &LineEditor{Complete: func(before string) []string {
+		if before == "mark" || before == "ma" || before == "m" {
+			return []string{"mark", "mark-done", "mark-in-progress", "list"}
+		}
+		return []string{"12", "13", "20"}
+	}}
+	editor.Reset()
+
+	typeLine(t, editor, Keys("ma")...)
+	typeLine(t, editor, KeyTab)
+	if text, _ := editor.View(); text != "mark" {
+		t.Errorf("expected the common prefix completed, received %q", text)
+	}
+	typeLine(t, editor, KeyTab)
+	if listed := editor.Listed(); len(listed) != 3 {
+		t.Errorf("expected the candidates listed, received %v", listed)
+	}
+
+	typeLine(t, editor, Keys("-done 2")...)
+	typeLine(t, editor, KeyTab)
+	if text, _ := editor.View(); text != "mark-done 20 " {
+		t.Errorf("expected the only match inserted, received %q", text)
+	}
+}
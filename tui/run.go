@@ -0,0 +1,79 @@
+package tui
+
+import (
+	"backend/tasks"
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// ErrNotTerminal is returned by Run when its input is not a terminal it can
+// put in raw mode.
+var ErrNotTerminal = errors.New("the board needs an interactive terminal")
+
+// defaultWidth and defaultHeight are used when the terminal does not report
+// its size.
+const (
+	defaultWidth  = 80
+	defaultHeight = 24
+)
+
+// Run shows the board full screen until the user quits. It reads keys from
+// in, which must be a terminal, and leaves the terminal as it found it.
+func Run(store *tasks.Store, in *os.File, out *os.File) error {
+	board, err := New(store)
+	if err != nil {
+		return err
+	}
+	restore, err := makeRaw(in.Fd())
+	if err != nil {
+		return err
+	}
+	defer restore()
+	// The alternate screen keeps the shell's scrollback untouched.
+	fmt.Fprint(out, "\x1b[?1049h")
+	defer fmt.Fprint(out, "\x1b[?25h\x1b[?1049l")
+
+	reader := bufio.NewReader(in)
+	for {
+		width, height, sizeErr := terminalSize(out.Fd())
+		if sizeErr != nil || width == 0 || height == 0 {
+			width, height = defaultWidth, defaultHeight
+		}
+		if _, err := out.WriteString(draw(board.Render(width, height))); err != nil {
+			return err
+		}
+
+		key, err := ReadKey(reader)
+		if err != nil {
+			return err
+		}
+		err = board.HandleKey(key)
+		if errors.Is(err, ErrQuit) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// draw returns the escape sequences that paint screen over the whole
+// terminal.
+func draw(screen *Screen) string {
+	var out strings.Builder
+	out.WriteString("\x1b[?25l\x1b[H")
+	for y := range screen.Height {
+		if y > 0 {
+			out.WriteString("\r\n")
+		}
+		out.WriteString(screen.Line(y))
+		out.WriteString("\x1b[K")
+	}
+	if screen.CursorVisible {
+		fmt.Fprintf(&out, "\x1b[%d;%dH\x1b[?25h", screen.CursorY+1, screen.CursorX+1)
+	}
+	return out.String()
+}
@@ -0,0 +1,66 @@
+package tui
+
+import (
+	"strings"
+)
+
+// Screen is a grid of characters the board renders into. Run draws it on the
+// terminal; tests read it with Line and String.
+type Screen struct {
+	Width, Height int
+	cells         [][]rune
+	// Cursor is where the terminal cursor is shown, when CursorVisible.
+	CursorX, CursorY int
+	CursorVisible    bool
+}
+
+func NewScreen(width, height int) *Screen {
+	cells := make([][]rune, height)
+	for y := range cells {
+		cells[y] = []rune(strings.Repeat(" ", width))
+	}
+	return &Screen{Width: width, Height: height, cells: cells}
+}
+
+// Put writes text at a position, cut at the right edge of the screen. It
+// returns the column after the last rune written.
+func (s *Screen) Put(x, y int, text string) int {
+	if y < 0 || y >= s.Height {
+		return x
+	}
+	for _, r := range text {
+		if x >= s.Width {
+			break
+		}
+		if x >= 0 {
+			s.cells[y][x] = r
+		}
+		x++
+	}
+	return x
+}
+
+// Line returns a row without its trailing spaces.
+func (s *Screen) Line(y int) string {
+	return strings.TrimRight(string(s.cells[y]), " ")
+}
+
+func (s *Screen) String() string {
+	lines := make([]string, s.Height)
+	for y := range lines {
+		lines[y] = s.Line(y)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// truncate cuts text to width runes, marking the cut with an ellipsis.
+func truncate(text string, width int) string {
+	runes := []rune(text)
+	if len(runes) <= width {
+		return text
+	}
+	if width <= 1 {
+		return string(runes[:max(width, 0)])
+	}
+	return string(runes[:width-1]) + "…"
+}
@@ -0,0 +1,11 @@
+//go:build !linux && !darwin
+
+package tui
+
+func makeRaw(fd uintptr) (func() error, error) {
+	return nil, ErrNotTerminal
+}
+
+func terminalSize(fd uintptr) (int, int, error) {
+	return 0, 0, ErrNotTerminal
+}
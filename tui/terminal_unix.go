@@ -0,0 +1,51 @@
+//go:build linux || darwin
+
+package tui
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+func ioctl(fd, request uintptr, arg unsafe.Pointer) error {
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, request, uintptr(arg)); errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// makeRaw turns off line buffering, echo and signal keys on the terminal, so
+// every key press is read as it comes. It returns a function that restores
+// the previous settings.
+func makeRaw(fd uintptr) (func() error, error) {
+	var saved syscall.Termios
+	if err := ioctl(fd, ioctlGetTermios, unsafe.Pointer(&saved)); err != nil {
+		return nil, ErrNotTerminal
+	}
+	raw := saved
+	raw.Iflag &^= syscall.IGNBRK | syscall.BRKINT | syscall.PARMRK | syscall.ISTRIP | syscall.INLCR | syscall.IGNCR | syscall.ICRNL | syscall.IXON
+	raw.Oflag &^= syscall.OPOST
+	raw.Lflag &^= syscall.ECHO | syscall.ECHONL | syscall.ICANON | syscall.ISIG | syscall.IEXTEN
+	raw.Cflag &^= syscall.CSIZE | syscall.PARENB
+	raw.Cflag |= syscall.CS8
+	raw.Cc[syscall.VMIN] = 1
+	raw.Cc[syscall.VTIME] = 0
+	if err := ioctl(fd, ioctlSetTermios, unsafe.Pointer(&raw)); err != nil {
+		return nil, err
+	}
+	return func() error {
+		return ioctl(fd, ioctlSetTermios, unsafe.Pointer(&saved))
+	}, nil
+}
+
+type winsize struct {
+	Rows, Cols, X, Y uint16
+}
+
+func terminalSize(fd uintptr) (int, int, error) {
+	var size winsize
+	if err := ioctl(fd, syscall.TIOCGWINSZ, unsafe.Pointer(&size)); err != nil {
+		return 0, 0, err
+	}
+	return int(size.Cols), int(size.Rows), nil
+}